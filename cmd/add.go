@@ -6,10 +6,9 @@ import (
 
 	"github.com/t-eckert/fave/cmd/utils"
 	"github.com/t-eckert/fave/internal"
-	"github.com/t-eckert/fave/internal/client"
 )
 
-func RunAdd(args []string) error {
+func RunAdd(args []string) (err error) {
 	// Parse command-specific flags
 	fs := flag.NewFlagSet("add", flag.ContinueOnError)
 	description := fs.String("description", "", "Bookmark description")
@@ -18,13 +17,14 @@ func RunAdd(args []string) error {
 	fs.Var(&tags, "tag", "Tag (can be specified multiple times)")
 	fs.Var(&tags, "t", "Tag (shorthand, can be specified multiple times)")
 
-	if err := fs.Parse(args); err != nil {
+	own, rest := utils.SplitClientArgs(fs, args)
+	if err := fs.Parse(own); err != nil {
 		return err
 	}
 
-	// Get remaining args (name, url, and client config flags)
+	// Get positional args (name and url)
 	remaining := fs.Args()
-	if len(remaining) < 2 {
+	if len(remaining) != 2 {
 		return fmt.Errorf("usage: fave add [flags] <name> <url>")
 	}
 
@@ -39,18 +39,18 @@ func RunAdd(args []string) error {
 	// Deduplicate tags
 	uniqueTags := utils.DeduplicateStrings(tags)
 
-	// Load client configuration from remaining args
-	cfg, err := utils.LoadClientConfig(remaining[2:])
+	// Load client configuration
+	cfg, err := utils.LoadClientConfig(rest)
 	if err != nil {
 		return err
 	}
 
-	// Create client
-	c, err := client.New(cfg)
+	// Connect to the server, or open the store file with --local
+	c, err := utils.OpenBackend(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
-	defer c.Close()
+	defer utils.CloseBackend(c, &err)
 
 	bookmark := internal.NewBookmark(url, name, *description, uniqueTags)
 
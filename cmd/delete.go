@@ -1,36 +1,42 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
 	"strconv"
 
 	"github.com/t-eckert/fave/cmd/utils"
-	"github.com/t-eckert/fave/internal/client"
 )
 
-func RunDelete(args []string) error {
-	if len(args) < 1 {
+func RunDelete(args []string) (err error) {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+
+	own, rest := utils.SplitClientArgs(fs, args)
+	if err := fs.Parse(own); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
 		return fmt.Errorf("usage: fave delete [flags] <id>")
 	}
 
 	// Parse ID
-	id, err := strconv.Atoi(args[0])
+	id, err := strconv.Atoi(fs.Arg(0))
 	if err != nil {
 		return fmt.Errorf("invalid bookmark ID: %w", err)
 	}
 
 	// Load configuration
-	cfg, err := utils.LoadClientConfig(args[1:])
+	cfg, err := utils.LoadClientConfig(rest)
 	if err != nil {
 		return err
 	}
 
-	// Create client
-	c, err := client.New(cfg)
+	// Connect to the server, or open the store file with --local
+	c, err := utils.OpenBackend(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
-	defer c.Close()
+	defer utils.CloseBackend(c, &err)
 
 	err = c.Delete(id)
 	if err != nil {
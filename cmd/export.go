@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/t-eckert/fave/cmd/utils"
+	"github.com/t-eckert/fave/internal/netscape"
+)
+
+func RunExport(args []string) (err error) {
+	// Parse command-specific flags
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", netscape.Format, "File format: netscape")
+	out := fs.String("out", "", "Write to this file instead of stdout")
+
+	own, rest := utils.SplitClientArgs(fs, args)
+	if err := fs.Parse(own); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: fave export [--format netscape] [--out <file>]")
+	}
+	if *format != netscape.Format {
+		return fmt.Errorf("unsupported format: %s (must be netscape)", *format)
+	}
+
+	// Load configuration
+	cfg, err := utils.LoadClientConfig(rest)
+	if err != nil {
+		return err
+	}
+
+	// Connect to the server, or open the store file with --local
+	c, err := utils.OpenBackend(cfg)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseBackend(c, &err)
+
+	data, err := c.Export(*format)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	if err := os.WriteFile(*out, data, 0644); err != nil {
+		return fmt.Errorf("failed to write bookmark file: %w", err)
+	}
+	fmt.Printf("Exported bookmarks to %s\n", *out)
+
+	return nil
+}
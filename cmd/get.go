@@ -6,20 +6,20 @@ import (
 	"strconv"
 
 	"github.com/t-eckert/fave/cmd/utils"
-	"github.com/t-eckert/fave/internal/client"
 )
 
-func RunGet(args []string) error {
+func RunGet(args []string) (err error) {
 	fs := flag.NewFlagSet("get", flag.ContinueOnError)
 	output := fs.String("output", "text", "Output format: text or json")
 	fs.String("o", "text", "Output format: text or json (shorthand)")
 
-	if err := fs.Parse(args); err != nil {
+	own, rest := utils.SplitClientArgs(fs, args)
+	if err := fs.Parse(own); err != nil {
 		return err
 	}
 
 	remaining := fs.Args()
-	if len(remaining) < 1 {
+	if len(remaining) != 1 {
 		return fmt.Errorf("usage: fave get [flags] <id>")
 	}
 
@@ -35,17 +35,17 @@ func RunGet(args []string) error {
 	}
 
 	// Load configuration
-	cfg, err := utils.LoadClientConfig(args[1:])
+	cfg, err := utils.LoadClientConfig(rest)
 	if err != nil {
 		return err
 	}
 
-	// Create client
-	c, err := client.New(cfg)
+	// Connect to the server, or open the store file with --local
+	c, err := utils.OpenBackend(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
-	defer c.Close()
+	defer utils.CloseBackend(c, &err)
 
 	bookmark, err := c.Get(id)
 	if err != nil {
@@ -1,18 +1,34 @@
 package cmd
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/t-eckert/fave/cmd/utils"
 	"github.com/t-eckert/fave/internal/client"
 )
 
 func RunHealth(args []string) error {
+	fs := flag.NewFlagSet("health", flag.ContinueOnError)
+	output := fs.String("output", "text", "Output format: text or json")
+
+	own, rest := utils.SplitClientArgs(fs, args)
+	if err := fs.Parse(own); err != nil {
+		return err
+	}
+
 	// Load configuration
-	cfg, err := utils.LoadClientConfig(args)
+	cfg, err := utils.LoadClientConfig(rest)
 	if err != nil {
 		return err
 	}
+	if err := utils.RequireServer(cfg, "health"); err != nil {
+		return err
+	}
 
 	// Create client
 	c, err := client.New(cfg)
@@ -21,12 +37,42 @@ func RunHealth(args []string) error {
 	}
 	defer c.Close()
 
-	err = c.Health()
+	report, err := c.HealthReport()
 	if err != nil {
 		return err
 	}
 
-	fmt.Println("Server is healthy")
+	if *output == "json" {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else {
+		fmt.Printf("Status:         %s\n", report.Status)
+		fmt.Printf("Version:        %s\n", report.Version)
+		fmt.Printf("Uptime:         %s (since %s)\n", time.Duration(report.UptimeSeconds)*time.Second, utils.FormatDate(report.StartedAt))
+		if report.Bookmarks != nil {
+			fmt.Printf("Bookmarks:      %d\n", *report.Bookmarks)
+		}
+		lastSnapshot := "none yet"
+		if report.LastSnapshotAt != 0 {
+			lastSnapshot = utils.FormatDate(report.LastSnapshotAt)
+		}
+		fmt.Printf("Last snapshot:  %s\n", lastSnapshot)
+		if report.SnapshotFailures > 0 {
+			fmt.Printf("Failing:        %d snapshots in a row\n", report.SnapshotFailures)
+		}
+
+		fmt.Println("Checks:")
+		for _, name := range slices.Sorted(maps.Keys(report.Checks)) {
+			fmt.Printf("  %-10s %s\n", name, report.Checks[name])
+		}
+	}
+
+	if report.Status != "healthy" {
+		return fmt.Errorf("server unhealthy: %s", report.Status)
+	}
 
 	return nil
 }
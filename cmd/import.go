@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/t-eckert/fave/cmd/utils"
+	"github.com/t-eckert/fave/internal/netscape"
+)
+
+func RunImport(args []string) (err error) {
+	// Parse command-specific flags
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", netscape.Format, "File format: netscape")
+	dryRun := fs.Bool("dry-run", false, "Report what would be imported without saving anything")
+
+	own, rest := utils.SplitClientArgs(fs, args)
+	if err := fs.Parse(own); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: fave import [--format netscape] [--dry-run] <file>")
+	}
+	if *format != netscape.Format {
+		return fmt.Errorf("unsupported format: %s (must be netscape)", *format)
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to read bookmark file: %w", err)
+	}
+
+	// Load configuration
+	cfg, err := utils.LoadClientConfig(rest)
+	if err != nil {
+		return err
+	}
+
+	// Connect to the server, or open the store file with --local
+	c, err := utils.OpenBackend(cfg)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseBackend(c, &err)
+
+	report, err := c.Import(*format, data, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, issue := range report.Duplicates {
+		fmt.Printf("Duplicate #%d: %s (%s): %s\n", issue.Index, issue.Name, issue.Url, issue.Reason)
+	}
+	for _, issue := range report.Invalid {
+		fmt.Printf("Invalid #%d: %s (%s): %s\n", issue.Index, issue.Name, issue.Url, issue.Reason)
+	}
+
+	verb := "Imported"
+	if report.DryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("%s %d of %d bookmarks (%d duplicates, %d invalid)\n",
+		verb, report.Imported, report.Total, len(report.Duplicates), len(report.Invalid))
+
+	return nil
+}
@@ -1,40 +1,105 @@
 package cmd
 
 import (
+	"flag"
 	"fmt"
 
 	"github.com/t-eckert/fave/cmd/utils"
-	"github.com/t-eckert/fave/internal/client"
+	"github.com/t-eckert/fave/internal"
 )
 
-func RunList(args []string) error {
-	// Load configuration
-	cfg, err := utils.LoadClientConfig(args)
-	if err != nil {
+func RunList(args []string) (err error) {
+	// Parse command-specific flags
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "Maximum bookmarks to show (0 = all)")
+	cursor := fs.String("cursor", "", "Cursor from a previous page")
+	sort := fs.String("sort", internal.SortID, "Sort by: id, created_at, updated_at, or name")
+	order := fs.String("order", "asc", "Sort direction: asc or desc")
+	var tags utils.StringSlice
+	fs.Var(&tags, "tag", "Only bookmarks with this tag (can be specified multiple times)")
+	fs.Var(&tags, "t", "Only bookmarks with this tag (shorthand)")
+	createdAfter := fs.String("created-after", "", "Only bookmarks created after this time (Unix, RFC 3339, or YYYY-MM-DD)")
+	createdBefore := fs.String("created-before", "", "Only bookmarks created before this time (Unix, RFC 3339, or YYYY-MM-DD)")
+	urlHost := fs.String("url-host", "", "Only bookmarks whose URL has this host")
+
+	own, rest := utils.SplitClientArgs(fs, args)
+	if err := fs.Parse(own); err != nil {
 		return err
 	}
 
-	// Create client
-	c, err := client.New(cfg)
+	opts := internal.ListOptions{
+		Limit:   *limit,
+		Cursor:  *cursor,
+		Sort:    *sort,
+		Tags:    tags,
+		URLHost: *urlHost,
+	}
+
+	switch *order {
+	case "asc":
+	case "desc":
+		opts.Desc = true
+	default:
+		return fmt.Errorf("invalid order: %s (must be asc or desc)", *order)
+	}
+
+	if *createdAfter != "" {
+		t, err := internal.ParseTime(*createdAfter)
+		if err != nil {
+			return fmt.Errorf("invalid --created-after: %w", err)
+		}
+		opts.CreatedAfter = t
+	}
+	if *createdBefore != "" {
+		t, err := internal.ParseTime(*createdBefore)
+		if err != nil {
+			return fmt.Errorf("invalid --created-before: %w", err)
+		}
+		opts.CreatedBefore = t
+	}
+
+	// Load configuration
+	cfg, err := utils.LoadClientConfig(rest)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
-	defer c.Close()
 
-	bookmarks, err := c.List()
+	// Connect to the server, or open the store file with --local
+	c, err := utils.OpenBackend(cfg)
 	if err != nil {
 		return err
 	}
+	defer utils.CloseBackend(c, &err)
 
-	if len(bookmarks) == 0 {
+	var entries []internal.BookmarkEntry
+	var nextCursor string
+	if *limit > 0 {
+		page, err := c.ListPage(opts)
+		if err != nil {
+			return err
+		}
+		entries = page.Bookmarks
+		nextCursor = page.NextCursor
+	} else {
+		entries, err = c.ListAll(opts)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(entries) == 0 {
 		fmt.Println("No bookmarks found")
 		return nil
 	}
 
-	for id, bookmark := range bookmarks {
-		fmt.Println(utils.FormatBookmark(id, &bookmark, "text"))
+	for _, entry := range entries {
+		fmt.Println(utils.FormatBookmark(entry.ID, &entry.Bookmark, "text"))
 		fmt.Println("---")
 	}
 
+	if nextCursor != "" {
+		fmt.Printf("Next cursor: %s\n", nextCursor)
+	}
+
 	return nil
 }
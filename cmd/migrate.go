@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/t-eckert/fave/cmd/utils"
+	"github.com/t-eckert/fave/internal/server"
+	"github.com/t-eckert/fave/internal/store"
+)
+
+const migrateUsage = `usage: fave migrate [--dry-run] [serve flags]
+
+Upgrades the store files of a server to the current snapshot schema version,
+keeping a copy of each file as it was. The server does the same when it opens
+a store; run this to see what would change first, or to upgrade ahead of time.
+Store files are found from the same --config, --store-file, and --users-file
+settings as fave serve, and cannot be migrated while a server has them open.
+
+Flags:
+	--dry-run	Report what would change without writing anything`
+
+func RunMigrate(args []string) error {
+	// Parse command-specific flags
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Report what would change without writing anything")
+
+	own, rest := utils.SplitArgs(fs, server.FlagSet(), args)
+	if err := fs.Parse(own); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New(migrateUsage)
+	}
+
+	// Find the store files the server would open
+	config, err := server.LoadConfig(rest)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	files := []string{config.StoreFileName}
+	if config.UsersFile != "" {
+		users, err := store.OpenUsers(config.UsersFile)
+		if err != nil {
+			return fmt.Errorf("failed to load users: %w", err)
+		}
+		defer users.Close()
+
+		list, err := users.List()
+		if err != nil {
+			return err
+		}
+		files = files[:0]
+		for _, user := range list {
+			files = append(files, users.NamespaceFile(user.Username))
+		}
+	}
+
+	for _, file := range files {
+		if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+			fmt.Printf("%s: no store file yet\n", file)
+			continue
+		}
+
+		report, err := store.MigrateFile(file, *dryRun)
+		if err != nil {
+			return err
+		}
+
+		if !report.Migrated() {
+			fmt.Printf("%s: up to date (schema version %d)\n", file, report.ToVersion)
+			continue
+		}
+
+		verb := "migrated"
+		if *dryRun {
+			verb = "would migrate"
+		}
+		fmt.Printf("%s: %s from schema version %d to %d\n", file, verb, report.FromVersion, report.ToVersion)
+		for _, step := range report.Steps {
+			fmt.Printf("  v%d: %s (%s)\n", step.Version, step.Description, step.Changes)
+		}
+		if report.BackupFile != "" {
+			fmt.Printf("  backup: %s\n", report.BackupFile)
+		}
+	}
+
+	if *dryRun {
+		fmt.Println("Dry run: no files were changed")
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/t-eckert/fave/cmd/utils"
+)
+
+func RunSearch(args []string) (err error) {
+	// Parse command-specific flags
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "Maximum number of results (0 = server default)")
+	output := fs.String("output", "text", "Output format: text or json")
+	fs.String("o", "text", "Output format: text or json (shorthand)")
+
+	own, rest := utils.SplitClientArgs(fs, args)
+	if err := fs.Parse(own); err != nil {
+		return err
+	}
+
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf(`usage: fave search [flags] <query>  (e.g. fave search 'golang "error handling" tag:guide -video')`)
+	}
+
+	// Handle shorthand -o flag
+	if o := fs.Lookup("o").Value.String(); o != "text" {
+		*output = o
+	}
+
+	// Load configuration
+	cfg, err := utils.LoadClientConfig(rest)
+	if err != nil {
+		return err
+	}
+
+	// Connect to the server, or open the store file with --local
+	c, err := utils.OpenBackend(cfg)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseBackend(c, &err)
+
+	results, err := c.Search(query, *limit)
+	if err != nil {
+		return err
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No bookmarks found")
+		return nil
+	}
+
+	for _, result := range results {
+		fmt.Println(utils.FormatBookmark(result.ID, &result.Bookmark, *output))
+		if *output == "text" {
+			fmt.Printf("Score: %.2f\n", result.Score)
+			fmt.Println("---")
+		}
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -9,6 +10,7 @@ import (
 	"path/filepath"
 	"syscall"
 
+	"github.com/t-eckert/fave/internal"
 	"github.com/t-eckert/fave/internal/server"
 	"github.com/t-eckert/fave/internal/store"
 )
@@ -24,28 +26,84 @@ func RunServe(args []string) error {
 	logger := setupLogger(config)
 
 	logger.Info("starting fave server",
-		"version", "0.1.0",
+		"version", internal.Version,
 		"addr", config.Addr(),
 	)
 
-	// Ensure store directory exists
-	storeDir := filepath.Dir(config.StoreFileName)
-	if err := os.MkdirAll(storeDir, 0755); err != nil {
-		return fmt.Errorf("creating store directory: %w", err)
+	// Load API tokens, which need another way to authenticate whoever creates them
+	var opts []server.Option
+	if config.UsersFile != "" || config.AuthPassword != "" || config.TLSClientCAFile != "" {
+		tokens, err := store.OpenTokens(config.TokensFile)
+		if err != nil {
+			return fmt.Errorf("loading tokens: %w", err)
+		}
+		opts = append(opts, server.WithTokens(tokens))
 	}
 
-	// Create store
-	bookmarkStore, err := store.NewStore(config.StoreFileName)
-	if err != nil {
-		return fmt.Errorf("creating store: %w", err)
-	}
+	// Create server, serving user accounts if a users file is configured
+	var srv *server.Server
+	if config.UsersFile != "" {
+		users, err := store.OpenUsers(config.UsersFile)
+		if err != nil {
+			return fmt.Errorf("loading users: %w", err)
+		}
+		defer users.Close()
 
-	logger.Info("store loaded", "file", config.StoreFileName)
+		logger.Info("users loaded", "file", config.UsersFile)
 
-	// Create server
-	srv, err := server.New(config, bookmarkStore, logger)
-	if err != nil {
-		return fmt.Errorf("creating server: %w", err)
+		if config.ForceUnlock {
+			broken, err := users.ForceUnlock()
+			for _, lock := range broken {
+				logger.Warn("broke store file lock", "file", lock.FileName, "pid", lock.PID, "host", lock.Host)
+			}
+			if errors.Is(err, internal.ErrStoreLocked) {
+				return fmt.Errorf("breaking store file locks: %w; the holder is still running or cannot be checked from this host, stop it first", err)
+			}
+			if err != nil {
+				return fmt.Errorf("breaking store file locks: %w", err)
+			}
+		}
+
+		srv, err = server.NewWithUsers(config, userStore{users}, logger, opts...)
+		if err != nil {
+			return fmt.Errorf("creating server: %w", err)
+		}
+	} else {
+		// Ensure store directory exists
+		storeDir := filepath.Dir(config.StoreFileName)
+		if err := os.MkdirAll(storeDir, 0755); err != nil {
+			return fmt.Errorf("creating store directory: %w", err)
+		}
+
+		if config.ForceUnlock {
+			lock, err := store.ForceUnlock(config.StoreFileName)
+			if errors.Is(err, internal.ErrStoreLocked) {
+				return fmt.Errorf("breaking store file lock: %w; the holder is still running or cannot be checked from this host, stop it first", err)
+			}
+			if err != nil {
+				return fmt.Errorf("breaking store file lock: %w", err)
+			}
+			if lock != nil {
+				logger.Warn("broke store file lock", "file", lock.FileName, "pid", lock.PID, "host", lock.Host)
+			}
+		}
+
+		// Create store
+		bookmarkStore, err := store.NewStoreWithEngine(config.StoreFileName, config.StorageEngine)
+		if errors.Is(err, internal.ErrStoreLocked) {
+			return fmt.Errorf("creating store: %w; stop that process first, or pass --force-unlock if it is no longer running", err)
+		}
+		if err != nil {
+			return fmt.Errorf("creating store: %w", err)
+		}
+		defer bookmarkStore.Close()
+
+		logger.Info("store loaded", "file", config.StoreFileName, "engine", config.StorageEngine)
+
+		srv, err = server.New(config, bookmarkStore, logger, opts...)
+		if err != nil {
+			return fmt.Errorf("creating server: %w", err)
+		}
 	}
 
 	// Setup graceful shutdown
@@ -71,6 +129,19 @@ func RunServe(args []string) error {
 	}
 }
 
+// userStore adapts store.Users, whose namespaces are concrete stores, to server.UserStore.
+type userStore struct {
+	*store.Users
+}
+
+func (u userStore) Namespace(username string) (server.StoreInterface, error) {
+	namespace, err := u.Users.Namespace(username)
+	if err != nil {
+		return nil, err
+	}
+	return namespace, nil
+}
+
 func setupLogger(config server.Config) *slog.Logger {
 	opts := &slog.HandlerOptions{
 		Level: config.LogLevelValue(),
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/t-eckert/fave/cmd/utils"
+	"github.com/t-eckert/fave/internal"
+	"github.com/t-eckert/fave/internal/client"
+)
+
+const tokenUsage = `usage: fave token <subcommand> [flags]
+
+Manages API tokens. Requires a password or a token with the admin scope.
+
+Subcommands:
+	create	Create a token and print it (--name, --scope, --expires-in)
+	list	List tokens
+	revoke	Revoke a token by ID`
+
+func RunToken(args []string) error {
+	if len(args) < 1 {
+		return errors.New(tokenUsage)
+	}
+	subcommand := args[0]
+
+	// Parse command-specific flags
+	fs := flag.NewFlagSet("token "+subcommand, flag.ContinueOnError)
+	var name *string
+	var scopes utils.StringSlice
+	var expiresIn *time.Duration
+	var output *string
+	switch subcommand {
+	case "create":
+		name = fs.String("name", "", "Name describing what the token is for")
+		fs.Var(&scopes, "scope", "Scope to grant: read, write, or admin (can be specified multiple times; default read)")
+		fs.Var(&scopes, "s", "Scope to grant (shorthand)")
+		expiresIn = fs.Duration("expires-in", 0, "Time until the token expires, e.g. 720h (default never)")
+	case "list":
+		output = fs.String("output", "text", "Output format: text or json")
+	case "revoke":
+	default:
+		return fmt.Errorf("unknown token subcommand: %s\n\n%s", subcommand, tokenUsage)
+	}
+
+	own, rest := utils.SplitClientArgs(fs, args[1:])
+	if err := fs.Parse(own); err != nil {
+		return err
+	}
+
+	// Load configuration
+	cfg, err := utils.LoadClientConfig(rest)
+	if err != nil {
+		return err
+	}
+	if err := utils.RequireServer(cfg, "token"); err != nil {
+		return err
+	}
+
+	// Create client
+	c, err := client.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	defer c.Close()
+
+	switch subcommand {
+	case "create":
+		if len(scopes) == 0 {
+			scopes = append(scopes, internal.ScopeRead)
+		}
+		var expiresAt int64
+		if *expiresIn < 0 {
+			return fmt.Errorf("--expires-in cannot be negative")
+		}
+		if *expiresIn > 0 {
+			expiresAt = time.Now().Add(*expiresIn).Unix()
+		}
+
+		token, err := c.CreateToken(*name, scopes, expiresAt)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Token %s created with scopes %s\n", token.ID, strings.Join(token.Scopes, ", "))
+		if token.ExpiresAt != 0 {
+			fmt.Printf("Expires: %s\n", utils.FormatDate(token.ExpiresAt))
+		}
+		fmt.Printf("\n%s\n\nStore it now; it cannot be shown again.\n", token.Secret)
+	case "list":
+		tokens, err := c.ListTokens()
+		if err != nil {
+			return err
+		}
+
+		if *output == "json" {
+			b, err := json.MarshalIndent(tokens, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(b))
+			return nil
+		}
+
+		if len(tokens) == 0 {
+			fmt.Println("No tokens found")
+			return nil
+		}
+		for _, token := range tokens {
+			fmt.Println(formatToken(token))
+		}
+	case "revoke":
+		if fs.NArg() != 1 {
+			return fmt.Errorf("usage: fave token revoke [flags] <id>")
+		}
+		if err := c.RevokeToken(fs.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Printf("Token %s revoked\n", fs.Arg(0))
+	}
+
+	return nil
+}
+
+// formatToken describes a token on one line.
+func formatToken(token internal.Token) string {
+	expires, lastUsed := "never", "never"
+	if token.ExpiresAt != 0 {
+		expires = utils.FormatDate(token.ExpiresAt)
+		if token.Expired(time.Now()) {
+			expires += " (expired)"
+		}
+	}
+	if token.LastUsedAt != 0 {
+		lastUsed = utils.FormatDate(token.LastUsedAt)
+	}
+
+	return fmt.Sprintf("%s\t%q\tscopes: %s\texpires: %s\tlast used: %s",
+		token.ID, token.Name, strings.Join(token.Scopes, ","), expires, lastUsed)
+}
@@ -7,26 +7,29 @@ import (
 
 	"github.com/t-eckert/fave/cmd/utils"
 	"github.com/t-eckert/fave/internal"
-	"github.com/t-eckert/fave/internal/client"
 )
 
-func RunUpdate(args []string) error {
+func RunUpdate(args []string) (err error) {
 	// Parse command-specific flags
 	fs := flag.NewFlagSet("update", flag.ContinueOnError)
-	description := fs.String("description", "", "Bookmark description")
-	fs.String("d", "", "Bookmark description (shorthand)")
+	name := fs.String("name", "", "New bookmark name")
+	url := fs.String("url", "", "New bookmark URL")
+	description := fs.String("description", "", "New bookmark description")
+	fs.String("d", "", "New bookmark description (shorthand)")
 	var tags utils.StringSlice
-	fs.Var(&tags, "tag", "Tag (can be specified multiple times)")
-	fs.Var(&tags, "t", "Tag (shorthand, can be specified multiple times)")
+	fs.Var(&tags, "tag", "Tag (can be specified multiple times; replaces existing tags)")
+	fs.Var(&tags, "t", "Tag (shorthand, can be specified multiple times; replaces existing tags)")
+	clearTags := fs.Bool("clear-tags", false, "Remove all tags")
 
-	if err := fs.Parse(args); err != nil {
+	own, rest := utils.SplitClientArgs(fs, args)
+	if err := fs.Parse(own); err != nil {
 		return err
 	}
 
-	// Get remaining args (id, name, url, and client config flags)
+	// Get positional args (id, and optionally name and url)
 	remaining := fs.Args()
-	if len(remaining) < 3 {
-		return fmt.Errorf("usage: fave update [flags] <id> <name> <url>")
+	if len(remaining) != 1 && len(remaining) != 3 {
+		return fmt.Errorf("usage: fave update [flags] <id> [<name> <url>]")
 	}
 
 	// Parse ID
@@ -35,33 +38,55 @@ func RunUpdate(args []string) error {
 		return fmt.Errorf("invalid bookmark ID: %w", err)
 	}
 
-	name := remaining[1]
-	url := remaining[2]
+	// Only fields that were explicitly given are sent to the server
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
 
-	// Handle shorthand -d flag
-	if d := fs.Lookup("d").Value.String(); d != "" {
-		*description = d
+	var patch internal.BookmarkPatch
+	if set["name"] {
+		patch.Name = name
+	}
+	if set["url"] {
+		patch.Url = url
+	}
+	if len(remaining) == 3 {
+		patch.Name = &remaining[1]
+		patch.Url = &remaining[2]
+	}
+	if set["description"] {
+		patch.Description = description
+	}
+	if set["d"] {
+		d := fs.Lookup("d").Value.String()
+		patch.Description = &d
+	}
+	if set["tag"] || set["t"] {
+		uniqueTags := utils.DeduplicateStrings(tags)
+		patch.Tags = &uniqueTags
+	} else if *clearTags {
+		patch.Tags = &[]string{}
 	}
 
-	// Deduplicate tags
-	uniqueTags := utils.DeduplicateStrings(tags)
+	if patch.Empty() {
+		return fmt.Errorf("nothing to update: pass --name, --url, --description, --tag, or --clear-tags")
+	}
 
 	// Load client configuration from remaining args
-	cfg, err := utils.LoadClientConfig(remaining[3:])
+	cfg, err := utils.LoadClientConfig(rest)
 	if err != nil {
 		return err
 	}
 
-	// Create client
-	c, err := client.New(cfg)
+	// Connect to the server, or open the store file with --local
+	c, err := utils.OpenBackend(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %w", err)
+		return err
 	}
-	defer c.Close()
-
-	bookmark := internal.NewBookmark(url, name, *description, uniqueTags)
+	defer utils.CloseBackend(c, &err)
 
-	err = c.Update(id, bookmark)
+	_, err = c.Patch(id, patch)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/t-eckert/fave/cmd/utils"
+	"github.com/t-eckert/fave/internal/store"
+)
+
+const userUsage = `usage: fave user <subcommand> [flags] [username]
+
+Manages the user accounts of a server, working directly on its users file.
+Changes take effect on a running server immediately.
+
+Subcommands:
+	create	Create a user (--password, or a generated one is printed)
+	reset	Reset a user's password (--password, or a generated one is printed)
+	disable	Stop a user from signing in; their bookmarks are kept
+	enable	Allow a disabled user to sign in again
+	list	List all users
+
+Flags:
+	--users-file	Path to the users file (default: $FAVE_USERS_FILE or ./data/users.json)`
+
+func RunUser(args []string) error {
+	if len(args) < 1 {
+		return errors.New(userUsage)
+	}
+	subcommand := args[0]
+	switch subcommand {
+	case "create", "reset", "disable", "enable", "list":
+	default:
+		return fmt.Errorf("unknown user subcommand: %s\n\n%s", subcommand, userUsage)
+	}
+
+	// Parse command-specific flags
+	fs := flag.NewFlagSet("user "+subcommand, flag.ContinueOnError)
+	usersFile := fs.String("users-file", defaultUsersFile(), "Path to the users file")
+	password := fs.String("password", "", "New password (create and reset only; generated if empty)")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	users, err := store.OpenUsers(*usersFile)
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+	defer users.Close()
+
+	if subcommand == "list" {
+		list, err := users.List()
+		if err != nil {
+			return err
+		}
+		for _, user := range list {
+			status := "enabled"
+			if user.Disabled {
+				status = "disabled"
+			}
+			fmt.Printf("%s\t%s\tcreated %s\n", user.Username, status, utils.FormatDate(user.CreatedAt))
+		}
+		return nil
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: fave user %s [flags] <username>", subcommand)
+	}
+	username := fs.Arg(0)
+
+	switch subcommand {
+	case "create", "reset":
+		generated := *password == ""
+		if generated {
+			if *password, err = generatePassword(); err != nil {
+				return err
+			}
+		}
+
+		if subcommand == "create" {
+			err = users.Create(username, *password)
+		} else {
+			err = users.SetPassword(username, *password)
+		}
+		if err != nil {
+			return err
+		}
+
+		if subcommand == "create" {
+			fmt.Printf("User %s created\n", username)
+		} else {
+			fmt.Printf("Password of %s reset\n", username)
+		}
+		if generated {
+			fmt.Printf("Password: %s\n", *password)
+		}
+	case "disable", "enable":
+		if err := users.SetDisabled(username, subcommand == "disable"); err != nil {
+			return err
+		}
+		fmt.Printf("User %s %sd\n", username, subcommand)
+	}
+
+	return nil
+}
+
+// defaultUsersFile returns the users file the server uses unless told otherwise.
+func defaultUsersFile() string {
+	if v := os.Getenv("FAVE_USERS_FILE"); v != "" {
+		return v
+	}
+	return "./data/users.json"
+}
+
+// generatePassword returns a random password with 128 bits of entropy.
+func generatePassword() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/t-eckert/fave/internal"
+	"github.com/t-eckert/fave/internal/client"
+	"github.com/t-eckert/fave/internal/local"
+)
+
+// Backend is the set of bookmark operations used by CLI commands.
+// It is implemented by client.Client, which sends them to a server, and by
+// local.Client, which performs them on a store file.
+type Backend interface {
+	Add(bookmark internal.Bookmark) (int, error)
+	Get(id int) (*internal.Bookmark, error)
+	ListPage(opts internal.ListOptions) (*internal.ListPage, error)
+	ListAll(opts internal.ListOptions) ([]internal.BookmarkEntry, error)
+	Search(query string, limit int) ([]internal.SearchResult, error)
+	Patch(id int, patch internal.BookmarkPatch) (*internal.Bookmark, error)
+	Delete(id int) error
+	Import(format string, data []byte, dryRun bool) (*internal.ImportReport, error)
+	Export(format string) ([]byte, error)
+	Close() error
+}
+
+var (
+	_ Backend = (*client.Client)(nil)
+	_ Backend = (*local.Client)(nil)
+)
+
+// OpenBackend returns a local.Client for the store file in cfg.Local if it is
+// set, and otherwise a client.Client for the server at cfg.Host.
+func OpenBackend(cfg client.Config) (Backend, error) {
+	if cfg.Local != "" {
+		return local.Open(cfg.Local)
+	}
+
+	c, err := client.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	return c, nil
+}
+
+// CloseBackend closes c and, unless *err already holds an error, sets it to
+// the error from closing. Commands defer it so that a local store whose final
+// snapshot fails makes the command fail.
+func CloseBackend(c Backend, err *error) {
+	if closeErr := c.Close(); closeErr != nil && *err == nil {
+		*err = closeErr
+	}
+}
+
+// RequireServer returns an error if cfg is set up for local mode, for
+// commands that only a server can carry out.
+func RequireServer(cfg client.Config, command string) error {
+	if cfg.Local != "" {
+		return fmt.Errorf("fave %s needs a server and cannot be used with --local", command)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"flag"
 	"fmt"
 
 	"github.com/t-eckert/fave/internal/client"
@@ -16,3 +17,9 @@ func LoadClientConfig(args []string) (client.Config, error) {
 
 	return cfg, nil
 }
+
+// SplitClientArgs separates the arguments of a command, parsed by fs, from the
+// client flags, which are returned in rest for LoadClientConfig.
+func SplitClientArgs(fs *flag.FlagSet, args []string) (own, rest []string) {
+	return SplitArgs(fs, client.FlagSet(&client.Config{}), args)
+}
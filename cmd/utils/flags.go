@@ -1,6 +1,10 @@
 package utils
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
 
 // StringSlice is a custom flag type for collecting multiple values.
 // It can be used with flag.Var() to allow specifying a flag multiple times.
@@ -29,3 +33,54 @@ func DeduplicateStrings(input []string) []string {
 
 	return result
 }
+
+// SplitArgs separates the arguments of a command, the flags defined on fs and
+// any positional arguments, from the flags defined on shared, such as the
+// client flags, which are returned in rest. This lets command flags, shared
+// flags, and positional arguments be given in any order.
+// A flag takes the following argument as its value unless it is a boolean
+// flag or the value is attached with "=". Flags defined on neither are left
+// in rest, so parsing it reports them.
+// The positional arguments come last in own, after "--", so fs.Parse reads
+// every flag before them.
+func SplitArgs(fs, shared *flag.FlagSet, args []string) (own, rest []string) {
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if arg == "--" {
+			positional = append(positional, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+
+		f := fs.Lookup(name)
+		target := &own
+		if f == nil {
+			f = shared.Lookup(name)
+			target = &rest
+		}
+		*target = append(*target, arg)
+		if f == nil || hasValue || isBoolFlag(f) {
+			continue
+		}
+		if i+1 < len(args) {
+			*target = append(*target, args[i+1])
+			i++
+		}
+	}
+
+	own = append(own, "--")
+	return append(own, positional...), rest
+}
+
+// isBoolFlag reports whether f is a boolean flag, which takes no value.
+func isBoolFlag(f *flag.Flag) bool {
+	bf, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
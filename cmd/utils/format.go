@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -43,3 +44,28 @@ func FormatBookmark(id int, bookmark *internal.Bookmark, output string) string {
 		return "Unsupported output format " + output
 	}
 }
+
+// FormatEvent formats a change from the change feed as a single line.
+// The json output is one JSON object per line (JSON Lines).
+func FormatEvent(event internal.Event, output string) string {
+	switch output {
+	case "json":
+		b, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Sprintf(`{"error": %q}`, err.Error())
+		}
+		return string(b)
+	case "text":
+		line := fmt.Sprintf("%s [%d] %s", FormatDate(event.Time), event.Seq, event.Type)
+		if event.Type == internal.EventReset {
+			return line + ": some changes were missed, reload bookmarks to catch up"
+		}
+		line += fmt.Sprintf(" %d", event.ID)
+		if event.Bookmark != nil {
+			line += fmt.Sprintf(" %q %s %v", event.Bookmark.Name, event.Bookmark.Url, event.Bookmark.Tags)
+		}
+		return line
+	default:
+		return "Unsupported output format " + output
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/t-eckert/fave/cmd/utils"
+	"github.com/t-eckert/fave/internal"
+	"github.com/t-eckert/fave/internal/client"
+)
+
+func RunWatch(args []string) error {
+	// Parse command-specific flags
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	output := fs.String("output", "text", "Output format: text or json (one object per line)")
+	fs.String("o", "text", "Output format: text or json (shorthand)")
+	var tags utils.StringSlice
+	fs.Var(&tags, "tag", "Only changes to bookmarks with this tag (can be specified multiple times)")
+	fs.Var(&tags, "t", "Only changes to bookmarks with this tag (shorthand)")
+
+	own, rest := utils.SplitClientArgs(fs, args)
+	if err := fs.Parse(own); err != nil {
+		return err
+	}
+
+	// Handle shorthand -o flag
+	if o := fs.Lookup("o").Value.String(); o != "text" {
+		*output = o
+	}
+	if *output != "text" && *output != "json" {
+		return fmt.Errorf("invalid output: %s (must be text or json)", *output)
+	}
+
+	// Load configuration
+	cfg, err := utils.LoadClientConfig(rest)
+	if err != nil {
+		return err
+	}
+	if err := utils.RequireServer(cfg, "watch"); err != nil {
+		return err
+	}
+
+	// Create client
+	c, err := client.New(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	defer c.Close()
+
+	// Watch until interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	events, err := c.Watch(ctx)
+	if err != nil {
+		return err
+	}
+
+	for event := range events {
+		if event.Type != internal.EventReset && !hasTags(event.Bookmark, tags) {
+			continue
+		}
+		fmt.Println(utils.FormatEvent(event, *output))
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("lost connection to %s after %d reconnect attempts", cfg.Host, cfg.RetryAttempts)
+}
+
+// hasTags reports whether a bookmark carries every one of the given tags.
+func hasTags(bookmark *internal.Bookmark, tags []string) bool {
+	for _, tag := range tags {
+		if bookmark == nil || !slices.Contains(bookmark.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
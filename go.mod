@@ -1,3 +1,5 @@
 module github.com/t-eckert/fave
 
 go 1.25.1
+
+require golang.org/x/crypto v0.54.0
@@ -0,0 +1,62 @@
+package internal
+
+// Operations accepted in a batch.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// MaxBatchSize is the largest number of operations accepted in one batch.
+const MaxBatchSize = 1000
+
+// BatchOp is one operation in a batch.
+// Create needs Bookmark; update needs ID and Bookmark; delete needs ID.
+// If IfRevision is set, an update or delete only happens if the bookmark is at that revision.
+type BatchOp struct {
+	Op         string    `json:"op"`
+	ID         int       `json:"id,omitempty"`
+	Bookmark   *Bookmark `json:"bookmark,omitempty"`
+	IfRevision *uint64   `json:"if_revision,omitempty"`
+}
+
+// BatchOpResult is the outcome of one operation as applied by a store.
+// ID is the bookmark created or affected and Revision its new revision.
+type BatchOpResult struct {
+	ID       int
+	Revision uint64
+	Err      error
+}
+
+// BatchRequest is the body of a batch request.
+// When Atomic is set, either every operation is applied or none is.
+// Otherwise operations are applied best-effort and failures are reported per item.
+type BatchRequest struct {
+	Atomic     bool      `json:"atomic"`
+	Operations []BatchOp `json:"operations"`
+}
+
+// BatchItemResult reports the outcome of one operation in a batch response.
+// Status is the HTTP status the operation would have had as a single request.
+type BatchItemResult struct {
+	Status   int          `json:"status"`
+	ID       int          `json:"id,omitempty"`
+	Revision uint64       `json:"revision,omitempty"`
+	Error    string       `json:"error,omitempty"`
+	Fields   []FieldError `json:"fields,omitempty"`
+}
+
+// BatchResponse is the body returned for a batch request, with one result per operation in order.
+// Aborted is set when an atomic batch was rolled back because an operation failed.
+type BatchResponse struct {
+	Atomic  bool              `json:"atomic"`
+	Aborted bool              `json:"aborted"`
+	Applied int               `json:"applied"`
+	Results []BatchItemResult `json:"results"`
+}
+
+// FieldError describes why a single field of a request was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
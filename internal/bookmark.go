@@ -11,6 +11,9 @@ type Bookmark struct {
 	Tags        []string `json:"tags"`
 	CreatedAt   int64    `json:"created_at"`
 	UpdatedAt   int64    `json:"updated_at"`
+
+	// Revision is set by the store. It starts at 1 and increases by one with every update.
+	Revision uint64 `json:"revision"`
 }
 
 func NewBookmark(url, name, description string, tags []string) Bookmark {
@@ -27,3 +30,17 @@ func NewBookmark(url, name, description string, tags []string) Bookmark {
 func nowUnix() int64 {
 	return time.Now().Unix()
 }
+
+// BookmarkPatch is a partial update to a bookmark, sent as a JSON Merge Patch.
+// Nil fields are left unchanged. A non-nil pointer to an empty value clears the field.
+type BookmarkPatch struct {
+	Url         *string   `json:"url,omitempty"`
+	Name        *string   `json:"name,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
+}
+
+// Empty reports whether the patch changes nothing.
+func (p BookmarkPatch) Empty() bool {
+	return p.Url == nil && p.Name == nil && p.Description == nil && p.Tags == nil
+}
@@ -0,0 +1,86 @@
+package client
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+)
+
+// maxCacheEntries bounds the number of responses kept by the response cache.
+const maxCacheEntries = 256
+
+// cachedResponse is the body of a successful GET response together with its validators.
+type cachedResponse struct {
+	path         string
+	etag         string
+	lastModified string
+	body         []byte
+}
+
+// responseCache keeps GET responses in memory so that repeated reads can be
+// revalidated with If-None-Match and If-Modified-Since instead of refetched.
+// Entries are always revalidated before use, so the cache never serves stale data.
+// When full, the least recently used entry is evicted.
+type responseCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Most recently used at the front
+}
+
+func newResponseCache() *responseCache {
+	return &responseCache{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the cached response for a path, or nil.
+func (rc *responseCache) get(path string) *cachedResponse {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	elem, ok := rc.entries[path]
+	if !ok {
+		return nil
+	}
+	rc.order.MoveToFront(elem)
+	return elem.Value.(*cachedResponse)
+}
+
+// put stores a response body if the response carries a validator to revalidate it with.
+func (rc *responseCache) put(path string, header http.Header, body []byte) {
+	entry := &cachedResponse{
+		path:         path,
+		etag:         header.Get("ETag"),
+		lastModified: header.Get("Last-Modified"),
+		body:         body,
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if elem, ok := rc.entries[path]; ok {
+		rc.order.Remove(elem)
+		delete(rc.entries, path)
+	}
+	if entry.etag == "" && entry.lastModified == "" {
+		return
+	}
+
+	rc.entries[path] = rc.order.PushFront(entry)
+	if rc.order.Len() > maxCacheEntries {
+		oldest := rc.order.Back()
+		rc.order.Remove(oldest)
+		delete(rc.entries, oldest.Value.(*cachedResponse).path)
+	}
+}
+
+// conditions adds the validators of a cached response to a request.
+func (cr *cachedResponse) conditions(req *http.Request) {
+	if cr.etag != "" {
+		req.Header.Set("If-None-Match", cr.etag)
+	}
+	if cr.lastModified != "" {
+		req.Header.Set("If-Modified-Since", cr.lastModified)
+	}
+}
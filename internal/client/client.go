@@ -4,25 +4,39 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/t-eckert/fave/internal"
 )
 
+// maxModifyAttempts bounds how often Modify retries after a conflicting update.
+const maxModifyAttempts = 5
+
 // Client is an HTTP client for the Fave bookmark API.
 type Client struct {
 	config Config
 	http   *http.Client
+	stream *http.Client   // Like http, but without a timeout, for long-lived event streams
+	cache  *responseCache // nil unless Config.Cache is set
 }
 
 // New creates a new Client with the given configuration.
 func New(config Config) (*Client, error) {
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	// Create custom transport with connection pooling
 	transport := &http.Transport{
+		TLSClientConfig: tlsConfig,
 		DialContext: (&net.Dialer{
 			Timeout:   config.DialTimeout,
 			KeepAlive: config.KeepAlive,
@@ -34,18 +48,27 @@ func New(config Config) (*Client, error) {
 		ExpectContinueTimeout: 1 * time.Second,
 	}
 
-	return &Client{
+	c := &Client{
 		config: config,
 		http: &http.Client{
 			Transport: transport,
 			Timeout:   config.Timeout,
 		},
-	}, nil
+		stream: &http.Client{
+			Transport: transport,
+		},
+	}
+	if config.Cache {
+		c.cache = newResponseCache()
+	}
+
+	return c, nil
 }
 
 // Close cleans up client resources.
-func (c *Client) Close() {
+func (c *Client) Close() error {
 	c.http.CloseIdleConnections()
+	return nil
 }
 
 // Add creates a new bookmark and returns its ID.
@@ -79,6 +102,63 @@ func (c *Client) List() (map[int]internal.Bookmark, error) {
 	return bookmarks, nil
 }
 
+// ListPage returns one page of bookmarks, filtered and ordered according to opts.
+// Pass the returned NextCursor in opts.Cursor to fetch the following page.
+func (c *Client) ListPage(opts internal.ListOptions) (*internal.ListPage, error) {
+	query := opts.Values()
+	if !internal.HasListParams(query) {
+		// Ask for a page explicitly so the server doesn't return the legacy map
+		query.Set("limit", strconv.Itoa(internal.DefaultPageSize))
+	}
+
+	var page internal.ListPage
+	err := c.doWithRetry("GET", "/bookmarks?"+query.Encode(), nil, http.StatusOK, &page)
+	if err != nil {
+		return nil, fmt.Errorf("list bookmarks: %w", err)
+	}
+
+	return &page, nil
+}
+
+// ListAll follows the cursor through every page matching opts and returns all entries.
+// opts.Limit sets the page size used for each request.
+func (c *Client) ListAll(opts internal.ListOptions) ([]internal.BookmarkEntry, error) {
+	var entries []internal.BookmarkEntry
+	for {
+		page, err := c.ListPage(opts)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, page.Bookmarks...)
+
+		if page.NextCursor == "" {
+			return entries, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// Search runs a full-text query and returns up to limit results, best match first.
+// A limit of 0 uses the server default.
+func (c *Client) Search(query string, limit int) ([]internal.SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	var result struct {
+		Results []internal.SearchResult `json:"results"`
+	}
+
+	err := c.doWithRetry("GET", "/bookmarks/search?"+params.Encode(), nil, http.StatusOK, &result)
+	if err != nil {
+		return nil, fmt.Errorf("search bookmarks: %w", err)
+	}
+
+	return result.Results, nil
+}
+
 // Get retrieves a bookmark by ID.
 func (c *Client) Get(id int) (*internal.Bookmark, error) {
 	var bookmark internal.Bookmark
@@ -94,6 +174,16 @@ func (c *Client) Get(id int) (*internal.Bookmark, error) {
 
 // Update updates an existing bookmark.
 func (c *Client) Update(id int, bookmark internal.Bookmark) error {
+	return c.update(id, bookmark, nil)
+}
+
+// UpdateIfMatch updates a bookmark only if it is still at the given revision.
+// It returns an error wrapping ErrPreconditionFailed if the bookmark has changed since.
+func (c *Client) UpdateIfMatch(id int, bookmark internal.Bookmark, revision uint64) error {
+	return c.update(id, bookmark, ifMatchHeader(revision))
+}
+
+func (c *Client) update(id int, bookmark internal.Bookmark, headers http.Header) error {
 	body, err := json.Marshal(bookmark)
 	if err != nil {
 		return fmt.Errorf("failed to marshal bookmark: %w", err)
@@ -104,7 +194,7 @@ func (c *Client) Update(id int, bookmark internal.Bookmark) error {
 		ID int `json:"id"`
 	}
 
-	err = c.doWithRetry("PUT", path, body, http.StatusOK, &result)
+	err = c.doWithRetryHeaders("PUT", path, body, headers, http.StatusOK, &result)
 	if err != nil {
 		return fmt.Errorf("update bookmark: %w", err)
 	}
@@ -112,14 +202,77 @@ func (c *Client) Update(id int, bookmark internal.Bookmark) error {
 	return nil
 }
 
+// Modify performs a read-modify-write of a bookmark. It fetches the bookmark,
+// passes it to fn to change in place, and saves it conditionally on the revision
+// that was read. If another writer changed the bookmark in between, the cycle is
+// repeated with the newer version, up to maxModifyAttempts times.
+// An error returned by fn aborts the loop and is returned as is.
+func (c *Client) Modify(id int, fn func(*internal.Bookmark) error) (*internal.Bookmark, error) {
+	var err error
+	for range maxModifyAttempts {
+		var bookmark *internal.Bookmark
+		bookmark, err = c.Get(id)
+		if err != nil {
+			return nil, err
+		}
+
+		revision := bookmark.Revision
+		if err := fn(bookmark); err != nil {
+			return nil, err
+		}
+
+		err = c.UpdateIfMatch(id, *bookmark, revision)
+		if err == nil {
+			bookmark.Revision = revision + 1
+			return bookmark, nil
+		}
+		if !errors.Is(err, ErrPreconditionFailed) {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("modify bookmark %d: gave up after %d conflicting updates: %w", id, maxModifyAttempts, err)
+}
+
+// Patch changes only the fields set in patch and returns the updated bookmark.
+// The server preserves CreatedAt and sets UpdatedAt.
+func (c *Client) Patch(id int, patch internal.BookmarkPatch) (*internal.Bookmark, error) {
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal patch: %w", err)
+	}
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/merge-patch+json")
+
+	var bookmark internal.Bookmark
+	path := fmt.Sprintf("/bookmarks/%d", id)
+	err = c.doWithRetryHeaders("PATCH", path, body, headers, http.StatusOK, &bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("patch bookmark: %w", err)
+	}
+
+	return &bookmark, nil
+}
+
 // Delete removes a bookmark by ID.
 func (c *Client) Delete(id int) error {
+	return c.delete(id, nil)
+}
+
+// DeleteIfMatch removes a bookmark only if it is still at the given revision.
+// It returns an error wrapping ErrPreconditionFailed if the bookmark has changed since.
+func (c *Client) DeleteIfMatch(id int, revision uint64) error {
+	return c.delete(id, ifMatchHeader(revision))
+}
+
+func (c *Client) delete(id int, headers http.Header) error {
 	path := fmt.Sprintf("/bookmarks/%d", id)
 	var result struct {
 		ID int `json:"id"`
 	}
 
-	err := c.doWithRetry("DELETE", path, nil, http.StatusOK, &result)
+	err := c.doWithRetryHeaders("DELETE", path, nil, headers, http.StatusOK, &result)
 	if err != nil {
 		return fmt.Errorf("delete bookmark: %w", err)
 	}
@@ -127,26 +280,147 @@ func (c *Client) Delete(id int) error {
 	return nil
 }
 
-// Health checks if the server is healthy.
-func (c *Client) Health() error {
-	var result struct {
-		Status string `json:"status"`
+// ifMatchHeader makes a request conditional on a bookmark revision.
+func ifMatchHeader(revision uint64) http.Header {
+	headers := http.Header{}
+	headers.Set("If-Match", `"`+strconv.FormatUint(revision, 10)+`"`)
+	return headers
+}
+
+// Batch sends many create, update, and delete operations in one request and returns
+// the server's result for each, in order. With atomic set, either every operation is
+// applied or none is. A failing operation does not make Batch return an error;
+// check the Status and Error of each result.
+func (c *Client) Batch(ops []internal.BatchOp, atomic bool) (*internal.BatchResponse, error) {
+	body, err := json.Marshal(internal.BatchRequest{Atomic: atomic, Operations: ops})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch: %w", err)
+	}
+
+	var resp internal.BatchResponse
+	err = c.doWithRetry("POST", "/bookmarks/batch", body, http.StatusOK, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("batch: %w", err)
+	}
+
+	return &resp, nil
+}
+
+// Import uploads a bookmark file in the given format (such as "netscape") and returns
+// the server's report of imported, duplicate, and invalid bookmarks.
+// With dryRun set, nothing is stored and the report describes what would happen.
+func (c *Client) Import(format string, data []byte, dryRun bool) (*internal.ImportReport, error) {
+	query := url.Values{}
+	query.Set("format", format)
+	expectedStatus := http.StatusCreated
+	if dryRun {
+		query.Set("dry_run", "true")
+		expectedStatus = http.StatusOK
+	}
+
+	headers := http.Header{}
+	headers.Set("Content-Type", "text/html")
+
+	var report internal.ImportReport
+	err := c.doWithRetryHeaders("POST", "/bookmarks/import?"+query.Encode(), data, headers, expectedStatus, &report)
+	if err != nil {
+		return nil, fmt.Errorf("import bookmarks: %w", err)
 	}
 
-	err := c.doWithRetry("GET", "/health", nil, http.StatusOK, &result)
+	return &report, nil
+}
+
+// Export downloads every bookmark as a file in the given format, such as "netscape".
+func (c *Client) Export(format string) ([]byte, error) {
+	query := url.Values{}
+	query.Set("format", format)
+
+	var data []byte
+	err := c.doWithRetry("GET", "/bookmarks/export?"+query.Encode(), nil, http.StatusOK, &data)
 	if err != nil {
+		return nil, fmt.Errorf("export bookmarks: %w", err)
+	}
+
+	return data, nil
+}
+
+// CreateToken issues an API token with the given name and scopes, expiring at the
+// Unix time expiresAt (0 for never). The returned token includes its secret,
+// which cannot be retrieved again. Requires the admin scope or a password.
+func (c *Client) CreateToken(name string, scopes []string, expiresAt int64) (*internal.CreatedToken, error) {
+	body, err := json.Marshal(internal.TokenRequest{Name: name, Scopes: scopes, ExpiresAt: expiresAt})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal token request: %w", err)
+	}
+
+	var token internal.CreatedToken
+	err = c.doWithRetry("POST", "/tokens", body, http.StatusCreated, &token)
+	if err != nil {
+		return nil, fmt.Errorf("create token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// ListTokens returns the API tokens of the signed-in user, without their secrets.
+func (c *Client) ListTokens() ([]internal.Token, error) {
+	var tokens []internal.Token
+
+	err := c.doWithRetry("GET", "/tokens", nil, http.StatusOK, &tokens)
+	if err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeToken deletes the API token with the given ID.
+func (c *Client) RevokeToken(id string) error {
+	err := c.doWithRetry("DELETE", "/tokens/"+url.PathEscape(id), nil, http.StatusOK, nil)
+	if err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+
+	return nil
+}
+
+// Health checks if the server is healthy. It needs no authentication.
+func (c *Client) Health() error {
+	var report internal.HealthReport
+
+	if err := c.doWithRetry("GET", "/health", nil, http.StatusOK, &report); err != nil {
 		return fmt.Errorf("health check: %w", err)
 	}
 
-	if result.Status != "healthy" {
-		return fmt.Errorf("server unhealthy: %s", result.Status)
+	if report.Status != "healthy" {
+		return fmt.Errorf("server unhealthy: %s", report.Status)
 	}
 
 	return nil
 }
 
+// HealthReport returns the server's detailed report on its state, which
+// requires authentication.
+func (c *Client) HealthReport() (*internal.HealthReport, error) {
+	var report internal.HealthReport
+
+	err := c.doWithRetry("GET", "/health/details", nil, http.StatusOK, &report)
+	if err != nil {
+		return nil, fmt.Errorf("health check: %w", err)
+	}
+
+	return &report, nil
+}
+
 // doWithRetry performs an HTTP request with retry logic and exponential backoff.
 func (c *Client) doWithRetry(method, path string, body []byte, expectedStatus int, result any) error {
+	return c.doWithRetryHeaders(method, path, body, nil, expectedStatus, result)
+}
+
+// doWithRetryHeaders is doWithRetry with additional request headers.
+// When the server answers 429 with Retry-After, the client waits as long as
+// asked instead, or gives up if that is longer than RetryMaxDelay.
+func (c *Client) doWithRetryHeaders(method, path string, body []byte, headers http.Header, expectedStatus int, result any) error {
 	var lastErr error
 
 	for attempt := 0; attempt <= c.config.RetryAttempts; attempt++ {
@@ -156,11 +430,18 @@ func (c *Client) doWithRetry(method, path string, body []byte, expectedStatus in
 			if delay > c.config.RetryMaxDelay {
 				delay = c.config.RetryMaxDelay
 			}
+			var clientErr *ClientError
+			if errors.As(lastErr, &clientErr) && clientErr.StatusCode == http.StatusTooManyRequests && clientErr.RetryAfter > 0 {
+				if clientErr.RetryAfter > c.config.RetryMaxDelay {
+					return lastErr
+				}
+				delay = clientErr.RetryAfter
+			}
 			time.Sleep(delay)
 		}
 
 		// Perform request
-		err := c.doRequest(method, path, body, expectedStatus, result)
+		err := c.doRequest(method, path, body, headers, expectedStatus, result)
 		if err == nil {
 			return nil
 		}
@@ -179,7 +460,8 @@ func (c *Client) doWithRetry(method, path string, body []byte, expectedStatus in
 }
 
 // doRequest performs a single HTTP request without retries.
-func (c *Client) doRequest(method, path string, body []byte, expectedStatus int, result any) error {
+// The response body is decoded as JSON into result, or stored raw if result is a *[]byte.
+func (c *Client) doRequest(method, path string, body []byte, headers http.Header, expectedStatus int, result any) error {
 	url := c.config.Host + path
 
 	var bodyReader io.Reader
@@ -196,10 +478,20 @@ func (c *Client) doRequest(method, path string, body []byte, expectedStatus int,
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+
+	// Add authentication if a token or password is configured
+	c.addAuth(req)
 
-	// Add authentication if password is configured
-	if c.config.Password != "" {
-		c.addAuth(req)
+	// Revalidate a cached response instead of downloading it again
+	cacheable := c.cache != nil && method == http.MethodGet
+	var cached *cachedResponse
+	if cacheable {
+		if cached = c.cache.get(path); cached != nil {
+			cached.conditions(req)
+		}
 	}
 
 	// Execute request
@@ -209,13 +501,32 @@ func (c *Client) doRequest(method, path string, body []byte, expectedStatus int,
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return decodeResult(cached.body, result)
+	}
+
 	// Check status code
 	if resp.StatusCode != expectedStatus {
 		return parseErrorResponse(resp)
 	}
 
+	if cacheable {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		c.cache.put(path, resp.Header, body)
+		return decodeResult(body, result)
+	}
+
 	// Parse response body if result is provided
-	if result != nil {
+	switch result := result.(type) {
+	case nil:
+	case *[]byte:
+		if *result, err = io.ReadAll(resp.Body); err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+	default:
 		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
 			return fmt.Errorf("failed to decode response: %w", err)
 		}
@@ -224,10 +535,38 @@ func (c *Client) doRequest(method, path string, body []byte, expectedStatus int,
 	return nil
 }
 
-// addAuth adds HTTP Basic Authentication to the request.
+// decodeResult parses a buffered response body into result, if one is provided.
+// A *[]byte result receives the raw body instead.
+func decodeResult(body []byte, result any) error {
+	if result == nil {
+		return nil
+	}
+	if raw, ok := result.(*[]byte); ok {
+		*raw = body
+		return nil
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// addAuth adds the configured API token, or else HTTP Basic Authentication, to the request.
 func (c *Client) addAuth(req *http.Request) {
-	// Username can be anything; only password matters
-	credentials := "user:" + c.config.Password
+	if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+		return
+	}
+	if c.config.Password == "" {
+		return
+	}
+
+	// Servers with a shared password accept any username
+	username := c.config.Username
+	if username == "" {
+		username = "user"
+	}
+	credentials := username + ":" + c.config.Password
 	encoded := base64.StdEncoding.EncodeToString([]byte(credentials))
 	req.Header.Set("Authorization", "Basic "+encoded)
 }
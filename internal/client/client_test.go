@@ -1,11 +1,18 @@
 package client_test
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -90,6 +97,80 @@ func TestAdd_WithAuth(t *testing.T) {
 	}
 }
 
+// TestAdd_WithUsername tests that the configured username is sent with the password.
+func TestAdd_WithUsername(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "alice" || password != "secret123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Authentication required"})
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]int{"id": 1})
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Username = "alice"
+	cfg.Password = "secret123"
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	_, err = c.Add(testBookmark("Test"))
+	if err != nil {
+		t.Fatalf("Add with username failed: %v", err)
+	}
+}
+
+// TestCreateToken_WithBearer tests token creation and that a configured token is sent instead of a password.
+func TestCreateToken_WithBearer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer fave_admin" {
+			t.Errorf("Expected bearer token, got %q", auth)
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/tokens" {
+			t.Errorf("Expected POST /tokens, got %s %s", r.Method, r.URL.Path)
+		}
+
+		var req internal.TokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.Name != "CI" || !slices.Equal(req.Scopes, []string{"read"}) || req.ExpiresAt != 1900000000 {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(internal.CreatedToken{
+			Token:  internal.Token{ID: "abc", Name: req.Name, Scopes: req.Scopes, ExpiresAt: req.ExpiresAt},
+			Secret: "fave_new",
+		})
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Password = "ignored"
+	cfg.Token = "fave_admin"
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	token, err := c.CreateToken("CI", []string{"read"}, 1900000000)
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	if token.ID != "abc" || token.Secret != "fave_new" {
+		t.Errorf("Unexpected token: %+v", token)
+	}
+}
+
 // TestList_Success tests successful bookmark listing.
 func TestList_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -124,6 +205,89 @@ func TestList_Success(t *testing.T) {
 	}
 }
 
+// TestListAll_FollowsCursor tests that ListAll requests every page.
+func TestListAll_FollowsCursor(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.RawQuery)
+
+		if r.URL.Query().Get("tag") != "go" {
+			t.Errorf("Expected tag=go, got %q", r.URL.Query().Get("tag"))
+		}
+
+		page := internal.ListPage{}
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			page.Bookmarks = []internal.BookmarkEntry{{ID: 1, Bookmark: testBookmark("First")}}
+			page.NextCursor = "next"
+		case "next":
+			page.Bookmarks = []internal.BookmarkEntry{{ID: 2, Bookmark: testBookmark("Second")}}
+		default:
+			t.Errorf("Unexpected cursor %q", r.URL.Query().Get("cursor"))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer server.Close()
+
+	c, err := client.New(testConfig(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	entries, err := c.ListAll(internal.ListOptions{Tags: []string{"go"}})
+	if err != nil {
+		t.Fatalf("ListAll failed: %v", err)
+	}
+
+	if len(entries) != 2 || entries[0].ID != 1 || entries[1].ID != 2 {
+		t.Errorf("Expected entries 1 and 2, got %+v", entries)
+	}
+	if len(requests) != 2 {
+		t.Errorf("Expected 2 requests, got %d", len(requests))
+	}
+}
+
+// TestSearch_Success tests full-text search.
+func TestSearch_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/bookmarks/search" {
+			t.Errorf("Expected GET /bookmarks/search, got %s %s", r.Method, r.URL.Path)
+		}
+		if q := r.URL.Query().Get("q"); q != `"error handling" -video` {
+			t.Errorf("Expected query to be passed through, got %q", q)
+		}
+		if limit := r.URL.Query().Get("limit"); limit != "5" {
+			t.Errorf("Expected limit=5, got %q", limit)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []internal.SearchResult{
+				{BookmarkEntry: internal.BookmarkEntry{ID: 3, Bookmark: testBookmark("Errors")}, Score: 4.2},
+			},
+		})
+	}))
+	defer server.Close()
+
+	c, err := client.New(testConfig(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	results, err := c.Search(`"error handling" -video`, 5)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+
+	if len(results) != 1 || results[0].ID != 3 || results[0].Score != 4.2 {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+}
+
 // TestGet_Success tests successful bookmark retrieval.
 func TestGet_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -203,6 +367,122 @@ func TestUpdate_Success(t *testing.T) {
 	}
 }
 
+// TestPatch_Success tests that Patch sends only the given fields as a merge patch.
+func TestPatch_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/bookmarks/42" {
+			t.Errorf("Expected PATCH /bookmarks/42, got %s %s", r.Method, r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/merge-patch+json" {
+			t.Errorf("Expected Content-Type application/merge-patch+json, got %s", ct)
+		}
+
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if len(body) != 2 || body["name"] != "Patched" {
+			t.Errorf("Expected only name and tags in patch, got %v", body)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(internal.Bookmark{Name: "Patched", Url: "https://example.com"})
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	name := "Patched"
+	tags := []string{}
+	bookmark, err := c.Patch(42, internal.BookmarkPatch{Name: &name, Tags: &tags})
+	if err != nil {
+		t.Fatalf("Patch failed: %v", err)
+	}
+	if bookmark.Name != "Patched" {
+		t.Errorf("Expected name 'Patched', got '%s'", bookmark.Name)
+	}
+}
+
+// TestUpdateIfMatch_PreconditionFailed tests that a stale revision surfaces ErrPreconditionFailed.
+func TestUpdateIfMatch_PreconditionFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("If-Match"); got != `"3"` {
+			t.Errorf("Expected If-Match \"3\", got %q", got)
+		}
+		w.WriteHeader(http.StatusPreconditionFailed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Bookmark has been modified"})
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	err = c.UpdateIfMatch(42, testBookmark("Updated"), 3)
+	if !errors.Is(err, client.ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed, got: %v", err)
+	}
+}
+
+// TestModify_RetriesOnConflict tests that Modify re-reads and retries after a 412.
+func TestModify_RetriesOnConflict(t *testing.T) {
+	var gets, puts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			gets++
+			// Another writer bumps the revision after the first read
+			bookmark := testBookmark("Current")
+			bookmark.Revision = uint64(gets)
+			json.NewEncoder(w).Encode(bookmark)
+		case http.MethodPut:
+			puts++
+			if r.Header.Get("If-Match") != `"2"` {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Bookmark has been modified"})
+				return
+			}
+			var bookmark internal.Bookmark
+			json.NewDecoder(r.Body).Decode(&bookmark)
+			if bookmark.Description != "modified" {
+				t.Errorf("Expected modified description, got %q", bookmark.Description)
+			}
+			json.NewEncoder(w).Encode(map[string]int{"id": 42})
+		}
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	bookmark, err := c.Modify(42, func(b *internal.Bookmark) error {
+		b.Description = "modified"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Modify failed: %v", err)
+	}
+
+	if gets != 2 || puts != 2 {
+		t.Errorf("Expected 2 reads and 2 writes, got %d and %d", gets, puts)
+	}
+	if bookmark.Revision != 3 {
+		t.Errorf("Expected revision 3, got %d", bookmark.Revision)
+	}
+}
+
 // TestDelete_Success tests successful bookmark deletion.
 func TestDelete_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -228,6 +508,208 @@ func TestDelete_Success(t *testing.T) {
 	}
 }
 
+// TestImport_DryRun tests that Import uploads the file and decodes the report.
+func TestImport_DryRun(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/bookmarks/import" {
+			t.Errorf("Expected POST /bookmarks/import, got %s %s", r.Method, r.URL.Path)
+		}
+		if r.URL.Query().Get("format") != "netscape" || r.URL.Query().Get("dry_run") != "true" {
+			t.Errorf("Unexpected query: %s", r.URL.RawQuery)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "text/html" {
+			t.Errorf("Expected Content-Type text/html, got %s", ct)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "<DL></DL>" {
+			t.Errorf("Expected file contents as body, got %q", body)
+		}
+
+		json.NewEncoder(w).Encode(internal.ImportReport{DryRun: true, Total: 3, Imported: 2})
+	}))
+	defer server.Close()
+
+	c, err := client.New(testConfig(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	report, err := c.Import("netscape", []byte("<DL></DL>"), true)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if !report.DryRun || report.Imported != 2 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+// TestBatch_Success tests that Batch sends the operations and decodes per-item results.
+func TestBatch_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/bookmarks/batch" {
+			t.Errorf("Expected POST /bookmarks/batch, got %s %s", r.Method, r.URL.Path)
+		}
+
+		var req internal.BatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if !req.Atomic || len(req.Operations) != 2 || req.Operations[1].Op != internal.BatchDelete {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+
+		json.NewEncoder(w).Encode(internal.BatchResponse{
+			Atomic:  true,
+			Applied: 2,
+			Results: []internal.BatchItemResult{
+				{Status: http.StatusCreated, ID: 7, Revision: 1},
+				{Status: http.StatusOK, ID: 3},
+			},
+		})
+	}))
+	defer server.Close()
+
+	c, err := client.New(testConfig(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	bookmark := testBookmark("New")
+	resp, err := c.Batch([]internal.BatchOp{
+		{Op: internal.BatchCreate, Bookmark: &bookmark},
+		{Op: internal.BatchDelete, ID: 3},
+	}, true)
+	if err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
+	if resp.Applied != 2 || len(resp.Results) != 2 || resp.Results[0].ID != 7 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+}
+
+// TestWatch_ReceivesEvents tests that Watch parses the event stream into typed events.
+func TestWatch_ReceivesEvents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/events" {
+			t.Errorf("Expected /events, got %s", r.URL.Path)
+		}
+		if id := r.Header.Get("Last-Event-ID"); id != "4" {
+			t.Errorf("Expected Last-Event-ID 4, got %q", id)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, ": keep-alive\n\n")
+		io.WriteString(w, "id: 5\nevent: add\ndata: {\"seq\":5,\"type\":\"add\",\"id\":3,\"bookmark\":{\"name\":\"New\"}}\n\n")
+		io.WriteString(w, "id: 6\nevent: delete\ndata: {\"seq\":6,\"type\":\"delete\",\"id\":3}\n\n")
+	}))
+	defer server.Close()
+
+	c, err := client.New(testConfig(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	events, err := c.WatchFrom(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("WatchFrom failed: %v", err)
+	}
+
+	var got []internal.Event
+	for event := range events {
+		got = append(got, event)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 events, got %+v", got)
+	}
+	if got[0].Seq != 5 || got[0].Type != internal.EventAdd || got[0].Bookmark == nil || got[0].Bookmark.Name != "New" {
+		t.Errorf("Unexpected add event: %+v", got[0])
+	}
+	if got[1].Seq != 6 || got[1].Type != internal.EventDelete || got[1].ID != 3 {
+		t.Errorf("Unexpected delete event: %+v", got[1])
+	}
+}
+
+// TestWatch_ReconnectsWithoutDuplicates tests that a dropped stream is resumed
+// from the last event received and replayed events are skipped.
+func TestWatch_ReconnectsWithoutDuplicates(t *testing.T) {
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+
+		switch len(lastEventIDs) {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "id: 4\n\n")
+			io.WriteString(w, "id: 5\nevent: add\ndata: {\"seq\":5,\"type\":\"add\",\"id\":1}\n\n")
+		case 2:
+			w.Header().Set("Content-Type", "text/event-stream")
+			io.WriteString(w, "id: 5\nevent: add\ndata: {\"seq\":5,\"type\":\"add\",\"id\":1}\n\n")
+			io.WriteString(w, "id: 6\nevent: add\ndata: {\"seq\":6,\"type\":\"add\",\"id\":2}\n\n")
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Service unavailable"})
+		}
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.RetryAttempts = 1
+	cfg.RetryDelay = 10 * time.Millisecond
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	events, err := c.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	var seqs []uint64
+	for event := range events {
+		seqs = append(seqs, event.Seq)
+	}
+
+	if len(seqs) != 2 || seqs[0] != 5 || seqs[1] != 6 {
+		t.Errorf("Expected events 5 and 6 once each, got %v", seqs)
+	}
+	if len(lastEventIDs) != 3 || lastEventIDs[0] != "" || lastEventIDs[1] != "5" || lastEventIDs[2] != "6" {
+		t.Errorf("Expected to resume from the last event received, got Last-Event-IDs %q", lastEventIDs)
+	}
+}
+
+// TestExport_ReturnsRawFile tests that Export returns the body without decoding it.
+func TestExport_ReturnsRawFile(t *testing.T) {
+	const file = "<!DOCTYPE NETSCAPE-Bookmark-file-1>\n<DL><p>\n</DL><p>\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/bookmarks/export" {
+			t.Errorf("Expected /bookmarks/export, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, file)
+	}))
+	defer server.Close()
+
+	c, err := client.New(testConfig(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	data, err := c.Export("netscape")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if string(data) != file {
+		t.Errorf("Expected raw file, got %q", data)
+	}
+}
+
 // TestHealth_Success tests successful health check.
 func TestHealth_Success(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -274,6 +756,173 @@ func TestHealth_Unhealthy(t *testing.T) {
 	}
 }
 
+// TestHealthReport tests decoding the server's health report.
+func TestHealthReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.Write([]byte(`{"status":"unhealthy","checks":{"snapshots":"3 snapshots failed in a row: disk full"}}`))
+			return
+		}
+		if r.URL.Path != "/health/details" {
+			t.Errorf("Expected GET /health/details, got %s %s", r.Method, r.URL.Path)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"unhealthy","version":"1.2.3","started_at":1700000000,"uptime_seconds":90,` +
+			`"bookmarks":4,"last_snapshot_at":0,"snapshot_failures":3,"checks":{"snapshots":"3 snapshots failed in a row: disk full"}}`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	report, err := c.HealthReport()
+	if err != nil {
+		t.Fatalf("HealthReport failed: %v", err)
+	}
+	if report.Status != "unhealthy" || report.Version != "1.2.3" || report.UptimeSeconds != 90 || report.SnapshotFailures != 3 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if report.Bookmarks == nil || *report.Bookmarks != 4 {
+		t.Errorf("Expected 4 bookmarks, got %v", report.Bookmarks)
+	}
+	if report.Checks["snapshots"] != "3 snapshots failed in a row: disk full" {
+		t.Errorf("Unexpected checks: %v", report.Checks)
+	}
+
+	if err := c.Health(); err == nil {
+		t.Error("Expected Health to fail for an unhealthy server")
+	}
+}
+
+// TestTLS_CABundle tests that a server certificate is trusted through the CA
+// bundle or InsecureSkipVerify, and rejected otherwise.
+func TestTLS_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0644); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		config  func(*client.Config)
+		wantErr bool
+	}{
+		{"untrusted", func(*client.Config) {}, true},
+		{"CA bundle", func(cfg *client.Config) { cfg.CACertFile = caFile }, false},
+		{"insecure", func(cfg *client.Config) { cfg.InsecureSkipVerify = true }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(server.URL)
+			tt.config(&cfg)
+			c, err := client.New(cfg)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			defer c.Close()
+
+			err = c.Health()
+			if tt.wantErr && err == nil {
+				t.Error("Expected a certificate error, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+		})
+	}
+
+	// A missing CA bundle is reported when the client is created
+	cfg := testConfig(server.URL)
+	cfg.CACertFile = filepath.Join(t.TempDir(), "missing.pem")
+	if _, err := client.New(cfg); err == nil {
+		t.Error("Expected an error for a missing CA bundle, got nil")
+	}
+}
+
+// TestCache_Revalidates tests that cached responses are revalidated and reused on 304.
+func TestCache_Revalidates(t *testing.T) {
+	var requests, notModified int
+	etag := `"g1"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		json.NewEncoder(w).Encode(map[int]internal.Bookmark{1: testBookmark("Cached")})
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.Cache = true
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 3; i++ {
+		bookmarks, err := c.List()
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if bookmarks[1].Name != "Cached" {
+			t.Fatalf("Expected cached bookmark on request %d, got %v", i+1, bookmarks)
+		}
+	}
+
+	if requests != 3 || notModified != 2 {
+		t.Errorf("Expected 3 requests with 2 revalidated, got %d and %d", requests, notModified)
+	}
+
+	// A changed resource replaces the cached copy
+	etag = `"g2"`
+	if _, err := c.List(); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if notModified != 2 {
+		t.Errorf("Expected a full response after the ETag changed")
+	}
+}
+
+// TestCache_DisabledByDefault tests that no validators are sent without Config.Cache.
+func TestCache_DisabledByDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("Expected no If-None-Match without caching")
+		}
+		w.Header().Set("ETag", `"g1"`)
+		json.NewEncoder(w).Encode(map[int]internal.Bookmark{})
+	}))
+	defer server.Close()
+
+	c, err := client.New(testConfig(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.List(); err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+	}
+}
+
 // TestUnauthorized tests 401 error handling.
 func TestUnauthorized(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -324,6 +973,38 @@ func TestBadRequest(t *testing.T) {
 	}
 }
 
+// TestBadRequest_FieldErrors tests that per-field validation errors are surfaced.
+func TestBadRequest_FieldErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"Invalid bookmark","fields":[{"field":"url","message":"must be an absolute URL"}]}`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	_, err = c.Add(testBookmark("Test"))
+	if !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("Expected ErrBadRequest, got: %v", err)
+	}
+
+	var clientErr *client.ClientError
+	if !errors.As(err, &clientErr) {
+		t.Fatalf("Expected ClientError, got: %T", err)
+	}
+	if len(clientErr.Fields) != 1 || clientErr.Fields[0].Field != "url" {
+		t.Errorf("Expected a url field error, got %v", clientErr.Fields)
+	}
+	if !strings.Contains(err.Error(), "url must be an absolute URL") {
+		t.Errorf("Expected field error in message, got: %v", err)
+	}
+}
+
 // TestInternalServerError tests 500 error handling.
 func TestInternalServerError(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -448,6 +1129,61 @@ func TestRetryLogic_NoRetryOn4xx(t *testing.T) {
 	}
 }
 
+// TestRetryLogic_HonorsRetryAfter tests that a 429 is retried after the delay
+// the server asks for, and not retried if that is longer than RetryMaxDelay.
+func TestRetryLogic_HonorsRetryAfter(t *testing.T) {
+	var attempts []time.Time
+	retryAfter := "1"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts = append(attempts, time.Now())
+		if len(attempts) == 1 {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Rate limit exceeded"})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[int]internal.Bookmark{})
+	}))
+	defer server.Close()
+
+	cfg := testConfig(server.URL)
+	cfg.RetryAttempts = 3
+	cfg.RetryDelay = 10 * time.Millisecond
+	cfg.RetryMaxDelay = 5 * time.Second
+	c, err := client.New(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.List(); err != nil {
+		t.Fatalf("Expected success after retry, got error: %v", err)
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("Expected 2 attempts, got %d", len(attempts))
+	}
+	if waited := attempts[1].Sub(attempts[0]); waited < time.Second {
+		t.Errorf("Expected to wait at least 1s before retrying, waited %v", waited)
+	}
+
+	// A wait longer than RetryMaxDelay is not worth retrying
+	attempts, retryAfter = nil, "60"
+	_, err = c.List()
+	if !errors.Is(err, client.ErrTooManyRequests) {
+		t.Fatalf("Expected ErrTooManyRequests, got %v", err)
+	}
+	var clientErr *client.ClientError
+	if !errors.As(err, &clientErr) || clientErr.RetryAfter != time.Minute {
+		t.Errorf("Expected RetryAfter of 1m, got %v", err)
+	}
+	if len(attempts) != 1 {
+		t.Errorf("Expected 1 attempt, got %d", len(attempts))
+	}
+}
+
 // TestClientError_Wrapping tests error wrapping and unwrapping.
 func TestClientError_Wrapping(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -12,26 +12,42 @@ import (
 // Config holds the client configuration.
 type Config struct {
 	Host          string
+	Username      string // Sent with Password; checked by servers with user accounts
 	Password      string
+	Token         string // API token; sent instead of Username and Password when set
 	Timeout       time.Duration
 	DialTimeout   time.Duration
 	KeepAlive     time.Duration
 	RetryAttempts int
 	RetryDelay    time.Duration
 	RetryMaxDelay time.Duration
+	Cache         bool // Keep GET responses in memory and revalidate them with the server
+
+	// Local is the path of a store file to work on directly, without a server.
+	// When set, the server settings are ignored.
+	Local string
+
+	// TLS settings, for servers using HTTPS
+	CACertFile         string // PEM bundle of CAs to trust in addition to the system roots
+	ClientCertFile     string // PEM certificate to present to the server
+	ClientKeyFile      string // PEM private key of the client certificate
+	InsecureSkipVerify bool   // Accept any server certificate; only for testing
 }
 
 // DefaultConfig returns a Config with sensible defaults.
 func DefaultConfig() Config {
 	return Config{
 		Host:          "http://localhost:8080",
+		Username:      "",
 		Password:      "",
+		Token:         "",
 		Timeout:       30 * time.Second,
 		DialTimeout:   10 * time.Second,
 		KeepAlive:     30 * time.Second,
 		RetryAttempts: 3,
 		RetryDelay:    1 * time.Second,
 		RetryMaxDelay: 10 * time.Second,
+		Cache:         false,
 	}
 }
 
@@ -85,6 +101,9 @@ func (c *Config) Validate() error {
 	if c.RetryMaxDelay < 0 {
 		return fmt.Errorf("retry_max_delay cannot be negative")
 	}
+	if (c.ClientCertFile == "") != (c.ClientKeyFile == "") {
+		return fmt.Errorf("client_cert_file and client_key_file must be set together")
+	}
 	return nil
 }
 
@@ -113,13 +132,21 @@ func loadConfigFile(cfg *Config) error {
 	// Parse JSON
 	var fileConfig struct {
 		Host          string `json:"host,omitempty"`
+		Username      string `json:"username,omitempty"`
 		Password      string `json:"password,omitempty"`
+		Token         string `json:"token,omitempty"`
 		Timeout       string `json:"timeout,omitempty"`
 		DialTimeout   string `json:"dial_timeout,omitempty"`
 		KeepAlive     string `json:"keep_alive,omitempty"`
 		RetryAttempts int    `json:"retry_attempts,omitempty"`
 		RetryDelay    string `json:"retry_delay,omitempty"`
 		RetryMaxDelay string `json:"retry_max_delay,omitempty"`
+		Cache         bool   `json:"cache,omitempty"`
+
+		CACertFile         string `json:"ca_cert_file,omitempty"`
+		ClientCertFile     string `json:"client_cert_file,omitempty"`
+		ClientKeyFile      string `json:"client_key_file,omitempty"`
+		InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
 	}
 
 	if err := json.Unmarshal(data, &fileConfig); err != nil {
@@ -130,9 +157,15 @@ func loadConfigFile(cfg *Config) error {
 	if fileConfig.Host != "" {
 		cfg.Host = fileConfig.Host
 	}
+	if fileConfig.Username != "" {
+		cfg.Username = fileConfig.Username
+	}
 	if fileConfig.Password != "" {
 		cfg.Password = fileConfig.Password
 	}
+	if fileConfig.Token != "" {
+		cfg.Token = fileConfig.Token
+	}
 	if fileConfig.Timeout != "" {
 		d, err := time.ParseDuration(fileConfig.Timeout)
 		if err != nil {
@@ -171,6 +204,21 @@ func loadConfigFile(cfg *Config) error {
 		}
 		cfg.RetryMaxDelay = d
 	}
+	if fileConfig.Cache {
+		cfg.Cache = true
+	}
+	if fileConfig.CACertFile != "" {
+		cfg.CACertFile = fileConfig.CACertFile
+	}
+	if fileConfig.ClientCertFile != "" {
+		cfg.ClientCertFile = fileConfig.ClientCertFile
+	}
+	if fileConfig.ClientKeyFile != "" {
+		cfg.ClientKeyFile = fileConfig.ClientKeyFile
+	}
+	if fileConfig.InsecureSkipVerify {
+		cfg.InsecureSkipVerify = true
+	}
 
 	return nil
 }
@@ -180,9 +228,15 @@ func loadFromEnv(cfg *Config) {
 	if v := os.Getenv("FAVE_HOST"); v != "" {
 		cfg.Host = v
 	}
+	if v := os.Getenv("FAVE_USERNAME"); v != "" {
+		cfg.Username = v
+	}
 	if v := os.Getenv("FAVE_PASSWORD"); v != "" {
 		cfg.Password = v
 	}
+	if v := os.Getenv("FAVE_TOKEN"); v != "" {
+		cfg.Token = v
+	}
 	if v := os.Getenv("FAVE_TIMEOUT"); v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
 			cfg.Timeout = d
@@ -214,36 +268,51 @@ func loadFromEnv(cfg *Config) {
 			cfg.RetryMaxDelay = d
 		}
 	}
+	if v := os.Getenv("FAVE_CACHE"); v == "true" {
+		cfg.Cache = true
+	}
+	if v := os.Getenv("FAVE_LOCAL"); v != "" {
+		cfg.Local = v
+	}
+	if v := os.Getenv("FAVE_CA_CERT_FILE"); v != "" {
+		cfg.CACertFile = v
+	}
+	if v := os.Getenv("FAVE_CLIENT_CERT_FILE"); v != "" {
+		cfg.ClientCertFile = v
+	}
+	if v := os.Getenv("FAVE_CLIENT_KEY_FILE"); v != "" {
+		cfg.ClientKeyFile = v
+	}
+	if v := os.Getenv("FAVE_INSECURE_SKIP_VERIFY"); v == "true" {
+		cfg.InsecureSkipVerify = true
+	}
 }
 
 // loadFromFlags loads configuration from CLI flags.
 func loadFromFlags(cfg *Config, args []string) error {
-	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	return FlagSet(cfg).Parse(args)
+}
 
-	// Define flags
-	host := fs.String("host", cfg.Host, "Server URL")
-	password := fs.String("password", cfg.Password, "Authentication password")
-	timeout := fs.Duration("timeout", cfg.Timeout, "Request timeout")
-	dialTimeout := fs.Duration("dial-timeout", cfg.DialTimeout, "Connection dial timeout")
-	keepAlive := fs.Duration("keep-alive", cfg.KeepAlive, "Keep-alive duration")
-	retryAttempts := fs.Int("retry-attempts", cfg.RetryAttempts, "Number of retry attempts")
-	retryDelay := fs.Duration("retry-delay", cfg.RetryDelay, "Initial retry delay")
-	retryMaxDelay := fs.Duration("retry-max-delay", cfg.RetryMaxDelay, "Maximum retry delay")
-
-	// Parse flags
-	if err := fs.Parse(args); err != nil {
-		return err
-	}
-
-	// Apply flags
-	cfg.Host = *host
-	cfg.Password = *password
-	cfg.Timeout = *timeout
-	cfg.DialTimeout = *dialTimeout
-	cfg.KeepAlive = *keepAlive
-	cfg.RetryAttempts = *retryAttempts
-	cfg.RetryDelay = *retryDelay
-	cfg.RetryMaxDelay = *retryMaxDelay
+// FlagSet defines the client flags, bound to the fields of cfg and defaulting
+// to their current values.
+func FlagSet(cfg *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	fs.StringVar(&cfg.Host, "host", cfg.Host, "Server URL")
+	fs.StringVar(&cfg.Username, "username", cfg.Username, "Account username")
+	fs.StringVar(&cfg.Password, "password", cfg.Password, "Authentication password")
+	fs.StringVar(&cfg.Token, "token", cfg.Token, "API token (used instead of username and password)")
+	fs.DurationVar(&cfg.Timeout, "timeout", cfg.Timeout, "Request timeout")
+	fs.DurationVar(&cfg.DialTimeout, "dial-timeout", cfg.DialTimeout, "Connection dial timeout")
+	fs.DurationVar(&cfg.KeepAlive, "keep-alive", cfg.KeepAlive, "Keep-alive duration")
+	fs.IntVar(&cfg.RetryAttempts, "retry-attempts", cfg.RetryAttempts, "Number of retry attempts")
+	fs.DurationVar(&cfg.RetryDelay, "retry-delay", cfg.RetryDelay, "Initial retry delay")
+	fs.DurationVar(&cfg.RetryMaxDelay, "retry-max-delay", cfg.RetryMaxDelay, "Maximum retry delay")
+	fs.BoolVar(&cfg.Cache, "cache", cfg.Cache, "Cache GET responses in memory and revalidate them")
+	fs.StringVar(&cfg.Local, "local", cfg.Local, "Work on this store file directly instead of a server")
+	fs.StringVar(&cfg.CACertFile, "ca-cert-file", cfg.CACertFile, "PEM bundle of CAs to trust for HTTPS")
+	fs.StringVar(&cfg.ClientCertFile, "client-cert-file", cfg.ClientCertFile, "PEM client certificate to present")
+	fs.StringVar(&cfg.ClientKeyFile, "client-key-file", cfg.ClientKeyFile, "PEM private key of the client certificate")
+	fs.BoolVar(&cfg.InsecureSkipVerify, "insecure-skip-verify", cfg.InsecureSkipVerify, "Accept any server certificate (testing only)")
 
-	return nil
+	return fs
 }
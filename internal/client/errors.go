@@ -6,6 +6,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
 )
 
 // Sentinel errors for common HTTP status codes.
@@ -13,23 +18,41 @@ var (
 	ErrBadRequest          = errors.New("bad request")
 	ErrUnauthorized        = errors.New("unauthorized")
 	ErrNotFound            = errors.New("not found")
+	ErrPreconditionFailed  = errors.New("precondition failed")
+	ErrTooManyRequests     = errors.New("too many requests")
 	ErrInternalServerError = errors.New("internal server error")
 	ErrServiceUnavailable  = errors.New("service unavailable")
 )
 
+// FieldError is a validation failure for a single field of a request.
+type FieldError = internal.FieldError
+
 // ClientError represents an error from the server with status code and message.
+// Fields is set when the server rejected individual fields of the request.
+// RetryAfter is set when the server asked the client to wait before retrying.
 type ClientError struct {
 	StatusCode int
 	Message    string
+	Fields     []FieldError
+	RetryAfter time.Duration
 	Err        error
 }
 
 // Error implements the error interface.
 func (e *ClientError) Error() string {
+	message := e.Message
+	if len(e.Fields) > 0 {
+		parts := make([]string, len(e.Fields))
+		for i, f := range e.Fields {
+			parts[i] = f.Field + " " + f.Message
+		}
+		message += " (" + strings.Join(parts, "; ") + ")"
+	}
+
 	if e.Err != nil {
-		return fmt.Sprintf("HTTP %d: %s: %v", e.StatusCode, e.Message, e.Err)
+		return fmt.Sprintf("HTTP %d: %s: %v", e.StatusCode, message, e.Err)
 	}
-	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, e.Message)
+	return fmt.Sprintf("HTTP %d: %s", e.StatusCode, message)
 }
 
 // Unwrap returns the wrapped error.
@@ -44,25 +67,52 @@ func parseErrorResponse(resp *http.Response) error {
 		return &ClientError{
 			StatusCode: resp.StatusCode,
 			Message:    "failed to read response body",
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
 			Err:        err,
 		}
 	}
 
+	clientErr := parseErrorBody(resp.StatusCode, body)
+	clientErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	return clientErr
+}
+
+// parseErrorBody builds the error for a response body, using the server's
+// JSON error message if there is one.
+func parseErrorBody(statusCode int, body []byte) *ClientError {
 	// Try to parse JSON error
 	var errResp struct {
-		Error string `json:"error"`
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
 	}
 
 	if err := json.Unmarshal(body, &errResp); err == nil && errResp.Error != "" {
-		return statusCodeToError(resp.StatusCode, errResp.Error)
+		clientErr := statusCodeToError(statusCode, errResp.Error)
+		clientErr.Fields = errResp.Fields
+		return clientErr
 	}
 
 	// Fallback to status text if JSON parsing fails
-	return statusCodeToError(resp.StatusCode, string(body))
+	return statusCodeToError(statusCode, string(body))
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or as
+// an HTTP date. It returns 0 if the header is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
 }
 
 // statusCodeToError converts HTTP status code to appropriate error.
-func statusCodeToError(statusCode int, message string) error {
+func statusCodeToError(statusCode int, message string) *ClientError {
 	// Wrap with sentinel error for easy error checking
 	var sentinelErr error
 	switch statusCode {
@@ -72,6 +122,10 @@ func statusCodeToError(statusCode int, message string) error {
 		sentinelErr = ErrUnauthorized
 	case http.StatusNotFound:
 		sentinelErr = ErrNotFound
+	case http.StatusPreconditionFailed:
+		sentinelErr = ErrPreconditionFailed
+	case http.StatusTooManyRequests:
+		sentinelErr = ErrTooManyRequests
 	case http.StatusInternalServerError:
 		sentinelErr = ErrInternalServerError
 	case http.StatusServiceUnavailable:
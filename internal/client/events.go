@@ -0,0 +1,199 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// maxEventSize bounds a single line of the event stream.
+const maxEventSize = 1 << 20
+
+// Watch subscribes to the server's change feed and returns a channel that
+// receives every change made after the call.
+//
+// If the connection drops, Watch reconnects and resumes after the last change
+// it received, so no change is missed or delivered twice. Reconnecting follows
+// the client's retry settings; the channel is closed when ctx is cancelled or
+// the server cannot be reached again within RetryAttempts.
+// With RetryAttempts set to 0, the channel is closed when the connection drops.
+func (c *Client) Watch(ctx context.Context) (<-chan internal.Event, error) {
+	return c.watch(ctx, "")
+}
+
+// WatchFrom is like Watch, but resumes the feed after the event whose Seq is after,
+// first delivering the changes made since then. If those changes are no longer
+// available, the first event is of type internal.EventReset and the caller
+// should reload the bookmarks it depends on.
+func (c *Client) WatchFrom(ctx context.Context, after uint64) (<-chan internal.Event, error) {
+	return c.watch(ctx, strconv.FormatUint(after, 10))
+}
+
+func (c *Client) watch(ctx context.Context, lastEventID string) (<-chan internal.Event, error) {
+	body, err := c.openEvents(ctx, lastEventID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("watch: %w", err)
+	}
+
+	events := make(chan internal.Event)
+	go func() {
+		defer close(events)
+
+		for {
+			readEvents(body, &lastEventID, func(event internal.Event) bool {
+				select {
+				case events <- event:
+					return true
+				case <-ctx.Done():
+					return false
+				}
+			})
+			body.Close()
+
+			if ctx.Err() != nil || c.config.RetryAttempts == 0 {
+				return
+			}
+			// Reconnecting counts as a retry, so it waits RetryDelay first
+			if body, err = c.openEvents(ctx, lastEventID, 1); err != nil {
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// openEvents connects to the change feed, resuming after lastEventID if it is set.
+// Failed attempts are retried with the same backoff as other requests, starting
+// at the given attempt number.
+func (c *Client) openEvents(ctx context.Context, lastEventID string, firstAttempt int) (io.ReadCloser, error) {
+	var lastErr error
+
+	for attempt := firstAttempt; attempt <= c.config.RetryAttempts; attempt++ {
+		if attempt > 0 {
+			delay := time.Duration(attempt) * c.config.RetryDelay
+			if delay > c.config.RetryMaxDelay {
+				delay = c.config.RetryMaxDelay
+			}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		body, err := c.openEventsOnce(ctx, lastEventID)
+		if err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		lastErr = err
+
+		// Don't retry on client errors (4xx except 429)
+		if clientErr, ok := lastErr.(*ClientError); ok {
+			if clientErr.StatusCode >= 400 && clientErr.StatusCode < 500 && clientErr.StatusCode != 429 {
+				return nil, lastErr
+			}
+		}
+	}
+
+	return nil, lastErr
+}
+
+// openEventsOnce makes a single request for the change feed and returns the stream.
+func (c *Client) openEventsOnce(ctx context.Context, lastEventID string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.config.Host+"/events", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	c.addAuth(req)
+
+	resp, err := c.stream.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, parseErrorResponse(resp)
+	}
+
+	return resp.Body, nil
+}
+
+// readEvents parses a Server-Sent Events stream and passes every change to emit
+// until the stream ends or emit returns false. lastEventID tracks the ID of the
+// last event read, and changes at or before it are skipped, so a resumed stream
+// never repeats one. Comments and events whose data is not a change are ignored.
+func readEvents(r io.Reader, lastEventID *string, emit func(internal.Event) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+
+	var id string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" {
+			// A blank line dispatches the event
+			if !dispatchEvent(id, data, lastEventID, emit) {
+				return nil
+			}
+			id, data = "", nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			id = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// dispatchEvent handles one parsed event and reports whether reading should continue.
+func dispatchEvent(id string, data []string, lastEventID *string, emit func(internal.Event) bool) bool {
+	previous := *lastEventID
+	if id != "" {
+		*lastEventID = id
+	}
+	if len(data) == 0 {
+		return true
+	}
+
+	var event internal.Event
+	if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event); err != nil {
+		return true
+	}
+	if event.Seq == 0 {
+		event.Seq, _ = strconv.ParseUint(id, 10, 64)
+	}
+	if last, err := strconv.ParseUint(previous, 10, 64); err == nil && event.Type != internal.EventReset && event.Seq <= last {
+		// Already delivered before a reconnect
+		return true
+	}
+
+	return emit(event)
+}
@@ -0,0 +1,23 @@
+package internal
+
+import "errors"
+
+var (
+	// ErrNotFound is returned by stores when a bookmark does not exist.
+	ErrNotFound = errors.New("bookmark not found")
+
+	// ErrRevisionMismatch is returned by conditional writes when the bookmark
+	// has been modified since the expected revision.
+	ErrRevisionMismatch = errors.New("bookmark revision mismatch")
+
+	// ErrBatchAborted is reported for operations of an atomic batch that were
+	// not applied because another operation in the batch failed.
+	ErrBatchAborted = errors.New("not applied because another operation in the batch failed")
+
+	// ErrEmptyQuery is returned by searches whose query has no terms to match.
+	ErrEmptyQuery = errors.New("empty search query")
+
+	// ErrStoreLocked is returned when opening a store whose files another
+	// process, such as a running server, already has open.
+	ErrStoreLocked = errors.New("store file is in use by another process")
+)
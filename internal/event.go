@@ -0,0 +1,29 @@
+package internal
+
+import "errors"
+
+// Kinds of change reported in the change feed.
+const (
+	EventAdd    = "add"
+	EventUpdate = "update"
+	EventDelete = "delete"
+
+	// EventReset tells a subscriber that the changes it asked to resume from are
+	// no longer available. It should reload the bookmarks it depends on.
+	EventReset = "reset"
+)
+
+// ErrEventsExpired is returned when resuming a change feed from a sequence
+// number whose following events are no longer buffered.
+var ErrEventsExpired = errors.New("events no longer available")
+
+// Event describes one change to the bookmarks.
+// Seq increases by one with every change and equals the store generation after it.
+// Bookmark is the new state for add and update events, and the removed bookmark for delete events.
+type Event struct {
+	Seq      uint64    `json:"seq"`
+	Type     string    `json:"type"`
+	ID       int       `json:"id,omitempty"`
+	Bookmark *Bookmark `json:"bookmark,omitempty"`
+	Time     int64     `json:"time"` // Unix seconds
+}
@@ -0,0 +1,14 @@
+package internal
+
+// HealthReport describes the state of a server, as returned by GET /health/details.
+// GET /health, which needs no authentication, only sets Status and Checks.
+type HealthReport struct {
+	Status           string            `json:"status"` // "healthy", or "unhealthy" if a readiness check failed
+	Version          string            `json:"version"`
+	StartedAt        int64             `json:"started_at"` // Unix seconds
+	UptimeSeconds    int64             `json:"uptime_seconds"`
+	Bookmarks        *int              `json:"bookmarks,omitempty"` // Bookmarks of the caller's namespace with user accounts
+	LastSnapshotAt   int64             `json:"last_snapshot_at"`    // Unix seconds, 0 if no snapshot succeeded yet
+	SnapshotFailures int               `json:"snapshot_failures"`   // Snapshots failed in a row
+	Checks           map[string]string `json:"checks"`              // Readiness checks: "ok", or why they failed
+}
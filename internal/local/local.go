@@ -0,0 +1,219 @@
+// Package local performs the bookmark operations of the CLI directly on a
+// store file, for use without a running server.
+package local
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+	"github.com/t-eckert/fave/internal/netscape"
+	"github.com/t-eckert/fave/internal/store"
+)
+
+// Client works on a store file the way client.Client works on a server, with
+// the same validation and timestamps that the server applies.
+// It holds the lock on the file until closed, so it cannot be used on a file
+// that a running server has open, nor the other way around.
+type Client struct {
+	store *store.Store
+}
+
+// Open opens the store file at fileName, creating it and its directory if
+// they do not exist. The storage engine is that of the file.
+func Open(fileName string) (*Client, error) {
+	if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+		return nil, fmt.Errorf("creating store directory: %w", err)
+	}
+
+	engine, err := store.FileEngine(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("opening store: %w", err)
+	}
+
+	s, err := store.NewStoreWithEngine(fileName, engine)
+	if errors.Is(err, internal.ErrStoreLocked) {
+		return nil, fmt.Errorf("opening store: %w; if a server is using it, use --host instead of --local", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("opening store: %w", err)
+	}
+
+	return &Client{store: s}, nil
+}
+
+// Close saves a snapshot and releases the store file. Writes are already
+// durable in the write-ahead log, so if the snapshot fails they are folded
+// into the file the next time it is opened instead, but the error is still
+// returned so the command can report it.
+func (c *Client) Close() error {
+	snapshotErr := c.store.SaveSnapshot()
+	closeErr := c.store.Close()
+	if snapshotErr != nil {
+		return fmt.Errorf("saving store: %w", snapshotErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("closing store: %w", closeErr)
+	}
+	return nil
+}
+
+// Add creates a new bookmark and returns its ID.
+func (c *Client) Add(bookmark internal.Bookmark) (int, error) {
+	if err := internal.ValidateBookmark(&bookmark, false); err != nil {
+		return 0, fmt.Errorf("add bookmark: %w", err)
+	}
+
+	now := time.Now().Unix()
+	bookmark.CreatedAt = now
+	bookmark.UpdatedAt = now
+
+	id, err := c.store.Add(bookmark)
+	if err != nil {
+		return 0, fmt.Errorf("add bookmark: %w", err)
+	}
+
+	return id, nil
+}
+
+// ListPage returns one page of bookmarks, filtered and ordered according to opts.
+func (c *Client) ListPage(opts internal.ListOptions) (*internal.ListPage, error) {
+	page, err := c.store.Query(opts)
+	if err != nil {
+		return nil, fmt.Errorf("list bookmarks: %w", err)
+	}
+
+	return &page, nil
+}
+
+// ListAll follows the cursor through every page matching opts and returns all entries.
+func (c *Client) ListAll(opts internal.ListOptions) ([]internal.BookmarkEntry, error) {
+	var entries []internal.BookmarkEntry
+	for {
+		page, err := c.ListPage(opts)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, page.Bookmarks...)
+
+		if page.NextCursor == "" {
+			return entries, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+// Search runs a full-text query and returns up to limit results, best match first.
+// A limit of 0 uses the same default as the server.
+func (c *Client) Search(query string, limit int) ([]internal.SearchResult, error) {
+	if limit <= 0 {
+		limit = internal.DefaultPageSize
+	}
+
+	results, err := c.store.Search(query, min(limit, internal.MaxPageSize))
+	if err != nil {
+		return nil, fmt.Errorf("search bookmarks: %w", err)
+	}
+
+	return results, nil
+}
+
+// Get returns a bookmark by ID.
+func (c *Client) Get(id int) (*internal.Bookmark, error) {
+	bookmark, err := c.store.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("get bookmark: %w", err)
+	}
+
+	return &bookmark, nil
+}
+
+// Patch changes only the fields set in patch and returns the updated bookmark.
+// CreatedAt is preserved and UpdatedAt is set to now.
+func (c *Client) Patch(id int, patch internal.BookmarkPatch) (*internal.Bookmark, error) {
+	existing, err := c.store.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("patch bookmark: %w", err)
+	}
+
+	bookmark := existing
+	if patch.Url != nil {
+		bookmark.Url = *patch.Url
+	}
+	if patch.Name != nil {
+		bookmark.Name = *patch.Name
+	}
+	if patch.Description != nil {
+		bookmark.Description = *patch.Description
+	}
+	if patch.Tags != nil {
+		bookmark.Tags = *patch.Tags
+	}
+	if err := internal.ValidateBookmark(&bookmark, false); err != nil {
+		return nil, fmt.Errorf("patch bookmark: %w", err)
+	}
+	bookmark.UpdatedAt = time.Now().Unix()
+
+	if err := c.store.UpdateIfRevision(id, bookmark, existing.Revision); err != nil {
+		return nil, fmt.Errorf("patch bookmark: %w", err)
+	}
+
+	bookmark.Revision = existing.Revision + 1
+	return &bookmark, nil
+}
+
+// Delete removes a bookmark by ID.
+func (c *Client) Delete(id int) error {
+	if err := c.store.Delete(id); err != nil {
+		return fmt.Errorf("delete bookmark: %w", err)
+	}
+
+	return nil
+}
+
+// Import adds the bookmarks in a bookmark file, skipping invalid bookmarks and
+// URLs that are already stored. With dryRun set nothing is stored and the
+// report describes what an import would do.
+func (c *Client) Import(format string, data []byte, dryRun bool) (*internal.ImportReport, error) {
+	if format != netscape.Format {
+		return nil, fmt.Errorf("import bookmarks: unsupported format: %s", format)
+	}
+
+	bookmarks, err := netscape.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("import bookmarks: %w", err)
+	}
+
+	report, err := internal.ImportBookmarks(c.store, bookmarks, dryRun, false)
+	if err != nil {
+		return nil, fmt.Errorf("import bookmarks: %w", err)
+	}
+
+	return &report, nil
+}
+
+// Export returns every bookmark, ordered by ID, as a bookmark file in format.
+func (c *Client) Export(format string) ([]byte, error) {
+	if format != netscape.Format {
+		return nil, fmt.Errorf("export bookmarks: unsupported format: %s", format)
+	}
+
+	bookmarks := c.store.List()
+	entries := make([]internal.BookmarkEntry, 0, len(bookmarks))
+	for _, id := range slices.Sorted(maps.Keys(bookmarks)) {
+		entries = append(entries, internal.BookmarkEntry{ID: id, Bookmark: bookmarks[id]})
+	}
+
+	var buf bytes.Buffer
+	if err := netscape.Write(&buf, entries); err != nil {
+		return nil, fmt.Errorf("export bookmarks: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,207 @@
+package local_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/t-eckert/fave/internal"
+	"github.com/t-eckert/fave/internal/local"
+	"github.com/t-eckert/fave/internal/netscape"
+	"github.com/t-eckert/fave/internal/store"
+)
+
+func openTemp(t *testing.T) (*local.Client, string) {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), "data", "bookmarks.json")
+	c, err := local.Open(fileName)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	return c, fileName
+}
+
+func testBookmark(name, url string) internal.Bookmark {
+	return internal.Bookmark{Url: url, Name: name, Tags: []string{"test"}}
+}
+
+func TestAddGetPatchDelete(t *testing.T) {
+	c, _ := openTemp(t)
+	defer c.Close()
+
+	id, err := c.Add(testBookmark("Go", "https://go.dev"))
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	bookmark, err := c.Get(id)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if bookmark.CreatedAt == 0 || bookmark.UpdatedAt != bookmark.CreatedAt {
+		t.Errorf("Expected timestamps to be set on add, got %d and %d", bookmark.CreatedAt, bookmark.UpdatedAt)
+	}
+
+	name := "Golang"
+	patched, err := c.Patch(id, internal.BookmarkPatch{Name: &name})
+	if err != nil {
+		t.Fatalf("Patch failed: %v", err)
+	}
+	if patched.Name != "Golang" || patched.Url != "https://go.dev" || patched.Revision != 2 {
+		t.Errorf("Unexpected bookmark after patch: %+v", patched)
+	}
+
+	if err := c.Delete(id); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := c.Get(id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestAdd_Validates(t *testing.T) {
+	c, _ := openTemp(t)
+	defer c.Close()
+
+	_, err := c.Add(testBookmark("", "not a url"))
+	var verr *internal.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	if len(verr.Fields) != 2 {
+		t.Errorf("Expected name and url to be rejected, got %v", verr.Fields)
+	}
+}
+
+func TestListAndSearch(t *testing.T) {
+	c, _ := openTemp(t)
+	defer c.Close()
+
+	for _, b := range []internal.Bookmark{
+		testBookmark("Go", "https://go.dev"),
+		testBookmark("Rust", "https://rust-lang.org"),
+		testBookmark("Zig", "https://ziglang.org"),
+	} {
+		if _, err := c.Add(b); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+
+	entries, err := c.ListAll(internal.ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListAll failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("Expected 3 bookmarks across pages, got %d", len(entries))
+	}
+
+	results, err := c.Search("rust", 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Name != "Rust" {
+		t.Errorf("Expected one result for Rust, got %v", results)
+	}
+}
+
+func TestImportExport(t *testing.T) {
+	c, _ := openTemp(t)
+	defer c.Close()
+
+	if _, err := c.Add(testBookmark("Go", "https://go.dev")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	data, err := c.Export(netscape.Format)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !strings.Contains(string(data), "https://go.dev") {
+		t.Errorf("Expected the bookmark in the export, got %s", data)
+	}
+
+	report, err := c.Import(netscape.Format, data, false)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if report.Imported != 0 || len(report.Duplicates) != 1 {
+		t.Errorf("Expected the bookmark to be reported as a duplicate, got %+v", report)
+	}
+}
+
+func TestClose_PersistsAndReleases(t *testing.T) {
+	c, fileName := openTemp(t)
+
+	id, err := c.Add(testBookmark("Go", "https://go.dev"))
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	c.Close()
+
+	// A server can open the file once the client is closed
+	s, err := store.NewStore(fileName)
+	if err != nil {
+		t.Fatalf("Expected the store to be released: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.Get(id); err != nil {
+		t.Errorf("Expected bookmark %d in the store file: %v", id, err)
+	}
+}
+
+func TestClose_ReportsFailedSnapshot(t *testing.T) {
+	c, fileName := openTemp(t)
+
+	if _, err := c.Add(testBookmark("Go", "https://go.dev")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	// The snapshot cannot be written once its directory is gone
+	if err := os.RemoveAll(filepath.Dir(fileName)); err != nil {
+		t.Fatalf("Failed to remove store directory: %v", err)
+	}
+
+	if err := c.Close(); err == nil {
+		t.Error("Expected Close to report the failed snapshot")
+	}
+}
+
+func TestOpen_LockedByServer(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "bookmarks.json")
+	s, err := store.NewStore(fileName)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := local.Open(fileName); !errors.Is(err, internal.ErrStoreLocked) {
+		t.Fatalf("Expected ErrStoreLocked while a server has the store open, got %v", err)
+	}
+}
+
+func TestOpen_UsesEngineOfFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "bookmarks")
+	s, err := store.NewStoreWithEngine(fileName, store.EngineBTree)
+	if err != nil {
+		t.Fatalf("NewStoreWithEngine failed: %v", err)
+	}
+	if _, err := s.Add(testBookmark("Go", "https://go.dev")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	s.Close()
+
+	c, err := local.Open(fileName)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer c.Close()
+
+	if _, err := c.Get(1); err != nil {
+		t.Errorf("Expected bookmark 1 from the B-tree file: %v", err)
+	}
+}
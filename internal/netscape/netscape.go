@@ -0,0 +1,290 @@
+// Package netscape reads and writes the Netscape bookmark file format,
+// the bookmarks.html file that every major browser can import and export.
+package netscape
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// Format is the name of the Netscape format in import and export requests.
+const Format = "netscape"
+
+// ContentType is the media type of a Netscape bookmark file.
+const ContentType = "text/html; charset=utf-8"
+
+// Parse reads the bookmarks in a Netscape bookmark file.
+// Each bookmark is tagged with the names of the folders it is nested in, plus any
+// tags from a TAGS attribute. ADD_DATE and LAST_MODIFIED become CreatedAt and
+// UpdatedAt; they are zero when absent. Bookmarks are returned in file order and
+// are not validated, so a link without an HREF yields a bookmark with an empty Url.
+func Parse(r io.Reader) ([]internal.Bookmark, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{src: string(data)}
+	return p.parse(), nil
+}
+
+// parser is a small tolerant scanner for the subset of HTML used by bookmark files.
+// Browsers write these files with unclosed <DT> and <p> tags, so it tracks only the
+// elements that carry meaning: folder headings, folder lists, links, and descriptions.
+type parser struct {
+	src string
+	pos int
+
+	folders   []string // Names of the enclosing folder lists; "" for unnamed lists
+	heading   string   // Name of the last folder heading, waiting for its list
+	bookmarks []internal.Bookmark
+}
+
+// tag is an HTML start or end tag.
+type tag struct {
+	name  string // Lowercase
+	end   bool
+	attrs map[string]string // Lowercase names, unescaped values
+}
+
+func (p *parser) parse() []internal.Bookmark {
+	for {
+		t, ok := p.nextTag()
+		if !ok {
+			return p.bookmarks
+		}
+
+		switch {
+		case t.name == "h3" && !t.end:
+			p.heading = strings.TrimSpace(p.textUntil("h3"))
+		case t.name == "dl" && !t.end:
+			p.folders = append(p.folders, p.heading)
+			p.heading = ""
+		case t.name == "dl" && t.end:
+			if len(p.folders) > 0 {
+				p.folders = p.folders[:len(p.folders)-1]
+			}
+		case t.name == "a" && !t.end:
+			p.bookmarks = append(p.bookmarks, p.bookmark(t))
+		case t.name == "dd" && !t.end:
+			// A description belongs to the link right before it
+			if n := len(p.bookmarks); n > 0 {
+				p.bookmarks[n-1].Description = strings.TrimSpace(p.text())
+			}
+		}
+	}
+}
+
+// bookmark builds a bookmark from a link tag and the text that follows it.
+func (p *parser) bookmark(t tag) internal.Bookmark {
+	bookmark := internal.Bookmark{
+		Url:       strings.TrimSpace(t.attrs["href"]),
+		Name:      strings.TrimSpace(p.textUntil("a")),
+		CreatedAt: parseDate(t.attrs["add_date"]),
+		UpdatedAt: parseDate(t.attrs["last_modified"]),
+	}
+
+	for _, folder := range p.folders {
+		if folder != "" && !slices.Contains(bookmark.Tags, folder) {
+			bookmark.Tags = append(bookmark.Tags, folder)
+		}
+	}
+	for _, name := range strings.Split(t.attrs["tags"], ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.Contains(bookmark.Tags, name) {
+			bookmark.Tags = append(bookmark.Tags, name)
+		}
+	}
+
+	return bookmark
+}
+
+// parseDate parses a Unix timestamp attribute, returning zero if it is missing or invalid.
+func parseDate(v string) int64 {
+	unix, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || unix < 0 {
+		return 0
+	}
+	return unix
+}
+
+// nextTag advances past the next tag, skipping text, comments, and doctypes.
+func (p *parser) nextTag() (tag, bool) {
+	for {
+		start := strings.IndexByte(p.src[p.pos:], '<')
+		if start < 0 {
+			p.pos = len(p.src)
+			return tag{}, false
+		}
+		p.pos += start
+
+		if strings.HasPrefix(p.src[p.pos:], "<!--") {
+			end := strings.Index(p.src[p.pos:], "-->")
+			if end < 0 {
+				p.pos = len(p.src)
+				return tag{}, false
+			}
+			p.pos += end + len("-->")
+			continue
+		}
+
+		end := strings.IndexByte(p.src[p.pos:], '>')
+		if end < 0 {
+			p.pos = len(p.src)
+			return tag{}, false
+		}
+		raw := p.src[p.pos+1 : p.pos+end]
+		p.pos += end + 1
+
+		if strings.HasPrefix(raw, "!") || strings.HasPrefix(raw, "?") {
+			continue
+		}
+		return parseTag(raw), true
+	}
+}
+
+// text returns the unescaped text up to the next tag without consuming the tag.
+func (p *parser) text() string {
+	end := strings.IndexByte(p.src[p.pos:], '<')
+	if end < 0 {
+		end = len(p.src) - p.pos
+	}
+	text := p.src[p.pos : p.pos+end]
+	p.pos += end
+	return html.UnescapeString(text)
+}
+
+// textUntil returns the unescaped text up to the end tag of the named element and
+// consumes the end tag. Tags nested inside the element are dropped.
+func (p *parser) textUntil(name string) string {
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		b.WriteString(p.text())
+		if p.pos >= len(p.src) {
+			break
+		}
+		mark := p.pos
+		t, ok := p.nextTag()
+		if !ok {
+			break
+		}
+		if t.end && t.name == name {
+			break
+		}
+		if !t.end && (t.name == "dt" || t.name == "dl" || t.name == "dd") {
+			// The element was never closed; leave the structural tag for the caller
+			p.pos = mark
+			break
+		}
+	}
+	return b.String()
+}
+
+// parseTag splits the inside of a tag into its name and attributes.
+func parseTag(raw string) tag {
+	raw = strings.TrimSuffix(strings.TrimSpace(raw), "/")
+
+	var t tag
+	if strings.HasPrefix(raw, "/") {
+		t.end = true
+		raw = raw[1:]
+	}
+
+	nameEnd := strings.IndexFunc(raw, isSpace)
+	if nameEnd < 0 {
+		nameEnd = len(raw)
+	}
+	t.name = strings.ToLower(raw[:nameEnd])
+	t.attrs = parseAttrs(raw[nameEnd:])
+	return t
+}
+
+// parseAttrs parses name=value pairs, where values may be double-quoted, single-quoted, or bare.
+func parseAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		s = strings.TrimLeftFunc(s, isSpace)
+		if s == "" {
+			return attrs
+		}
+
+		nameEnd := strings.IndexFunc(s, func(r rune) bool { return r == '=' || isSpace(r) })
+		if nameEnd < 0 {
+			attrs[strings.ToLower(s)] = ""
+			return attrs
+		}
+		name := strings.ToLower(s[:nameEnd])
+		s = strings.TrimLeftFunc(s[nameEnd:], isSpace)
+
+		if !strings.HasPrefix(s, "=") {
+			attrs[name] = ""
+			continue
+		}
+		s = strings.TrimLeftFunc(s[1:], isSpace)
+
+		var value string
+		if s != "" && (s[0] == '"' || s[0] == '\'') {
+			quote := s[0]
+			end := strings.IndexByte(s[1:], quote)
+			if end < 0 {
+				value, s = s[1:], ""
+			} else {
+				value, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(s, isSpace)
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+		attrs[name] = html.UnescapeString(value)
+	}
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
+
+// Write writes bookmarks as a Netscape bookmark file.
+// Bookmarks are written in a single list, with their tags in a TAGS attribute,
+// which Firefox imports as tags and Parse reads back.
+func Write(w io.Writer, bookmarks []internal.BookmarkEntry) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString(`<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+`)
+
+	for _, entry := range bookmarks {
+		fmt.Fprintf(bw, `    <DT><A HREF="%s"`, html.EscapeString(entry.Url))
+		if entry.CreatedAt > 0 {
+			fmt.Fprintf(bw, ` ADD_DATE="%d"`, entry.CreatedAt)
+		}
+		if entry.UpdatedAt > 0 {
+			fmt.Fprintf(bw, ` LAST_MODIFIED="%d"`, entry.UpdatedAt)
+		}
+		if len(entry.Tags) > 0 {
+			fmt.Fprintf(bw, ` TAGS="%s"`, html.EscapeString(strings.Join(entry.Tags, ",")))
+		}
+		fmt.Fprintf(bw, ">%s</A>\n", html.EscapeString(entry.Name))
+		if entry.Description != "" {
+			fmt.Fprintf(bw, "    <DD>%s\n", html.EscapeString(entry.Description))
+		}
+	}
+
+	bw.WriteString("</DL><p>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,122 @@
+package netscape_test
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/t-eckert/fave/internal"
+	"github.com/t-eckert/fave/internal/netscape"
+)
+
+// chromeExport is a trimmed-down file in the shape browsers write, with unclosed
+// <DT> and <p> tags, nested folders, and a description.
+const chromeExport = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<!-- This is an automatically generated file.
+     It will be read and overwritten.
+     DO NOT EDIT! -->
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><H3 ADD_DATE="1600000000" PERSONAL_TOOLBAR_FOLDER="true">Bookmarks bar</H3>
+    <DL><p>
+        <DT><A HREF="https://go.dev/" ADD_DATE="1600000100" ICON="data:image/png;base64,AAAA">The Go Programming Language</A>
+        <DT><H3>Reading</H3>
+        <DL><p>
+            <DT><A HREF="https://example.com/a?x=1&amp;y=2" ADD_DATE="1600000200" LAST_MODIFIED="1600000300" TAGS="long read,Go">Tom &amp; Jerry&#39;s &quot;article&quot;</A>
+            <DD>A description
+that spans lines
+        </DL><p>
+    </DL><p>
+    <DT><A HREF=https://unquoted.example>Unquoted</A>
+    <DT><A>No link</A>
+</DL><p>
+`
+
+func TestParse_Structure(t *testing.T) {
+	bookmarks, err := netscape.Parse(strings.NewReader(chromeExport))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	want := []internal.Bookmark{
+		{
+			Url:       "https://go.dev/",
+			Name:      "The Go Programming Language",
+			Tags:      []string{"Bookmarks bar"},
+			CreatedAt: 1600000100,
+		},
+		{
+			Url:         "https://example.com/a?x=1&y=2",
+			Name:        `Tom & Jerry's "article"`,
+			Description: "A description\nthat spans lines",
+			Tags:        []string{"Bookmarks bar", "Reading", "long read", "Go"},
+			CreatedAt:   1600000200,
+			UpdatedAt:   1600000300,
+		},
+		{Url: "https://unquoted.example", Name: "Unquoted"},
+		{Name: "No link"},
+	}
+
+	if len(bookmarks) != len(want) {
+		t.Fatalf("Expected %d bookmarks, got %d: %+v", len(want), len(bookmarks), bookmarks)
+	}
+	for i := range want {
+		got := bookmarks[i]
+		if got.Url != want[i].Url || got.Name != want[i].Name || got.Description != want[i].Description ||
+			got.CreatedAt != want[i].CreatedAt || got.UpdatedAt != want[i].UpdatedAt || !slices.Equal(got.Tags, want[i].Tags) {
+			t.Errorf("Bookmark %d:\n  got  %+v\n  want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	for _, input := range []string{"", "<html><body>not bookmarks</body></html>", "<DL><p><DT><A HREF=\"https://x"} {
+		bookmarks, err := netscape.Parse(strings.NewReader(input))
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", input, err)
+		}
+		if len(bookmarks) != 0 {
+			t.Errorf("Parse(%q) returned %d bookmarks, expected none", input, len(bookmarks))
+		}
+	}
+}
+
+func TestWrite_RoundTrip(t *testing.T) {
+	entries := []internal.BookmarkEntry{
+		{ID: 1, Bookmark: internal.Bookmark{
+			Url:         "https://example.com/?q=a&b=<c>",
+			Name:        `Quotes "and" <angles>`,
+			Description: "Described & detailed",
+			Tags:        []string{"one", "two"},
+			CreatedAt:   1700000000,
+			UpdatedAt:   1700000500,
+		}},
+		{ID: 2, Bookmark: internal.Bookmark{Url: "https://go.dev", Name: "Go"}},
+	}
+
+	var buf bytes.Buffer
+	if err := netscape.Write(&buf, entries); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "<!DOCTYPE NETSCAPE-Bookmark-file-1>") {
+		t.Errorf("Expected Netscape doctype, got %q", buf.String()[:40])
+	}
+
+	bookmarks, err := netscape.Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(bookmarks) != len(entries) {
+		t.Fatalf("Expected %d bookmarks after round trip, got %d", len(entries), len(bookmarks))
+	}
+	for i, entry := range entries {
+		got := bookmarks[i]
+		if got.Url != entry.Url || got.Name != entry.Name || got.Description != entry.Description ||
+			got.CreatedAt != entry.CreatedAt || got.UpdatedAt != entry.UpdatedAt || !slices.Equal(got.Tags, entry.Tags) {
+			t.Errorf("Bookmark %d:\n  got  %+v\n  want %+v", i, got, entry.Bookmark)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sort fields accepted by ListOptions.
+const (
+	SortID        = "id"
+	SortCreatedAt = "created_at"
+	SortUpdatedAt = "updated_at"
+	SortName      = "name"
+)
+
+// Page size limits for paginated listings.
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+// or was issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListOptions controls pagination, ordering, and filtering of a bookmark listing.
+// The zero value lists every bookmark ordered by ID.
+type ListOptions struct {
+	Limit  int    // Maximum bookmarks per page; 0 means DefaultPageSize
+	Cursor string // Opaque cursor returned as NextCursor by the previous page
+	Sort   string // One of the Sort* constants; empty means SortID
+	Desc   bool   // Sort in descending order
+
+	Tags          []string // Only bookmarks carrying all of these tags
+	CreatedAfter  int64    // Only bookmarks created strictly after this Unix time
+	CreatedBefore int64    // Only bookmarks created strictly before this Unix time
+	URLHost       string   // Only bookmarks whose URL has this host
+}
+
+// BookmarkEntry is a bookmark together with its ID.
+type BookmarkEntry struct {
+	ID int `json:"id"`
+	Bookmark
+}
+
+// ListPage is one page of a bookmark listing.
+// NextCursor is empty on the last page.
+type ListPage struct {
+	Bookmarks  []BookmarkEntry `json:"bookmarks"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// listParams are the query parameters understood by ParseListOptions.
+var listParams = []string{"limit", "cursor", "sort", "order", "tag", "created_after", "created_before", "url_host"}
+
+// HasListParams reports whether the query contains any listing parameters.
+func HasListParams(query url.Values) bool {
+	for _, param := range listParams {
+		if query.Has(param) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseListOptions reads ListOptions from URL query parameters.
+// Timestamps may be given as Unix seconds or RFC 3339.
+func ParseListOptions(query url.Values) (ListOptions, error) {
+	var opts ListOptions
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return opts, fmt.Errorf("invalid limit: %s", v)
+		}
+		opts.Limit = limit
+	}
+
+	opts.Cursor = query.Get("cursor")
+
+	opts.Sort = query.Get("sort")
+	switch opts.Sort {
+	case "", SortID, SortCreatedAt, SortUpdatedAt, SortName:
+		// Valid
+	default:
+		return opts, fmt.Errorf("invalid sort: %s (must be id, created_at, updated_at, or name)", opts.Sort)
+	}
+
+	switch order := query.Get("order"); order {
+	case "", "asc":
+		// Valid
+	case "desc":
+		opts.Desc = true
+	default:
+		return opts, fmt.Errorf("invalid order: %s (must be asc or desc)", order)
+	}
+
+	opts.Tags = query["tag"]
+
+	if v := query.Get("created_after"); v != "" {
+		t, err := ParseTime(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid created_after: %w", err)
+		}
+		opts.CreatedAfter = t
+	}
+	if v := query.Get("created_before"); v != "" {
+		t, err := ParseTime(v)
+		if err != nil {
+			return opts, fmt.Errorf("invalid created_before: %w", err)
+		}
+		opts.CreatedBefore = t
+	}
+
+	opts.URLHost = query.Get("url_host")
+
+	return opts, nil
+}
+
+// Values encodes the options as URL query parameters for ParseListOptions.
+func (o ListOptions) Values() url.Values {
+	query := url.Values{}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Cursor != "" {
+		query.Set("cursor", o.Cursor)
+	}
+	if o.Sort != "" {
+		query.Set("sort", o.Sort)
+	}
+	if o.Desc {
+		query.Set("order", "desc")
+	}
+	for _, tag := range o.Tags {
+		query.Add("tag", tag)
+	}
+	if o.CreatedAfter != 0 {
+		query.Set("created_after", strconv.FormatInt(o.CreatedAfter, 10))
+	}
+	if o.CreatedBefore != 0 {
+		query.Set("created_before", strconv.FormatInt(o.CreatedBefore, 10))
+	}
+	if o.URLHost != "" {
+		query.Set("url_host", o.URLHost)
+	}
+	return query
+}
+
+// ParseTime parses a timestamp given as Unix seconds, an RFC 3339 time, or a date (YYYY-MM-DD).
+func ParseTime(v string) (int64, error) {
+	if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return unix, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.Unix(), nil
+	}
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t.Unix(), nil
+	}
+	return 0, fmt.Errorf("%q is not a Unix time, RFC 3339 time, or YYYY-MM-DD date", v)
+}
+
+// Matches reports whether the bookmark passes the filters in the options.
+func (o ListOptions) Matches(bookmark Bookmark) bool {
+	for _, tag := range o.Tags {
+		if !slices.Contains(bookmark.Tags, tag) {
+			return false
+		}
+	}
+	if o.CreatedAfter != 0 && bookmark.CreatedAt <= o.CreatedAfter {
+		return false
+	}
+	if o.CreatedBefore != 0 && bookmark.CreatedAt >= o.CreatedBefore {
+		return false
+	}
+	if o.URLHost != "" {
+		u, err := url.Parse(bookmark.Url)
+		if err != nil || !strings.EqualFold(u.Hostname(), o.URLHost) {
+			return false
+		}
+	}
+	return true
+}
+
+// cursor is the decoded form of ListPage.NextCursor.
+// It records the sort key of the last entry on a page, so the next page starts
+// after it even if bookmarks were added or removed in the meantime.
+// It also records a hash of the filters of the listing, so it cannot be used
+// to page through a listing with other filters.
+type cursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Filter uint64 `json:"f,omitempty"`
+	Int    int64  `json:"i,omitempty"`
+	Str    string `json:"n,omitempty"`
+	ID     int    `json:"id"`
+}
+
+// filterHash identifies the filters in the options, ignoring the order of
+// tags and the case of the host. It is 0 when there are no filters.
+func (o ListOptions) filterHash() uint64 {
+	tags := slices.Compact(slices.Sorted(slices.Values(o.Tags)))
+	if len(tags) == 0 && o.CreatedAfter == 0 && o.CreatedBefore == 0 && o.URLHost == "" {
+		return 0
+	}
+
+	b, _ := json.Marshal(struct {
+		Tags          []string
+		CreatedAfter  int64
+		CreatedBefore int64
+		URLHost       string
+	}{tags, o.CreatedAfter, o.CreatedBefore, strings.ToLower(o.URLHost)})
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+// sortKey is the value an entry is ordered by, with the ID as a tie-breaker.
+type sortKey struct {
+	Int int64
+	Str string
+	ID  int
+}
+
+func keyOf(sort string, entry BookmarkEntry) sortKey {
+	key := sortKey{ID: entry.ID}
+	switch sort {
+	case SortCreatedAt:
+		key.Int = entry.CreatedAt
+	case SortUpdatedAt:
+		key.Int = entry.UpdatedAt
+	case SortName:
+		key.Str = strings.ToLower(entry.Name)
+	}
+	return key
+}
+
+func compareKeys(a, b sortKey) int {
+	if c := cmpInt64(a.Int, b.Int); c != 0 {
+		return c
+	}
+	if c := strings.Compare(a.Str, b.Str); c != 0 {
+		return c
+	}
+	return cmpInt64(int64(a.ID), int64(b.ID))
+}
+
+func cmpInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Paginate filters, sorts, and pages a set of bookmarks according to opts.
+// Stores use it to implement listing queries over their in-memory state.
+func Paginate(bookmarks map[int]Bookmark, opts ListOptions) (ListPage, error) {
+	sort := opts.Sort
+	if sort == "" {
+		sort = SortID
+	}
+
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	limit = min(limit, MaxPageSize)
+
+	var after *sortKey
+	if opts.Cursor != "" {
+		c, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return ListPage{}, err
+		}
+		if c.Sort != sort || c.Desc != opts.Desc || c.Filter != opts.filterHash() {
+			return ListPage{}, ErrInvalidCursor
+		}
+		after = &sortKey{Int: c.Int, Str: c.Str, ID: c.ID}
+	}
+
+	direction := 1
+	if opts.Desc {
+		direction = -1
+	}
+
+	entries := make([]BookmarkEntry, 0, len(bookmarks))
+	for id, bookmark := range bookmarks {
+		if !opts.Matches(bookmark) {
+			continue
+		}
+		entry := BookmarkEntry{ID: id, Bookmark: bookmark}
+		if after != nil && direction*compareKeys(keyOf(sort, entry), *after) <= 0 {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	slices.SortFunc(entries, func(a, b BookmarkEntry) int {
+		return direction * compareKeys(keyOf(sort, a), keyOf(sort, b))
+	})
+
+	page := ListPage{Bookmarks: entries}
+	if len(entries) > limit {
+		page.Bookmarks = entries[:limit]
+		last := keyOf(sort, page.Bookmarks[limit-1])
+		page.NextCursor = encodeCursor(cursor{
+			Sort:   sort,
+			Desc:   opts.Desc,
+			Filter: opts.filterHash(),
+			Int:    last.Int,
+			Str:    last.Str,
+			ID:     last.ID,
+		})
+	}
+
+	return page, nil
+}
+
+// SearchResult is a bookmark matching a search query, with its relevance score.
+type SearchResult struct {
+	BookmarkEntry
+	Score float64 `json:"score"`
+}
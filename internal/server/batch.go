@@ -0,0 +1,158 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// maxBatchBodySize caps the size of a batch request body.
+const maxBatchBodySize = 16 << 20
+
+// BatchBookmarksHandler applies a list of create, update, and delete operations
+// in one request. Bookmarks are validated and stamped as they would be by the
+// single-bookmark endpoints, and the store applies the whole batch under one lock.
+// With "atomic": true, a single failing operation rolls back the batch and the
+// other operations are reported as not applied. Otherwise each operation
+// succeeds or fails on its own. The response lists one result per operation.
+func (s *Server) BatchBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	store := s.storeFor(r)
+
+	var req internal.BatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodySize)).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(req.Operations) == 0:
+		writeJSONError(w, "Batch has no operations", http.StatusBadRequest)
+		return
+	case len(req.Operations) > internal.MaxBatchSize:
+		writeJSONError(w, fmt.Sprintf("Batch has more than %d operations", internal.MaxBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	resp := internal.BatchResponse{
+		Atomic:  req.Atomic,
+		Results: make([]internal.BatchItemResult, len(req.Operations)),
+	}
+
+	// Operations that pass validation, and their positions in the request
+	var ops []internal.BatchOp
+	var positions []int
+	now := time.Now().Unix()
+
+	for i, op := range req.Operations {
+		if err := s.prepareBatchOp(&op, now); err != nil {
+			resp.Results[i] = batchItemResult(op, internal.BatchOpResult{ID: op.ID, Err: err})
+			continue
+		}
+		ops = append(ops, op)
+		positions = append(positions, i)
+	}
+
+	var results []internal.BatchOpResult
+	if req.Atomic && len(ops) < len(req.Operations) {
+		// Nothing reaches the store if any operation is invalid
+		for _, op := range ops {
+			results = append(results, internal.BatchOpResult{ID: op.ID, Err: internal.ErrBatchAborted})
+		}
+	} else if len(ops) > 0 {
+		var err error
+		results, err = store.Batch(ops, req.Atomic)
+		if err != nil {
+			s.logger.Error("batch failed", "error", err, "operations", len(ops))
+			writeJSONError(w, "Failed to save bookmarks", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for j, result := range results {
+		resp.Results[positions[j]] = batchItemResult(ops[j], result)
+	}
+	for _, result := range resp.Results {
+		if result.Error == "" {
+			resp.Applied++
+		}
+	}
+	resp.Aborted = req.Atomic && resp.Applied == 0
+
+	s.logger.Info("batch applied",
+		"atomic", req.Atomic,
+		"operations", len(req.Operations),
+		"applied", resp.Applied,
+		"aborted", resp.Aborted,
+	)
+
+	writeJSON(w, resp, http.StatusOK)
+}
+
+// prepareBatchOp checks that an operation is well formed, validates its bookmark,
+// and sets the timestamps owned by the server.
+func (s *Server) prepareBatchOp(op *internal.BatchOp, now int64) error {
+	switch op.Op {
+	case internal.BatchCreate, internal.BatchUpdate, internal.BatchDelete:
+		// Valid
+	default:
+		return fmt.Errorf("unknown operation %q (must be create, update, or delete)", op.Op)
+	}
+
+	if op.Op != internal.BatchCreate && op.ID <= 0 {
+		return errors.New("bookmark ID is required")
+	}
+	if op.Op == internal.BatchDelete {
+		return nil
+	}
+
+	if op.Bookmark == nil {
+		return errors.New("bookmark is required")
+	}
+	// Copy so normalizing tags does not touch the request
+	bookmark := *op.Bookmark
+	if err := internal.ValidateBookmark(&bookmark, s.config.NormalizeTags); err != nil {
+		return err
+	}
+	// The store keeps CreatedAt on updates
+	bookmark.CreatedAt = now
+	bookmark.UpdatedAt = now
+	op.Bookmark = &bookmark
+
+	return nil
+}
+
+// batchItemResult describes the outcome of one operation with the status and
+// error message the equivalent single-bookmark request would have returned.
+func batchItemResult(op internal.BatchOp, result internal.BatchOpResult) internal.BatchItemResult {
+	item := internal.BatchItemResult{ID: result.ID, Revision: result.Revision}
+
+	var verr *ValidationError
+	switch {
+	case result.Err == nil && op.Op == internal.BatchCreate:
+		item.Status = http.StatusCreated
+	case result.Err == nil:
+		item.Status = http.StatusOK
+	case errors.As(result.Err, &verr):
+		item.Status = http.StatusBadRequest
+		item.Error = "Invalid bookmark"
+		item.Fields = verr.Fields
+	case errors.Is(result.Err, internal.ErrNotFound):
+		item.Status = http.StatusNotFound
+		item.Error = "Bookmark not found"
+	case errors.Is(result.Err, internal.ErrRevisionMismatch):
+		item.Status = http.StatusPreconditionFailed
+		item.Error = "Bookmark has been modified"
+	case errors.Is(result.Err, internal.ErrBatchAborted):
+		item.Status = http.StatusFailedDependency
+		item.Error = "Not applied because another operation in the batch failed"
+	default:
+		item.Status = http.StatusBadRequest
+		item.Error = "Invalid operation: " + result.Err.Error()
+	}
+
+	return item
+}
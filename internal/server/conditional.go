@@ -0,0 +1,127 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// maxReplaceAttempts bounds how often an update without If-Match is retried
+// when the bookmark changes between being read and written.
+const maxReplaceAttempts = 3
+
+// revisionETag returns the strong entity tag for a bookmark revision.
+func revisionETag(revision uint64) string {
+	return `"` + strconv.FormatUint(revision, 10) + `"`
+}
+
+// ifMatch returns the request's If-Match header values joined into one list.
+func ifMatch(r *http.Request) string {
+	return strings.Join(r.Header.Values("If-Match"), ",")
+}
+
+// etagMatches reports whether an If-Match list matches the given revision.
+// If-Match uses strong comparison, so weak tags never match.
+func etagMatches(list string, revision uint64) bool {
+	return etagListContains(list, revisionETag(revision), false)
+}
+
+// etagListContains reports whether a comma-separated list of entity tags contains etag
+// or is "*". With weak comparison, the W/ prefix is ignored on both sides.
+func etagListContains(list, etag string, weak bool) bool {
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	}
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// generationETag returns the entity tag for a listing of the store at the given generation.
+// Every mutation bumps the generation, so the tag changes whenever any bookmark does.
+func generationETag(generation uint64) string {
+	return `"g` + strconv.FormatUint(generation, 10) + `"`
+}
+
+// notModified sets the cache validators for a representation and reports whether
+// the request's conditions show the client already has it, in which case a 304
+// has been written and the handler must not write a body.
+// If-None-Match takes precedence over If-Modified-Since, as in RFC 9110.
+func notModified(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	// Clients may keep the response but must revalidate before reusing it
+	w.Header().Set("Cache-Control", "no-cache")
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if list := strings.Join(r.Header.Values("If-None-Match"), ","); list != "" {
+		if !etagListContains(list, etag, true) {
+			return false
+		}
+	} else if since := r.Header.Get("If-Modified-Since"); since != "" && !modified.IsZero() {
+		t, err := http.ParseTime(since)
+		// Last-Modified has one-second resolution
+		if err != nil || modified.Truncate(time.Second).After(t) {
+			return false
+		}
+	} else {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// replaceBookmark performs a read-modify-write of a bookmark. build receives the
+// current bookmark and returns its replacement, whose timestamps are set here.
+// The write only succeeds if the bookmark is still at the revision that was read.
+// If the request carries If-Match, the current revision must match it or
+// internal.ErrRevisionMismatch is returned; otherwise a concurrent change is
+// retried a few times against the newer revision.
+func (s *Server) replaceBookmark(r *http.Request, id int, build func(existing internal.Bookmark) (internal.Bookmark, error)) (internal.Bookmark, error) {
+	store := s.storeFor(r)
+
+	conditions := ifMatch(r)
+
+	for attempt := 1; ; attempt++ {
+		existing, err := store.Get(id)
+		if err != nil {
+			return internal.Bookmark{}, err
+		}
+		if conditions != "" && !etagMatches(conditions, existing.Revision) {
+			return internal.Bookmark{}, internal.ErrRevisionMismatch
+		}
+
+		bookmark, err := build(existing)
+		if err != nil {
+			return internal.Bookmark{}, err
+		}
+
+		// Timestamps are owned by the server
+		bookmark.CreatedAt = existing.CreatedAt
+		bookmark.UpdatedAt = time.Now().Unix()
+
+		err = store.UpdateIfRevision(id, bookmark, existing.Revision)
+		if errors.Is(err, internal.ErrRevisionMismatch) && conditions == "" && attempt < maxReplaceAttempts {
+			continue
+		}
+		if err != nil {
+			return internal.Bookmark{}, err
+		}
+
+		bookmark.Revision = existing.Revision + 1
+		return bookmark, nil
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
+
+	"github.com/t-eckert/fave/internal"
 )
 
 // Config holds all server configuration.
@@ -17,10 +20,28 @@ type Config struct {
 
 	// Storage settings
 	StoreFileName string `json:"store_file"`
+	StorageEngine string `json:"storage_engine"` // json or btree
+	ForceUnlock   bool   `json:"-"`              // If true, break a store file lock whose holder no longer exists; flag only
+
+	// TLS settings
+	TLSCertFile      string `json:"tls_cert_file"`      // PEM certificate; serve HTTPS when set
+	TLSKeyFile       string `json:"tls_key_file"`       // PEM private key of the certificate
+	TLSSelfSigned    bool   `json:"tls_self_signed"`    // If true, generate a self-signed certificate if there is none
+	HTTPRedirectPort string `json:"http_redirect_port"` // If set, redirect plain HTTP on this port to HTTPS
+
+	// Client certificate settings, for mutual TLS
+	TLSClientCAFile     string           `json:"tls_client_ca_file"`    // PEM bundle of CAs whose client certificates are accepted
+	TLSClientIdentities []ClientIdentity `json:"tls_client_identities"` // Access given to client certificates; config file only
 
 	// Auth settings
 	AuthPassword string `json:"auth_password"`
-	Public       bool   `json:"public"` // If true, allow unauthenticated read access (GET requests)
+	Public       bool   `json:"public"`      // If true, allow unauthenticated read access (GET requests)
+	UsersFile    string `json:"users_file"`  // If set, authenticate user accounts from this file, each with their own bookmarks
+	TokensFile   string `json:"tokens_file"` // API tokens, accepted when any other authentication is configured
+
+	// Rate limit settings, in requests per minute per client (0 = no limit)
+	RateLimitRead  int `json:"rate_limit_read"`  // GET and HEAD requests
+	RateLimitWrite int `json:"rate_limit_write"` // All other requests
 
 	// Logging settings
 	LogLevel string `json:"log_level"` // debug, info, warn, error
@@ -28,6 +49,20 @@ type Config struct {
 
 	// Snapshot settings
 	SnapshotInterval string `json:"snapshot_interval"` // e.g., "1s", "5s", "1m"
+
+	// Validation settings
+	NormalizeTags bool `json:"normalize_tags"` // If true, trim, lowercase, and deduplicate tags
+}
+
+// ClientIdentity gives the client certificates matching Subject access with
+// the given token scopes, signed in as User on servers with user accounts.
+// Subject is one of "cn:<common name>", "dns:<name>", "email:<address>", or
+// "uri:<uri>", matched against the certificate's common name or subject
+// alternative names.
+type ClientIdentity struct {
+	Subject string   `json:"subject"`
+	User    string   `json:"user,omitempty"`
+	Scopes  []string `json:"scopes"`
 }
 
 // DefaultConfig returns a Config with sensible defaults.
@@ -36,30 +71,72 @@ func DefaultConfig() Config {
 		Port:             "8080",
 		Host:             "localhost",
 		StoreFileName:    "./data/bookmarks.json",
+		StorageEngine:    "json",
+		TLSCertFile:      "", // Empty means plain HTTP
+		TLSKeyFile:       "",
+		TLSSelfSigned:    false,
+		HTTPRedirectPort: "", // Empty means no redirect
+		TLSClientCAFile:  "", // Empty means client certificates are not checked
 		AuthPassword:     "", // Empty means no auth required
 		Public:           false,
+		UsersFile:        "", // Empty means a single shared store
+		TokensFile:       "./data/tokens.json",
+		RateLimitRead:    0, // No limit
+		RateLimitWrite:   0, // No limit
 		LogLevel:         "info",
 		LogJSON:          false,
 		SnapshotInterval: "1s",
+		NormalizeTags:    false,
 	}
 }
 
+// newFlagSet defines the flags of fave serve, bound to the fields of flags and
+// defaulting to their current values. The path of the config file is bound to
+// configFile.
+func newFlagSet(flags *Config, configFile *string) *flag.FlagSet {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.StringVar(configFile, "config", "", "Path to config file (JSON)")
+	fs.StringVar(&flags.Port, "port", flags.Port, "Server port")
+	fs.StringVar(&flags.Host, "host", flags.Host, "Server host")
+	fs.StringVar(&flags.StoreFileName, "store-file", flags.StoreFileName, "Path to bookmarks storage file")
+	fs.StringVar(&flags.StorageEngine, "storage-engine", flags.StorageEngine, "Storage engine for the store file (json, btree)")
+	fs.BoolVar(&flags.ForceUnlock, "force-unlock", flags.ForceUnlock, "Break a lock on the store file whose holding process no longer exists")
+	fs.StringVar(&flags.TLSCertFile, "tls-cert-file", flags.TLSCertFile, "Path to TLS certificate (PEM); serves HTTPS when set")
+	fs.StringVar(&flags.TLSKeyFile, "tls-key-file", flags.TLSKeyFile, "Path to TLS private key (PEM)")
+	fs.BoolVar(&flags.TLSSelfSigned, "tls-self-signed", flags.TLSSelfSigned, "Serve HTTPS with a generated self-signed certificate")
+	fs.StringVar(&flags.HTTPRedirectPort, "http-redirect-port", flags.HTTPRedirectPort, "Port to redirect plain HTTP to HTTPS on (empty = none)")
+	fs.StringVar(&flags.TLSClientCAFile, "tls-client-ca-file", flags.TLSClientCAFile, "Path to CA bundle (PEM) for client certificate authentication")
+	fs.StringVar(&flags.AuthPassword, "password", flags.AuthPassword, "Authentication password (empty = no auth)")
+	fs.BoolVar(&flags.Public, "public", flags.Public, "Allow unauthenticated read access (GET requests)")
+	fs.StringVar(&flags.UsersFile, "users-file", flags.UsersFile, "Path to user accounts file (empty = single shared store)")
+	fs.StringVar(&flags.TokensFile, "tokens-file", flags.TokensFile, "Path to API tokens file")
+	fs.IntVar(&flags.RateLimitRead, "rate-limit-read", flags.RateLimitRead, "Read requests per minute per client (0 = no limit)")
+	fs.IntVar(&flags.RateLimitWrite, "rate-limit-write", flags.RateLimitWrite, "Write requests per minute per client (0 = no limit)")
+	fs.StringVar(&flags.LogLevel, "log-level", flags.LogLevel, "Log level (debug, info, warn, error)")
+	fs.BoolVar(&flags.LogJSON, "log-json", flags.LogJSON, "Output logs as JSON")
+	fs.StringVar(&flags.SnapshotInterval, "snapshot-interval", flags.SnapshotInterval, "Snapshot save interval (e.g., 1s, 5s, 1m)")
+	fs.BoolVar(&flags.NormalizeTags, "normalize-tags", flags.NormalizeTags, "Trim, lowercase, and deduplicate tags")
+
+	return fs
+}
+
+// FlagSet returns the flags of fave serve, for commands that take them
+// alongside flags of their own.
+func FlagSet() *flag.FlagSet {
+	flags := DefaultConfig()
+	var configFile string
+	return newFlagSet(&flags, &configFile)
+}
+
 // LoadConfig loads configuration from multiple sources with precedence:
 // CLI flags > Environment variables > Config file > Defaults
 func LoadConfig(args []string) (Config, error) {
 	cfg := DefaultConfig()
 
-	// Create FlagSet with all flags
-	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
-	configFile := fs.String("config", "", "Path to config file (JSON)")
-	port := fs.String("port", cfg.Port, "Server port")
-	host := fs.String("host", cfg.Host, "Server host")
-	storeFile := fs.String("store-file", cfg.StoreFileName, "Path to bookmarks storage file")
-	password := fs.String("password", cfg.AuthPassword, "Authentication password (empty = no auth)")
-	public := fs.Bool("public", cfg.Public, "Allow unauthenticated read access (GET requests)")
-	logLevel := fs.String("log-level", cfg.LogLevel, "Log level (debug, info, warn, error)")
-	logJSON := fs.Bool("log-json", cfg.LogJSON, "Output logs as JSON")
-	snapshotInterval := fs.String("snapshot-interval", cfg.SnapshotInterval, "Snapshot save interval (e.g., 1s, 5s, 1m)")
+	// Create FlagSet with all flags, bound to a copy of the defaults
+	flags := cfg
+	var configFile string
+	fs := newFlagSet(&flags, &configFile)
 
 	// Parse flags
 	if err := fs.Parse(args); err != nil {
@@ -73,8 +150,8 @@ func LoadConfig(args []string) (Config, error) {
 	})
 
 	// 1. Load from config file if specified
-	if *configFile != "" {
-		if err := loadConfigFile(&cfg, *configFile); err != nil {
+	if configFile != "" {
+		if err := loadConfigFile(&cfg, configFile); err != nil {
 			return cfg, fmt.Errorf("loading config file: %w", err)
 		}
 	}
@@ -89,12 +166,50 @@ func LoadConfig(args []string) (Config, error) {
 	if v := os.Getenv("FAVE_STORE_FILE"); v != "" {
 		cfg.StoreFileName = v
 	}
+	if v := os.Getenv("FAVE_STORAGE_ENGINE"); v != "" {
+		cfg.StorageEngine = v
+	}
+	if v := os.Getenv("FAVE_TLS_CERT_FILE"); v != "" {
+		cfg.TLSCertFile = v
+	}
+	if v := os.Getenv("FAVE_TLS_KEY_FILE"); v != "" {
+		cfg.TLSKeyFile = v
+	}
+	if v := os.Getenv("FAVE_TLS_SELF_SIGNED"); v == "true" {
+		cfg.TLSSelfSigned = true
+	}
+	if v := os.Getenv("FAVE_HTTP_REDIRECT_PORT"); v != "" {
+		cfg.HTTPRedirectPort = v
+	}
+	if v := os.Getenv("FAVE_TLS_CLIENT_CA_FILE"); v != "" {
+		cfg.TLSClientCAFile = v
+	}
 	if v := os.Getenv("FAVE_AUTH_PASSWORD"); v != "" {
 		cfg.AuthPassword = v
 	}
 	if v := os.Getenv("FAVE_PUBLIC"); v == "true" {
 		cfg.Public = true
 	}
+	if v := os.Getenv("FAVE_USERS_FILE"); v != "" {
+		cfg.UsersFile = v
+	}
+	if v := os.Getenv("FAVE_TOKENS_FILE"); v != "" {
+		cfg.TokensFile = v
+	}
+	if v := os.Getenv("FAVE_RATE_LIMIT_READ"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid FAVE_RATE_LIMIT_READ: %w", err)
+		}
+		cfg.RateLimitRead = n
+	}
+	if v := os.Getenv("FAVE_RATE_LIMIT_WRITE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid FAVE_RATE_LIMIT_WRITE: %w", err)
+		}
+		cfg.RateLimitWrite = n
+	}
 	if v := os.Getenv("FAVE_LOG_LEVEL"); v != "" {
 		cfg.LogLevel = v
 	}
@@ -104,31 +219,70 @@ func LoadConfig(args []string) (Config, error) {
 	if v := os.Getenv("FAVE_SNAPSHOT_INTERVAL"); v != "" {
 		cfg.SnapshotInterval = v
 	}
+	if v := os.Getenv("FAVE_NORMALIZE_TAGS"); v == "true" {
+		cfg.NormalizeTags = true
+	}
 
 	// 3. Apply CLI flags (highest precedence) - only if explicitly set
 	if explicitFlags["port"] {
-		cfg.Port = *port
+		cfg.Port = flags.Port
 	}
 	if explicitFlags["host"] {
-		cfg.Host = *host
+		cfg.Host = flags.Host
 	}
 	if explicitFlags["store-file"] {
-		cfg.StoreFileName = *storeFile
+		cfg.StoreFileName = flags.StoreFileName
+	}
+	if explicitFlags["storage-engine"] {
+		cfg.StorageEngine = flags.StorageEngine
+	}
+	if explicitFlags["force-unlock"] {
+		cfg.ForceUnlock = flags.ForceUnlock
+	}
+	if explicitFlags["tls-cert-file"] {
+		cfg.TLSCertFile = flags.TLSCertFile
+	}
+	if explicitFlags["tls-key-file"] {
+		cfg.TLSKeyFile = flags.TLSKeyFile
+	}
+	if explicitFlags["tls-self-signed"] {
+		cfg.TLSSelfSigned = flags.TLSSelfSigned
+	}
+	if explicitFlags["http-redirect-port"] {
+		cfg.HTTPRedirectPort = flags.HTTPRedirectPort
+	}
+	if explicitFlags["tls-client-ca-file"] {
+		cfg.TLSClientCAFile = flags.TLSClientCAFile
 	}
 	if explicitFlags["password"] {
-		cfg.AuthPassword = *password
+		cfg.AuthPassword = flags.AuthPassword
 	}
 	if explicitFlags["public"] {
-		cfg.Public = *public
+		cfg.Public = flags.Public
+	}
+	if explicitFlags["users-file"] {
+		cfg.UsersFile = flags.UsersFile
+	}
+	if explicitFlags["tokens-file"] {
+		cfg.TokensFile = flags.TokensFile
+	}
+	if explicitFlags["rate-limit-read"] {
+		cfg.RateLimitRead = flags.RateLimitRead
+	}
+	if explicitFlags["rate-limit-write"] {
+		cfg.RateLimitWrite = flags.RateLimitWrite
 	}
 	if explicitFlags["log-level"] {
-		cfg.LogLevel = *logLevel
+		cfg.LogLevel = flags.LogLevel
 	}
 	if explicitFlags["log-json"] {
-		cfg.LogJSON = *logJSON
+		cfg.LogJSON = flags.LogJSON
 	}
 	if explicitFlags["snapshot-interval"] {
-		cfg.SnapshotInterval = *snapshotInterval
+		cfg.SnapshotInterval = flags.SnapshotInterval
+	}
+	if explicitFlags["normalize-tags"] {
+		cfg.NormalizeTags = flags.NormalizeTags
 	}
 
 	// Validate
@@ -147,6 +301,45 @@ func (c Config) Validate() error {
 	if c.StoreFileName == "" {
 		return fmt.Errorf("store file name cannot be empty")
 	}
+	if c.TokensFile == "" {
+		return fmt.Errorf("tokens file name cannot be empty")
+	}
+	switch c.StorageEngine {
+	case "json", "btree":
+		// Valid
+	default:
+		return fmt.Errorf("invalid storage engine: %s (must be json or btree)", c.StorageEngine)
+	}
+
+	// A certificate needs its key, and a self-signed one is generated into both
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("tls cert file and tls key file must be set together")
+	}
+	if c.HTTPRedirectPort != "" && !c.TLSEnabled() {
+		return fmt.Errorf("http redirect port requires TLS")
+	}
+	if c.HTTPRedirectPort != "" && c.HTTPRedirectPort == c.Port {
+		return fmt.Errorf("http redirect port must differ from port")
+	}
+
+	if err := c.validateClientIdentities(); err != nil {
+		return err
+	}
+
+	// User accounts replace the shared password and public read access
+	if c.UsersFile != "" && c.AuthPassword != "" {
+		return fmt.Errorf("auth password cannot be used with a users file")
+	}
+	if c.UsersFile != "" && c.Public {
+		return fmt.Errorf("public read access cannot be used with a users file")
+	}
+	if c.UsersFile != "" && c.StorageEngine != "json" {
+		return fmt.Errorf("storage engine %s cannot be used with a users file", c.StorageEngine)
+	}
+
+	if c.RateLimitRead < 0 || c.RateLimitWrite < 0 {
+		return fmt.Errorf("rate limits cannot be negative")
+	}
 
 	// Validate log level
 	switch c.LogLevel {
@@ -159,6 +352,38 @@ func (c Config) Validate() error {
 	return nil
 }
 
+// validateClientIdentities checks the client certificate settings.
+func (c Config) validateClientIdentities() error {
+	if c.TLSClientCAFile != "" && !c.TLSEnabled() {
+		return fmt.Errorf("tls client ca file requires TLS")
+	}
+	if len(c.TLSClientIdentities) > 0 && c.TLSClientCAFile == "" {
+		return fmt.Errorf("tls client identities require a tls client ca file")
+	}
+
+	for _, identity := range c.TLSClientIdentities {
+		if _, _, ok := parseCertSubject(identity.Subject); !ok {
+			return fmt.Errorf("invalid client identity subject %q: use cn:, dns:, email:, or uri: followed by a value", identity.Subject)
+		}
+		if len(identity.Scopes) == 0 {
+			return fmt.Errorf("client identity %s needs at least one scope", identity.Subject)
+		}
+		for _, scope := range identity.Scopes {
+			if !internal.ValidScope(scope) {
+				return fmt.Errorf("client identity %s has invalid scope %q", identity.Subject, scope)
+			}
+		}
+		if c.UsersFile != "" && identity.User == "" {
+			return fmt.Errorf("client identity %s needs a user when user accounts are enabled", identity.Subject)
+		}
+		if c.UsersFile == "" && identity.User != "" {
+			return fmt.Errorf("client identity %s has a user, but user accounts are not enabled", identity.Subject)
+		}
+	}
+
+	return nil
+}
+
 // LogLevelValue returns the slog.Level for the configured log level.
 func (c Config) LogLevelValue() slog.Level {
 	switch c.LogLevel {
@@ -173,6 +398,21 @@ func (c Config) LogLevelValue() slog.Level {
 	}
 }
 
+// TLSEnabled reports whether the server serves HTTPS.
+func (c Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" || c.TLSSelfSigned
+}
+
+// TLSFiles returns the certificate and key files to serve HTTPS with.
+// Self-signed certificates are kept next to the store unless files are configured.
+func (c Config) TLSFiles() (certFile, keyFile string) {
+	if c.TLSCertFile == "" && c.TLSSelfSigned {
+		dir := filepath.Dir(c.StoreFileName)
+		return filepath.Join(dir, "tls-cert.pem"), filepath.Join(dir, "tls-key.pem")
+	}
+	return c.TLSCertFile, c.TLSKeyFile
+}
+
 // Addr returns the full address for the server to listen on.
 func (c Config) Addr() string {
 	return c.Host + ":" + c.Port
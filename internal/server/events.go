@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// eventKeepAlive is how often an idle event stream sends a comment, so proxies
+// and clients can tell a quiet stream from a dead connection.
+const eventKeepAlive = 15 * time.Second
+
+// EventsHandler streams changes to bookmarks as Server-Sent Events.
+// Each event's ID is its sequence number, and the stream opens with the ID it
+// starts after. A client that reconnects with the Last-Event-ID header receives
+// the changes it missed; if they are no longer available, the stream starts
+// with a reset event and the client should reload.
+// Without the header, only changes made after connecting are sent.
+func (s *Server) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	store := s.storeFor(r)
+	since := store.CurrentGeneration()
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		seq, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeJSONError(w, "Invalid Last-Event-ID", http.StatusBadRequest)
+			return
+		}
+		since = seq
+	}
+
+	var reset *internal.Event
+	events, cancel, err := store.Subscribe(since)
+	if errors.Is(err, internal.ErrEventsExpired) {
+		since = store.CurrentGeneration()
+		reset = &internal.Event{Seq: since, Type: internal.EventReset, Time: time.Now().Unix()}
+		events, cancel, err = store.Subscribe(since)
+	}
+	if err != nil {
+		s.logger.Error("subscribe failed", "error", err)
+		writeJSONError(w, "Failed to subscribe to events", http.StatusInternalServerError)
+		return
+	}
+	defer cancel()
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.logger.Error("failed to clear write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// Tell the client where the stream starts, so it can resume even if it
+	// disconnects before the first change
+	if reset != nil {
+		err = writeEvent(w, *reset)
+	} else {
+		_, err = fmt.Fprintf(w, "id: %d\n\n", since)
+	}
+	if err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// Dropped for falling behind; the client resumes from its last event
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes one change as a Server-Sent Event.
+func writeEvent(w io.Writer, event internal.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data)
+	return err
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// maxSnapshotFailures is how many snapshots in a row may fail before the
+// server reports that it is not ready.
+const maxSnapshotFailures = 3
+
+// HealthHandler reports whether the server is healthy, with the outcome of the
+// readiness checks. It is served without authentication, so it reports nothing
+// else; HealthDetailsHandler gives the full report. It always responds 200,
+// with a status of "unhealthy" if a check failed; use /readyz to route traffic.
+func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	checks, ready := s.readiness()
+	writeJSON(w, map[string]any{"status": healthStatus(ready), "checks": checks}, http.StatusOK)
+}
+
+// HealthDetailsHandler reports the state of the server: its version, uptime,
+// snapshots, and the number of bookmarks of the caller, along with the outcome
+// of the readiness checks. Unlike /health, it requires authentication.
+func (s *Server) HealthDetailsHandler(w http.ResponseWriter, r *http.Request) {
+	checks, ready := s.readiness()
+	lastSnapshot, failures, _ := s.metrics.snapshotHealth()
+
+	report := internal.HealthReport{
+		Status:           healthStatus(ready),
+		Version:          internal.Version,
+		StartedAt:        s.metrics.started.Unix(),
+		UptimeSeconds:    int64(time.Since(s.metrics.started).Seconds()),
+		SnapshotFailures: failures,
+		Checks:           checks,
+	}
+	if !lastSnapshot.IsZero() {
+		report.LastSnapshotAt = lastSnapshot.Unix()
+	}
+	if store := s.storeFor(r); store != nil {
+		count := store.Count()
+		report.Bookmarks = &count
+	}
+
+	writeJSON(w, report, http.StatusOK)
+}
+
+// healthStatus returns the status reported by the health endpoints.
+func healthStatus(ready bool) string {
+	if !ready {
+		return "unhealthy"
+	}
+	return "healthy"
+}
+
+// isProbePath reports whether a path is a health check or probe endpoint,
+// which are served without authentication or rate limits.
+func isProbePath(path string) bool {
+	return path == "/health" || path == "/livez" || path == "/readyz"
+}
+
+// LivezHandler reports that the server is running and able to answer requests.
+func (s *Server) LivezHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{"status": "ok"}, http.StatusOK)
+}
+
+// ReadyzHandler reports whether the server should be sent traffic. It responds
+// 503 while shutting down, once snapshots have failed maxSnapshotFailures
+// times in a row, or if the store directory cannot be written to.
+func (s *Server) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks, ready := s.readiness()
+	if !ready {
+		writeJSON(w, map[string]any{"status": "not ready", "checks": checks}, http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, map[string]any{"status": "ready", "checks": checks}, http.StatusOK)
+}
+
+// readiness runs the readiness checks, returning the outcome of each, "ok" or
+// why it failed, and whether they all passed.
+func (s *Server) readiness() (map[string]string, bool) {
+	checks := map[string]string{
+		"shutdown":  "ok",
+		"snapshots": "ok",
+		"storage":   "ok",
+	}
+
+	select {
+	case <-s.closing:
+		checks["shutdown"] = "shutting down"
+	default:
+	}
+
+	if _, failures, err := s.metrics.snapshotHealth(); failures >= maxSnapshotFailures {
+		checks["snapshots"] = fmt.Sprintf("%d snapshots failed in a row: %v", failures, err)
+	}
+
+	if err := checkWritable(s.dataDir()); err != nil {
+		checks["storage"] = err.Error()
+	}
+
+	for _, outcome := range checks {
+		if outcome != "ok" {
+			return checks, false
+		}
+	}
+	return checks, true
+}
+
+// dataDir returns the directory the server keeps its data in.
+func (s *Server) dataDir() string {
+	if s.users != nil {
+		return filepath.Dir(s.config.UsersFile)
+	}
+	return filepath.Dir(s.config.StoreFileName)
+}
+
+// checkWritable checks that files can be created in dir.
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".fave-ready-*")
+	if err != nil {
+		return fmt.Errorf("store directory is not writable: %w", err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Brute-force protection for password authentication. A client may fail
+// freeAuthFailures times in a row; each failure after that locks it out for
+// twice as long as the last, starting at baseLockout and capped at maxLockout.
+// Clients that stop failing are forgotten after failureMemory.
+const (
+	freeAuthFailures = 5
+	baseLockout      = time.Second
+	maxLockout       = 15 * time.Minute
+	failureMemory    = 15 * time.Minute
+)
+
+// lockout counts failed authentication attempts per client IP.
+type lockout struct {
+	clients   map[string]*failures
+	lastSweep time.Time
+	now       func() time.Time
+
+	mutex sync.Mutex
+}
+
+// failures records the failed authentication attempts of one client.
+type failures struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func newLockout() *lockout {
+	return &lockout{
+		clients: make(map[string]*failures),
+		now:     time.Now,
+	}
+}
+
+// locked returns how much longer a client is locked out, or 0 if it is not.
+func (l *lockout) locked(ip string) time.Duration {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	f, ok := l.clients[ip]
+	if !ok {
+		return 0
+	}
+	return max(f.lockedUntil.Sub(l.now()), 0)
+}
+
+// fail records a failed attempt by a client. It returns the number of failures
+// in a row and, if the client is now locked out, for how long.
+func (l *lockout) fail(ip string) (int, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > time.Minute {
+		l.forget(now)
+		l.lastSweep = now
+	}
+
+	f, ok := l.clients[ip]
+	if !ok || f.expired(now) {
+		f = &failures{}
+		l.clients[ip] = f
+	}
+	f.count++
+	f.lastFailure = now
+
+	if f.count <= freeAuthFailures {
+		return f.count, 0
+	}
+
+	duration := maxLockout
+	if shift := f.count - freeAuthFailures - 1; shift < 32 {
+		duration = min(baseLockout<<shift, maxLockout)
+	}
+	f.lockedUntil = now.Add(duration)
+
+	return f.count, duration
+}
+
+// succeed clears the failures of a client after it authenticates.
+func (l *lockout) succeed(ip string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	delete(l.clients, ip)
+}
+
+// forget drops clients that have not failed for failureMemory and are no
+// longer locked out, so the map cannot grow without bound.
+// The caller must hold the lock.
+func (l *lockout) forget(now time.Time) {
+	for ip, f := range l.clients {
+		if f.expired(now) {
+			delete(l.clients, ip)
+		}
+	}
+}
+
+// expired reports whether the failures are old enough to be forgotten.
+func (f *failures) expired(now time.Time) bool {
+	return now.Sub(f.lastFailure) > failureMemory && now.After(f.lockedUntil)
+}
+
+// clientIP returns the IP address a request came from. Forwarding headers are
+// ignored, since any client can set them to dodge a lockout.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
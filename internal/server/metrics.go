@@ -0,0 +1,296 @@
+package server
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets are the upper bounds, in seconds, of the request and
+// snapshot duration histograms.
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metrics collects the measurements served at /metrics in the Prometheus
+// text exposition format.
+type Metrics struct {
+	requests         map[requestLabels]*histogram
+	snapshots        histogram
+	snapshotFailures uint64
+	failedInARow     int       // Snapshots failed since the last success
+	lastFailure      error     // Error of the last failed snapshot
+	lastSnapshot     time.Time // Last successful snapshot
+	started          time.Time
+
+	mutex sync.Mutex
+}
+
+// requestLabels identifies a series of request metrics.
+type requestLabels struct {
+	method string
+	route  string
+	status string
+}
+
+// histogram counts observations in durationBuckets.
+type histogram struct {
+	buckets []uint64 // Cumulative counts are computed when written
+	count   uint64
+	sum     float64
+}
+
+// NewMetrics returns an empty set of metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests: make(map[requestLabels]*histogram),
+		started:  time.Now(),
+	}
+}
+
+func (h *histogram) observe(seconds float64) {
+	if h.buckets == nil {
+		h.buckets = make([]uint64, len(durationBuckets))
+	}
+	if i, _ := slices.BinarySearch(durationBuckets, seconds); i < len(durationBuckets) {
+		h.buckets[i]++
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// observeRequest records a request that was answered with status.
+func (m *Metrics) observeRequest(method, route string, status int, duration time.Duration) {
+	labels := requestLabels{method: method, route: route, status: strconv.Itoa(status)}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	h, ok := m.requests[labels]
+	if !ok {
+		h = &histogram{}
+		m.requests[labels] = h
+	}
+	h.observe(duration.Seconds())
+}
+
+// observeSnapshot records a snapshot save that took duration and failed with err, if not nil.
+func (m *Metrics) observeSnapshot(duration time.Duration, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.snapshots.observe(duration.Seconds())
+	if err != nil {
+		m.snapshotFailures++
+		m.failedInARow++
+		m.lastFailure = err
+		return
+	}
+	m.failedInARow = 0
+	m.lastFailure = nil
+	m.lastSnapshot = time.Now()
+}
+
+// snapshotHealth returns when the last snapshot succeeded, how many have
+// failed since, and the error of the last one that did.
+func (m *Metrics) snapshotHealth() (time.Time, int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.lastSnapshot, m.failedInARow, m.lastFailure
+}
+
+// MetricsMiddleware counts requests and measures their latency, labeled with
+// the method, the route pattern they matched in routes, and the status code.
+// Requests that match no route are labeled "unmatched", so clients cannot
+// create new series at will.
+func MetricsMiddleware(metrics *Metrics, routes *http.ServeMux) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			crw := &captureResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+
+			next.ServeHTTP(crw, r)
+
+			method := r.Method
+			switch method {
+			case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+				http.MethodPatch, http.MethodDelete, http.MethodOptions:
+			default:
+				method = "OTHER"
+			}
+
+			route := "unmatched"
+			if _, pattern := routes.Handler(r); pattern != "" {
+				_, route, _ = strings.Cut(pattern, " ")
+			}
+
+			metrics.observeRequest(method, route, crw.statusCode, time.Since(start))
+		})
+	}
+}
+
+// MetricsHandler serves the server's metrics in the Prometheus text format.
+func (s *Server) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	bw := bufio.NewWriter(w)
+	s.metrics.writeTo(bw)
+	s.writeStoreMetrics(bw)
+	writeRuntimeMetrics(bw)
+	bw.Flush()
+}
+
+// writeTo writes the request and snapshot metrics.
+func (m *Metrics) writeTo(w io.Writer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	labels := make([]requestLabels, 0, len(m.requests))
+	for l := range m.requests {
+		labels = append(labels, l)
+	}
+	slices.SortFunc(labels, func(a, b requestLabels) int {
+		return cmp.Or(cmp.Compare(a.route, b.route), cmp.Compare(a.method, b.method), cmp.Compare(a.status, b.status))
+	})
+
+	writeHeader(w, "fave_http_requests_total", "counter", "HTTP requests by method, route, and status.")
+	for _, l := range labels {
+		fmt.Fprintf(w, "fave_http_requests_total{%s} %d\n", l, m.requests[l].count)
+	}
+
+	writeHeader(w, "fave_http_request_duration_seconds", "histogram", "HTTP request latency by method, route, and status.")
+	for _, l := range labels {
+		m.requests[l].writeTo(w, "fave_http_request_duration_seconds", l.String())
+	}
+
+	writeHeader(w, "fave_snapshot_duration_seconds", "histogram", "Time taken to save snapshots.")
+	m.snapshots.writeTo(w, "fave_snapshot_duration_seconds", "")
+
+	writeHeader(w, "fave_snapshot_failures_total", "counter", "Snapshots that failed to save.")
+	fmt.Fprintf(w, "fave_snapshot_failures_total %d\n", m.snapshotFailures)
+
+	writeHeader(w, "fave_snapshot_last_success_timestamp_seconds", "gauge", "Unix time of the last successful snapshot, 0 if none.")
+	fmt.Fprintf(w, "fave_snapshot_last_success_timestamp_seconds %s\n", formatTimestamp(m.lastSnapshot))
+
+	writeHeader(w, "fave_start_time_seconds", "gauge", "Unix time the server started.")
+	fmt.Fprintf(w, "fave_start_time_seconds %s\n", formatTimestamp(m.started))
+}
+
+// writeStoreMetrics writes the size of the store. With user accounts there is
+// no single store, so there is nothing to write.
+func (s *Server) writeStoreMetrics(w io.Writer) {
+	if s.store == nil {
+		return
+	}
+
+	persistedGeneration, _ := s.store.Persisted()
+
+	writeHeader(w, "fave_bookmarks", "gauge", "Bookmarks in the store.")
+	fmt.Fprintf(w, "fave_bookmarks %d\n", s.store.Count())
+
+	writeHeader(w, "fave_store_generation", "gauge", "Mutations applied to the store.")
+	fmt.Fprintf(w, "fave_store_generation %d\n", s.store.CurrentGeneration())
+
+	writeHeader(w, "fave_store_persisted_generation", "gauge", "Last generation saved in a snapshot.")
+	fmt.Fprintf(w, "fave_store_persisted_generation %d\n", persistedGeneration)
+
+	writeHeader(w, "fave_store_file_bytes", "gauge", "Size of the store's snapshot and write-ahead log files.")
+	for _, file := range []struct{ name, path string }{
+		{"snapshot", s.config.StoreFileName},
+		{"wal", s.config.StoreFileName + ".wal"},
+	} {
+		var size int64
+		if info, err := os.Stat(file.path); err == nil {
+			size = info.Size()
+		}
+		fmt.Fprintf(w, "fave_store_file_bytes{file=%s} %d\n", quoteLabel(file.name), size)
+	}
+}
+
+// writeRuntimeMetrics writes Go runtime statistics. The go_info,
+// go_goroutines, and go_memstats_* metrics match those of the official
+// Prometheus client, so existing dashboards work.
+func writeRuntimeMetrics(w io.Writer) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	writeHeader(w, "go_info", "gauge", "Information about the Go environment.")
+	fmt.Fprintf(w, "go_info{version=%s} 1\n", quoteLabel(runtime.Version()))
+
+	writeHeader(w, "go_goroutines", "gauge", "Number of goroutines that currently exist.")
+	fmt.Fprintf(w, "go_goroutines %d\n", runtime.NumGoroutine())
+
+	writeHeader(w, "go_memstats_alloc_bytes", "gauge", "Bytes allocated and still in use.")
+	fmt.Fprintf(w, "go_memstats_alloc_bytes %d\n", mem.Alloc)
+
+	writeHeader(w, "go_memstats_heap_inuse_bytes", "gauge", "Bytes in in-use heap spans.")
+	fmt.Fprintf(w, "go_memstats_heap_inuse_bytes %d\n", mem.HeapInuse)
+
+	writeHeader(w, "go_memstats_sys_bytes", "gauge", "Bytes obtained from the system.")
+	fmt.Fprintf(w, "go_memstats_sys_bytes %d\n", mem.Sys)
+
+	writeHeader(w, "go_gc_cycles_total", "counter", "Completed GC cycles.")
+	fmt.Fprintf(w, "go_gc_cycles_total %d\n", mem.NumGC)
+
+	writeHeader(w, "go_gc_pause_seconds_total", "counter", "Total time spent in GC stop-the-world pauses.")
+	fmt.Fprintf(w, "go_gc_pause_seconds_total %s\n", formatFloat(time.Duration(mem.PauseTotalNs).Seconds()))
+}
+
+// writeTo writes a histogram's buckets, sum, and count, with extra labels if not empty.
+func (h *histogram) writeTo(w io.Writer, name, labels string) {
+	sep := ""
+	if labels != "" {
+		sep = ","
+	}
+
+	var cumulative uint64
+	for i, bound := range durationBuckets {
+		if h.buckets != nil {
+			cumulative += h.buckets[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%s%sle=%s} %d\n", name, labels, sep, quoteLabel(formatFloat(bound)), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s%sle=\"+Inf\"} %d\n", name, labels, sep, h.count)
+
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+}
+
+// String formats the labels for the exposition format.
+func (l requestLabels) String() string {
+	return fmt.Sprintf("method=%s,route=%s,status=%s", quoteLabel(l.method), quoteLabel(l.route), quoteLabel(l.status))
+}
+
+// writeHeader writes the HELP and TYPE lines of a metric.
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// quoteLabel quotes a label value, escaping backslashes, quotes, and newlines.
+func quoteLabel(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return `"` + value + `"`
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatTimestamp formats a time as Unix seconds, or 0 for the zero time.
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(t.UnixMilli())/1e3, 'f', 3, 64)
+}
@@ -2,15 +2,20 @@ package server
 
 import (
 	"context"
-	"encoding/base64"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"runtime/debug"
 	"slices"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
+
+	"github.com/t-eckert/fave/internal"
 )
 
 // Middleware is a function that wraps an http.Handler.
@@ -104,8 +109,9 @@ func CORSMiddleware(allowedOrigins []string) Middleware {
 
 			if allowed {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match, If-None-Match, If-Modified-Since, Last-Event-ID")
+				w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified")
 				w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
 			}
 
@@ -122,66 +128,312 @@ func CORSMiddleware(allowedOrigins []string) Middleware {
 
 // BasicAuthMiddleware implements HTTP Basic Authentication.
 // If publicRead is true, GET requests are allowed without authentication.
+// Clients that repeatedly send a wrong password are locked out for
+// exponentially longer periods, during which they get 429 responses.
 func BasicAuthMiddleware(password string, publicRead bool, logger *slog.Logger) Middleware {
+	failures := newLockout()
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Skip auth for health endpoint
-			if r.URL.Path == "/health" {
+			// Skip auth for health checks and probes
+			if isProbePath(r.URL.Path) {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			// Skip auth for GET requests if public mode is enabled
-			if publicRead && r.Method == http.MethodGet {
+			// Skip auth for requests authenticated with an API token or client certificate
+			if authenticated(r) {
 				next.ServeHTTP(w, r)
 				return
 			}
 
+			// Skip auth for GET requests if public mode is enabled; tokens are never public
+			if publicRead && r.Method == http.MethodGet && !isTokenPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestID, _ := r.Context().Value(requestIDKey).(string)
+			ip := clientIP(r)
+
+			if retryAfter := failures.locked(ip); retryAfter > 0 {
+				logger.Warn("authentication attempt while locked out",
+					"event", "auth_locked_out", "request_id", requestID, "client_ip", ip, "retry_after", retryAfter)
+				rejectLockedOut(w, retryAfter)
+				return
+			}
+
 			// Extract credentials
 			auth := r.Header.Get("Authorization")
 			if auth == "" {
-				requestID, _ := r.Context().Value(requestIDKey).(string)
 				logger.Warn("missing authorization header", "request_id", requestID)
 				requireAuth(w)
 				return
 			}
 
-			// Parse Basic auth
-			const prefix = "Basic "
-			if !strings.HasPrefix(auth, prefix) {
-				logger.Warn("invalid authorization format")
+			// For this simple implementation, we don't care about username
+			_, given, ok := r.BasicAuth()
+			if !ok || !passwordsMatch(given, password) {
+				authFailed(w, failures, logger, requestID, ip, "malformed", !ok)
+				return
+			}
+
+			failures.succeed(ip)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authFailed records a failed authentication attempt by the client at ip,
+// logs it along with attrs, and sends a 401 response.
+func authFailed(w http.ResponseWriter, failures *lockout, logger *slog.Logger, requestID, ip string, attrs ...any) {
+	count, lockedFor := failures.fail(ip)
+	logger.Warn("authentication failed", append([]any{
+		"event", "auth_failure", "request_id", requestID, "client_ip", ip, "failures", count,
+	}, attrs...)...)
+	if lockedFor > 0 {
+		logger.Warn("client locked out",
+			"event", "auth_lockout", "request_id", requestID, "client_ip", ip, "failures", count, "duration", lockedFor)
+	}
+	requireAuth(w)
+}
+
+// passwordsMatch compares passwords in constant time. Both are hashed first so
+// the comparison does not leak the length of the password either.
+func passwordsMatch(given, want string) bool {
+	a, b := sha256.Sum256([]byte(given)), sha256.Sum256([]byte(want))
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1
+}
+
+// UserAuthMiddleware authenticates requests with HTTP Basic Authentication
+// against user accounts, and serves each user from their own namespace.
+// Like BasicAuthMiddleware, it locks out clients that keep failing.
+func UserAuthMiddleware(users UserStore, logger *slog.Logger) Middleware {
+	failures := newLockout()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Skip auth for health checks and probes
+			if isProbePath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Skip auth for requests authenticated with an API token or client certificate
+			if authenticated(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestID, _ := r.Context().Value(requestIDKey).(string)
+			ip := clientIP(r)
+
+			if retryAfter := failures.locked(ip); retryAfter > 0 {
+				logger.Warn("authentication attempt while locked out",
+					"event", "auth_locked_out", "request_id", requestID, "client_ip", ip, "retry_after", retryAfter)
+				rejectLockedOut(w, retryAfter)
+				return
+			}
+
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				logger.Warn("missing or invalid authorization header", "request_id", requestID)
 				requireAuth(w)
 				return
 			}
 
-			decoded, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+			if _, err := users.Authenticate(username, password); err != nil {
+				if !errors.Is(err, internal.ErrInvalidCredentials) && !errors.Is(err, internal.ErrUserDisabled) {
+					logger.Error("authentication error", "request_id", requestID, "error", err)
+					writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+				if errors.Is(err, internal.ErrUserDisabled) {
+					logger.Warn("authentication failed",
+						"event", "auth_disabled_user", "request_id", requestID, "client_ip", ip, "username", username)
+					requireAuth(w)
+					return
+				}
+				authFailed(w, failures, logger, requestID, ip, "username", username)
+				return
+			}
+			failures.succeed(ip)
+
+			store, err := users.Namespace(username)
 			if err != nil {
-				logger.Warn("failed to decode authorization", "error", err)
-				requireAuth(w)
+				logger.Error("failed to open user namespace", "request_id", requestID, "username", username, "error", err)
+				writeJSONError(w, "Internal server error", http.StatusInternalServerError)
 				return
 			}
 
-			// Format is "username:password"
-			credentials := strings.SplitN(string(decoded), ":", 2)
-			if len(credentials) != 2 {
-				logger.Warn("invalid credentials format")
-				requireAuth(w)
+			ctx := context.WithValue(r.Context(), userKey, username)
+			ctx = context.WithValue(ctx, storeKey, store)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TokenAuthMiddleware authenticates requests that carry an API token in an
+// "Authorization: Bearer" header and limits them to the token's scopes.
+// With user accounts, the request is served from the namespace of the token's owner.
+// Requests without a token are passed on to authenticate with a password.
+func TokenAuthMiddleware(tokens TokenStore, users UserStore, logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret, ok := bearerToken(r)
+			if !ok || isProbePath(r.URL.Path) {
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			// For this simple implementation, we don't care about username
-			if credentials[1] != password {
-				requestID, _ := r.Context().Value(requestIDKey).(string)
-				logger.Warn("authentication failed", "request_id", requestID)
-				requireAuth(w)
+			requestID, _ := r.Context().Value(requestIDKey).(string)
+
+			token, err := tokens.Authenticate(secret)
+			if err != nil {
+				if !errors.Is(err, internal.ErrInvalidToken) && !errors.Is(err, internal.ErrTokenExpired) {
+					logger.Error("token authentication error", "request_id", requestID, "error", err)
+					writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+				logger.Warn("token authentication failed", "request_id", requestID, "reason", err)
+				requireToken(w)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			// Tokens only work for the accounts they were created for, and
+			// never without an owner
+			if users == nil && token.Owner != internal.SharedOwner {
+				logger.Warn("token not of the shared store", "request_id", requestID, "token_id", token.ID, "owner", token.Owner)
+				requireToken(w)
+				return
+			}
+			if users != nil {
+				if user, err := users.Lookup(token.Owner); err != nil || user.Disabled {
+					logger.Warn("token of a missing or disabled user", "request_id", requestID, "token_id", token.ID, "username", token.Owner)
+					requireToken(w)
+					return
+				}
+			}
+
+			if scope := requiredScope(r); !token.Allows(scope) {
+				logger.Warn("token lacks scope", "request_id", requestID, "token_id", token.ID, "scope", scope)
+				writeJSONError(w, "Token does not have the "+scope+" scope", http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), tokenKey, token)
+			if users != nil {
+				store, err := users.Namespace(token.Owner)
+				if err != nil {
+					logger.Error("failed to open user namespace", "request_id", requestID, "username", token.Owner, "error", err)
+					writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+				ctx = context.WithValue(ctx, userKey, token.Owner)
+				ctx = context.WithValue(ctx, storeKey, store)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// ClientCertAuthMiddleware authenticates requests made with a client
+// certificate, which the TLS handshake verified against the client CA bundle,
+// and limits them to the scopes of the client identity it matches.
+// With user accounts, the request is served from the namespace of the identity's user.
+// Requests without a certificate are passed on to authenticate with a password,
+// unless required is true; then only public reads and health checks are let through.
+func ClientCertAuthMiddleware(identities []ClientIdentity, users UserStore, required, publicRead bool, logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isProbePath(r.URL.Path) || authenticated(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			requestID, _ := r.Context().Value(requestIDKey).(string)
+
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+				if !required || (publicRead && r.Method == http.MethodGet && !isTokenPath(r.URL.Path)) {
+					next.ServeHTTP(w, r)
+					return
+				}
+				logger.Warn("missing client certificate", "request_id", requestID, "client_ip", clientIP(r))
+				writeJSONError(w, "Client certificate required", http.StatusUnauthorized)
+				return
+			}
+
+			cert := r.TLS.PeerCertificates[0]
+			identity, ok := identityFor(identities, cert)
+			if !ok {
+				logger.Warn("client certificate not authorized",
+					"event", "cert_unauthorized", "request_id", requestID, "client_ip", clientIP(r), "subject", cert.Subject.String())
+				writeJSONError(w, "Client certificate is not authorized", http.StatusForbidden)
+				return
+			}
+			if users != nil {
+				if user, err := users.Lookup(identity.User); err != nil || user.Disabled {
+					logger.Warn("client certificate of a missing or disabled user",
+						"event", "cert_unauthorized", "request_id", requestID, "identity", identity.Subject, "username", identity.User)
+					writeJSONError(w, "Client certificate is not authorized", http.StatusForbidden)
+					return
+				}
+			}
+
+			if scope := requiredScope(r); !internal.ScopesAllow(identity.Scopes, scope) {
+				logger.Warn("client certificate lacks scope", "request_id", requestID, "identity", identity.Subject, "scope", scope)
+				writeJSONError(w, "Client certificate does not have the "+scope+" scope", http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), certKey, identity.Subject)
+			if users != nil {
+				store, err := users.Namespace(identity.User)
+				if err != nil {
+					logger.Error("failed to open user namespace", "request_id", requestID, "username", identity.User, "error", err)
+					writeJSONError(w, "Internal server error", http.StatusInternalServerError)
+					return
+				}
+				ctx = context.WithValue(ctx, userKey, identity.User)
+				ctx = context.WithValue(ctx, storeKey, store)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// authenticated reports whether an earlier middleware authenticated the
+// request with an API token or client certificate.
+func authenticated(r *http.Request) bool {
+	if _, ok := r.Context().Value(tokenKey).(internal.Token); ok {
+		return true
+	}
+	_, ok := r.Context().Value(certKey).(string)
+	return ok
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", false
+	}
+	return auth[len(prefix):], true
+}
+
+// requireToken sends a 401 response for a bearer token that cannot be used.
+func requireToken(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="fave", error="invalid_token"`)
+	writeJSONError(w, "Invalid or expired token", http.StatusUnauthorized)
+}
+
+// rejectLockedOut sends a 429 response to a client that is locked out for retryAfter.
+func rejectLockedOut(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+	writeJSONError(w, "Too many failed authentication attempts; try again later", http.StatusTooManyRequests)
+}
+
 // requireAuth sends a 401 response with WWW-Authenticate header.
 func requireAuth(w http.ResponseWriter) {
 	w.Header().Set("WWW-Authenticate", `Basic realm="fave", charset="UTF-8"`)
@@ -199,10 +451,21 @@ func (crw *captureResponseWriter) WriteHeader(code int) {
 	crw.ResponseWriter.WriteHeader(code)
 }
 
-// Context key for request ID
+// Unwrap returns the wrapped writer, so http.ResponseController can flush streamed responses.
+func (crw *captureResponseWriter) Unwrap() http.ResponseWriter {
+	return crw.ResponseWriter
+}
+
+// Context keys for request-scoped values
 type contextKey string
 
-const requestIDKey contextKey = "request_id"
+const (
+	requestIDKey contextKey = "request_id"
+	userKey      contextKey = "user"  // Name of the signed-in user
+	storeKey     contextKey = "store" // Namespace of the signed-in user
+	tokenKey     contextKey = "token" // API token the request was authenticated with
+	certKey      contextKey = "cert"  // Subject of the client identity the request was authenticated with
+)
 
 // Simple request ID generator
 var requestCounter uint64
@@ -1,11 +1,15 @@
 package server_test
 
 import (
-	"errors"
+	"fmt"
 	"maps"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/t-eckert/fave/internal"
+	"github.com/t-eckert/fave/internal/server"
 )
 
 // MockStore implements StoreInterface for testing.
@@ -14,18 +18,28 @@ type MockStore struct {
 	bookmarks map[int]internal.Bookmark
 	idCounter int
 
+	generation          uint64
+	persistedGeneration uint64
+	persistedAt         time.Time
+	lastModified        time.Time
+
+	events      []internal.Event
+	subscribers map[chan internal.Event]struct{}
+
 	// Hooks for testing error scenarios
 	GetError          error
 	AddError          error
 	UpdateError       error
 	DeleteError       error
+	BatchError        error
 	SaveSnapshotError error
 }
 
 func NewMockStore() *MockStore {
 	return &MockStore{
-		bookmarks: make(map[int]internal.Bookmark),
-		idCounter: 0,
+		bookmarks:   make(map[int]internal.Bookmark),
+		idCounter:   0,
+		subscribers: make(map[chan internal.Event]struct{}),
 	}
 }
 
@@ -39,7 +53,7 @@ func (m *MockStore) Get(id int) (internal.Bookmark, error) {
 
 	bookmark, exists := m.bookmarks[id]
 	if !exists {
-		return internal.Bookmark{}, errors.New("bookmark not found")
+		return internal.Bookmark{}, internal.ErrNotFound
 	}
 
 	return bookmark, nil
@@ -55,16 +69,65 @@ func (m *MockStore) List() map[int]internal.Bookmark {
 	return result
 }
 
-func (m *MockStore) Add(bookmark internal.Bookmark) int {
+func (m *MockStore) Query(opts internal.ListOptions) (internal.ListPage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return internal.Paginate(m.bookmarks, opts)
+}
+
+// Search matches bookmarks whose name contains the query, case-insensitively.
+func (m *MockStore) Search(query string, limit int) ([]internal.SearchResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, internal.ErrEmptyQuery
+	}
+
+	results := []internal.SearchResult{}
+	for _, id := range slices.Sorted(maps.Keys(m.bookmarks)) {
+		bookmark := m.bookmarks[id]
+		if strings.Contains(strings.ToLower(bookmark.Name), query) {
+			results = append(results, internal.SearchResult{
+				BookmarkEntry: internal.BookmarkEntry{ID: id, Bookmark: bookmark},
+				Score:         1,
+			})
+		}
+	}
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (m *MockStore) Add(bookmark internal.Bookmark) (int, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if m.AddError != nil {
+		return 0, m.AddError
+	}
+
 	m.idCounter++
+	bookmark.Revision = 1
 	m.bookmarks[m.idCounter] = bookmark
-	return m.idCounter
+	m.generation++
+	m.lastModified = time.Now()
+	m.publish(internal.EventAdd, m.idCounter, &bookmark)
+	return m.idCounter, nil
 }
 
 func (m *MockStore) Update(id int, bookmark internal.Bookmark) error {
+	return m.update(id, bookmark, nil)
+}
+
+func (m *MockStore) UpdateIfRevision(id int, bookmark internal.Bookmark, revision uint64) error {
+	return m.update(id, bookmark, &revision)
+}
+
+func (m *MockStore) update(id int, bookmark internal.Bookmark, revision *uint64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -72,15 +135,31 @@ func (m *MockStore) Update(id int, bookmark internal.Bookmark) error {
 		return m.UpdateError
 	}
 
-	if _, exists := m.bookmarks[id]; !exists {
-		return errors.New("bookmark not found")
+	existing, exists := m.bookmarks[id]
+	if !exists {
+		return internal.ErrNotFound
+	}
+	if revision != nil && existing.Revision != *revision {
+		return internal.ErrRevisionMismatch
 	}
 
+	bookmark.Revision = existing.Revision + 1
 	m.bookmarks[id] = bookmark
+	m.generation++
+	m.lastModified = time.Now()
+	m.publish(internal.EventUpdate, id, &bookmark)
 	return nil
 }
 
 func (m *MockStore) Delete(id int) error {
+	return m.delete(id, nil)
+}
+
+func (m *MockStore) DeleteIfRevision(id int, revision uint64) error {
+	return m.delete(id, &revision)
+}
+
+func (m *MockStore) delete(id int, revision *uint64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -88,27 +167,152 @@ func (m *MockStore) Delete(id int) error {
 		return m.DeleteError
 	}
 
-	if _, exists := m.bookmarks[id]; !exists {
-		return errors.New("bookmark not found")
+	existing, exists := m.bookmarks[id]
+	if !exists {
+		return internal.ErrNotFound
+	}
+	if revision != nil && existing.Revision != *revision {
+		return internal.ErrRevisionMismatch
 	}
 
 	delete(m.bookmarks, id)
+	m.generation++
+	m.lastModified = time.Now()
+	m.publish(internal.EventDelete, id, &existing)
 	return nil
 }
 
+// Batch applies operations in order through the single-bookmark methods.
+// Atomic batches are rolled back by restoring the state from before the batch.
+func (m *MockStore) Batch(ops []internal.BatchOp, atomic bool) ([]internal.BatchOpResult, error) {
+	if m.BatchError != nil {
+		return nil, m.BatchError
+	}
+
+	m.mu.RLock()
+	saved := maps.Clone(m.bookmarks)
+	savedCounter, savedGeneration := m.idCounter, m.generation
+	m.mu.RUnlock()
+
+	results := make([]internal.BatchOpResult, len(ops))
+	failed := false
+	for i, op := range ops {
+		result := internal.BatchOpResult{ID: op.ID}
+		switch op.Op {
+		case internal.BatchCreate:
+			result.ID, result.Err = m.Add(*op.Bookmark)
+		case internal.BatchUpdate:
+			if existing, err := m.Get(op.ID); err == nil {
+				op.Bookmark.CreatedAt = existing.CreatedAt
+			}
+			result.Err = m.update(op.ID, *op.Bookmark, op.IfRevision)
+		case internal.BatchDelete:
+			result.Err = m.delete(op.ID, op.IfRevision)
+		}
+		if result.Err != nil {
+			failed = true
+		} else if op.Op != internal.BatchDelete {
+			bookmark, _ := m.Get(result.ID)
+			result.Revision = bookmark.Revision
+		}
+		results[i] = result
+	}
+
+	if atomic && failed {
+		m.mu.Lock()
+		m.bookmarks, m.idCounter, m.generation = saved, savedCounter, savedGeneration
+		m.events = slices.DeleteFunc(m.events, func(e internal.Event) bool { return e.Seq > savedGeneration })
+		m.mu.Unlock()
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = internal.BatchOpResult{ID: ops[i].ID, Err: internal.ErrBatchAborted}
+			}
+		}
+	}
+	return results, nil
+}
+
+// Subscribe replays every event recorded by the mock after since and then streams new ones.
+func (m *MockStore) Subscribe(since uint64) (<-chan internal.Event, func(), error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var backlog []internal.Event
+	for _, event := range m.events {
+		if event.Seq > since {
+			backlog = append(backlog, event)
+		}
+	}
+	if since > m.generation || uint64(len(backlog)) != m.generation-since {
+		return nil, nil, internal.ErrEventsExpired
+	}
+
+	ch := make(chan internal.Event, len(backlog)+64)
+	for _, event := range backlog {
+		ch <- event
+	}
+	m.subscribers[ch] = struct{}{}
+
+	cancel := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := m.subscribers[ch]; ok {
+			delete(m.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel, nil
+}
+
+// publish records an event for the current generation. The caller must hold the lock.
+func (m *MockStore) publish(eventType string, id int, bookmark *internal.Bookmark) {
+	event := internal.Event{Seq: m.generation, Type: eventType, ID: id, Bookmark: bookmark, Time: time.Now().Unix()}
+	m.events = append(m.events, event)
+	for ch := range m.subscribers {
+		ch <- event
+	}
+}
+
 func (m *MockStore) SaveSnapshot() error {
 	if m.SaveSnapshotError != nil {
 		return m.SaveSnapshotError
 	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.generation != m.persistedGeneration {
+		m.persistedGeneration = m.generation
+		m.persistedAt = time.Now()
+	}
 	return nil
 }
 
+func (m *MockStore) CurrentGeneration() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.generation
+}
+
+func (m *MockStore) LastModified() time.Time {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastModified
+}
+
+func (m *MockStore) Persisted() (uint64, time.Time) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.persistedGeneration, m.persistedAt
+}
+
 // Helper methods for testing
 
 func (m *MockStore) Seed(bookmarks map[int]internal.Bookmark) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	m.lastModified = time.Now()
 	m.bookmarks = make(map[int]internal.Bookmark, len(bookmarks))
 	for k, v := range bookmarks {
 		m.bookmarks[k] = v
@@ -123,3 +327,165 @@ func (m *MockStore) Count() int {
 	defer m.mu.RUnlock()
 	return len(m.bookmarks)
 }
+
+// MockUsers implements UserStore for testing, with a MockStore per user.
+type MockUsers struct {
+	mu         sync.Mutex
+	passwords  map[string]string
+	disabled   map[string]bool
+	namespaces map[string]*MockStore
+}
+
+func NewMockUsers() *MockUsers {
+	return &MockUsers{
+		passwords:  make(map[string]string),
+		disabled:   make(map[string]bool),
+		namespaces: make(map[string]*MockStore),
+	}
+}
+
+// AddUser creates a user and returns their namespace.
+func (m *MockUsers) AddUser(username, password string) *MockStore {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.passwords[username] = password
+	m.namespaces[username] = NewMockStore()
+	return m.namespaces[username]
+}
+
+func (m *MockUsers) SetDisabled(username string, disabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disabled[username] = disabled
+}
+
+func (m *MockUsers) Authenticate(username, password string) (internal.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expected, ok := m.passwords[username]
+	if !ok || expected != password {
+		return internal.User{}, internal.ErrInvalidCredentials
+	}
+	if m.disabled[username] {
+		return internal.User{}, internal.ErrUserDisabled
+	}
+	return internal.User{Username: username}, nil
+}
+
+func (m *MockUsers) Lookup(username string) (internal.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.passwords[username]; !ok {
+		return internal.User{}, internal.ErrUserNotFound
+	}
+	return internal.User{Username: username, Disabled: m.disabled[username]}, nil
+}
+
+func (m *MockUsers) Namespace(username string) (server.StoreInterface, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	namespace, ok := m.namespaces[username]
+	if !ok {
+		return nil, internal.ErrUserNotFound
+	}
+	return namespace, nil
+}
+
+func (m *MockUsers) SaveSnapshot() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, namespace := range m.namespaces {
+		if err := namespace.SaveSnapshot(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MockTokens implements TokenStore for testing, keeping secrets in plain text.
+type MockTokens struct {
+	mu      sync.Mutex
+	tokens  map[string]internal.Token // Keyed by secret
+	counter int
+}
+
+func NewMockTokens() *MockTokens {
+	return &MockTokens{tokens: make(map[string]internal.Token)}
+}
+
+func (m *MockTokens) Create(owner, name string, scopes []string, expiresAt int64) (internal.Token, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.counter++
+	token := internal.Token{
+		ID:        fmt.Sprintf("t%d", m.counter),
+		Name:      name,
+		Owner:     owner,
+		Scopes:    scopes,
+		CreatedAt: time.Now().Unix(),
+		ExpiresAt: expiresAt,
+	}
+	secret := fmt.Sprintf("secret-%d", m.counter)
+	m.tokens[secret] = token
+	return token, secret, nil
+}
+
+func (m *MockTokens) List(owner string) []internal.Token {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokens := []internal.Token{}
+	for _, token := range m.tokens {
+		if token.Owner == owner {
+			tokens = append(tokens, token)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b internal.Token) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return tokens
+}
+
+func (m *MockTokens) Revoke(owner, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for secret, token := range m.tokens {
+		if token.Owner == owner && token.ID == id {
+			delete(m.tokens, secret)
+			return nil
+		}
+	}
+	return internal.ErrTokenNotFound
+}
+
+func (m *MockTokens) Authenticate(secret string) (internal.Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, ok := m.tokens[secret]
+	if !ok {
+		return internal.Token{}, internal.ErrInvalidToken
+	}
+	if token.Expired(time.Now()) {
+		return internal.Token{}, internal.ErrTokenExpired
+	}
+	return token, nil
+}
+
+func (m *MockTokens) SaveSnapshot() error {
+	return nil
+}
+
+// AddToken stores a token with a known secret, bypassing validation.
+func (m *MockTokens) AddToken(secret string, token internal.Token) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokens[secret] = token
+}
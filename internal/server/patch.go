@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// serverOwnedFields are bookmark fields that clients cannot set through an update.
+var serverOwnedFields = []string{"created_at", "updated_at", "revision"}
+
+// patchError reports a merge patch document that cannot be applied.
+type patchError struct {
+	err error
+}
+
+func (e *patchError) Error() string {
+	return e.err.Error()
+}
+
+// applyMergePatch applies a JSON Merge Patch (RFC 7386) document to a bookmark.
+// Members set to null are reset to their zero value and members absent from the
+// patch are left unchanged. Server-owned fields in the patch are ignored.
+func applyMergePatch(bookmark internal.Bookmark, patch []byte) (internal.Bookmark, error) {
+	var patchDoc any
+	if err := json.Unmarshal(patch, &patchDoc); err != nil {
+		return bookmark, &patchError{err}
+	}
+	patchObj, ok := patchDoc.(map[string]any)
+	if !ok {
+		return bookmark, &patchError{errors.New("merge patch must be a JSON object")}
+	}
+	for _, field := range serverOwnedFields {
+		delete(patchObj, field)
+	}
+
+	current, err := json.Marshal(bookmark)
+	if err != nil {
+		return bookmark, err
+	}
+	var target map[string]any
+	if err := json.Unmarshal(current, &target); err != nil {
+		return bookmark, err
+	}
+
+	merged, err := json.Marshal(mergePatch(target, patchObj))
+	if err != nil {
+		return bookmark, err
+	}
+
+	var result internal.Bookmark
+	if err := json.Unmarshal(merged, &result); err != nil {
+		// A member of the wrong type, such as a string for tags
+		return bookmark, &patchError{err}
+	}
+	return result, nil
+}
+
+// mergePatch implements the MergePatch algorithm from RFC 7386 section 2.
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = make(map[string]any)
+	}
+
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+			continue
+		}
+		targetObj[name] = mergePatch(targetObj[name], value)
+	}
+
+	return targetObj
+}
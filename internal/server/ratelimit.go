@@ -0,0 +1,168 @@
+package server
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimits sets how many requests per minute each client may make.
+// A limit of 0 means no limit.
+type RateLimits struct {
+	Read  int // GET and HEAD requests
+	Write int // All other requests
+}
+
+// RateLimitMiddleware limits how fast each IP address can make requests, with
+// a token bucket per address for reads and another for writes. It goes before
+// authentication, so a client guessing passwords is limited before any is
+// checked. Clients are told their limit in RateLimit-* headers, and get a 429
+// response with Retry-After once they exceed it.
+func RateLimitMiddleware(limits RateLimits, logger *slog.Logger) Middleware {
+	return rateLimitMiddleware(limits, logger, func(r *http.Request) string {
+		return "ip:" + clientIP(r)
+	})
+}
+
+// UserRateLimitMiddleware limits how fast each signed-in user can make
+// requests, wherever they connect from, as RateLimitMiddleware does for IP
+// addresses. It goes after authentication, and lets through requests made
+// without a user account.
+func UserRateLimitMiddleware(limits RateLimits, logger *slog.Logger) Middleware {
+	return rateLimitMiddleware(limits, logger, func(r *http.Request) string {
+		if username := ownerFor(r); username != "" {
+			return "user:" + username
+		}
+		return ""
+	})
+}
+
+// rateLimitMiddleware limits requests by the client that key returns for
+// them; requests for which it returns "" are not limited. The RateLimit-*
+// headers describe the bucket with the fewest requests remaining, when a
+// request is counted by more than one.
+func rateLimitMiddleware(limits RateLimits, logger *slog.Logger, key func(r *http.Request) string) Middleware {
+	reads := newRateLimiter(limits.Read)
+	writes := newRateLimiter(limits.Write)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Never limit health checks and probes
+			if isProbePath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			limiter, class := writes, "write"
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				limiter, class = reads, "read"
+			}
+			client := key(r)
+			if limiter.limit == 0 || client == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed, remaining, reset := limiter.take(client)
+
+			previous, err := strconv.Atoi(w.Header().Get("RateLimit-Remaining"))
+			if err != nil || remaining <= previous {
+				w.Header().Set("RateLimit-Policy", strconv.Itoa(limiter.limit)+";w=60")
+				w.Header().Set("RateLimit-Limit", strconv.Itoa(limiter.limit))
+				w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+				w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+			}
+
+			if !allowed {
+				requestID, _ := r.Context().Value(requestIDKey).(string)
+				logger.Warn("rate limit exceeded",
+					"event", "rate_limited", "request_id", requestID, "client", client, "class", class, "retry_after", reset)
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(reset)))
+				writeJSONError(w, "Rate limit exceeded; try again later", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds rounds a duration up to whole seconds, as used in HTTP headers.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// rateLimiter keeps a token bucket per client, holding up to limit tokens and
+// refilled at limit tokens per minute. Each request takes a token.
+type rateLimiter struct {
+	limit     int
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+
+	mutex sync.Mutex
+}
+
+// bucket is the token bucket of one client.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newRateLimiter(limit int) *rateLimiter {
+	return &rateLimiter{
+		limit:   limit,
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// take takes a token from the bucket of a client. It reports whether there
+// was one, how many are left, and how long until the next request is allowed
+// if it was not, or until the bucket is full again if it was.
+func (l *rateLimiter) take(client string) (bool, int, time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > time.Minute {
+		l.sweep(now)
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit), updated: now}
+		l.buckets[client] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.updated = now
+
+	perToken := time.Minute / time.Duration(l.limit)
+	if b.tokens < 1 {
+		return false, 0, time.Duration((1 - b.tokens) * float64(perToken))
+	}
+
+	b.tokens--
+	return true, int(b.tokens), time.Duration((float64(l.limit) - b.tokens) * float64(perToken))
+}
+
+// refill returns the tokens in a bucket at the given time.
+func (l *rateLimiter) refill(b *bucket, now time.Time) float64 {
+	elapsed := now.Sub(b.updated).Minutes()
+	return min(b.tokens+elapsed*float64(l.limit), float64(l.limit))
+}
+
+// sweep drops full buckets, which are the same as no bucket, so the map only
+// holds clients that made requests recently.
+// The caller must hold the lock.
+func (l *rateLimiter) sweep(now time.Time) {
+	for client, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.limit) {
+			delete(l.buckets, client)
+		}
+	}
+}
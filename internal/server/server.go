@@ -3,8 +3,12 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"mime"
+	"net"
 	"net/http"
 	"strconv"
 	"sync"
@@ -16,25 +20,59 @@ import (
 type Server struct {
 	config Config
 	logger *slog.Logger
-	store  StoreInterface
+	store  StoreInterface // nil when serving user accounts
+	users  UserStore      // nil unless serving user accounts
+	tokens TokenStore     // nil unless API tokens are enabled
+
+	metrics *Metrics
 
 	// HTTP server
-	httpServer *http.Server
+	httpServer     *http.Server
+	redirectServer *http.Server // nil unless redirecting plain HTTP to HTTPS
 
 	// Background snapshot goroutine
 	ticker       *time.Ticker
 	snapshotDone chan struct{}
 
+	// Closed on shutdown to end open event streams
+	closing chan struct{}
+
 	// Graceful shutdown
 	shutdownOnce sync.Once
 	shutdownErr  error
 }
 
+// Option configures an optional feature of a Server.
+type Option func(*Server)
+
+// WithTokens enables authentication with the API tokens in tokens, and the
+// endpoints that manage them.
+func WithTokens(tokens TokenStore) Option {
+	return func(s *Server) {
+		s.tokens = tokens
+	}
+}
+
 // New creates a new Server with the given configuration and store.
-func New(config Config, store StoreInterface, logger *slog.Logger) (*Server, error) {
+func New(config Config, store StoreInterface, logger *slog.Logger, opts ...Option) (*Server, error) {
 	if store == nil {
 		return nil, fmt.Errorf("store cannot be nil")
 	}
+
+	return newServer(config, store, nil, logger, opts)
+}
+
+// NewWithUsers creates a new Server that authenticates requests against user
+// accounts and serves each user the bookmarks in their own namespace.
+func NewWithUsers(config Config, users UserStore, logger *slog.Logger, opts ...Option) (*Server, error) {
+	if users == nil {
+		return nil, fmt.Errorf("users cannot be nil")
+	}
+
+	return newServer(config, nil, users, logger, opts)
+}
+
+func newServer(config Config, store StoreInterface, users UserStore, logger *slog.Logger, opts []Option) (*Server, error) {
 	if logger == nil {
 		return nil, fmt.Errorf("logger cannot be nil")
 	}
@@ -49,8 +87,14 @@ func New(config Config, store StoreInterface, logger *slog.Logger) (*Server, err
 		config:       config,
 		logger:       logger,
 		store:        store,
+		users:        users,
+		metrics:      NewMetrics(),
 		ticker:       time.NewTicker(interval),
 		snapshotDone: make(chan struct{}),
+		closing:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 
 	// Create HTTP server with routes
@@ -62,6 +106,23 @@ func New(config Config, store StoreInterface, logger *slog.Logger) (*Server, err
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	if config.TLSEnabled() {
+		tlsConfig, err := s.tlsConfig()
+		if err != nil {
+			s.ticker.Stop()
+			return nil, err
+		}
+		s.httpServer.TLSConfig = tlsConfig
+
+		if config.HTTPRedirectPort != "" {
+			s.redirectServer = &http.Server{
+				Addr:         net.JoinHostPort(config.Host, config.HTTPRedirectPort),
+				Handler:      s.redirectHandler(),
+				ReadTimeout:  15 * time.Second,
+				WriteTimeout: 15 * time.Second,
+			}
+		}
+	}
 
 	// Start background snapshot loop
 	go s.snapshotLoop()
@@ -69,7 +130,12 @@ func New(config Config, store StoreInterface, logger *slog.Logger) (*Server, err
 	logger.Info("server created",
 		"addr", config.Addr(),
 		"snapshot_interval", interval,
-		"auth_enabled", config.AuthPassword != "",
+		"auth_enabled", s.authEnabled(),
+		"user_accounts", users != nil,
+		"tokens_enabled", s.tokensEnabled(),
+		"tls_enabled", config.TLSEnabled(),
+		"rate_limit_read", config.RateLimitRead,
+		"rate_limit_write", config.RateLimitWrite,
 	)
 
 	return s, nil
@@ -81,34 +147,105 @@ func (s *Server) SetupRoutes() http.Handler {
 
 	// Register handlers
 	mux.HandleFunc("GET /bookmarks", s.GetBookmarksHandler)
+	mux.HandleFunc("GET /bookmarks/search", s.SearchBookmarksHandler)
+	mux.HandleFunc("GET /bookmarks/export", s.ExportBookmarksHandler)
+	mux.HandleFunc("POST /bookmarks/import", s.ImportBookmarksHandler)
+	mux.HandleFunc("POST /bookmarks/batch", s.BatchBookmarksHandler)
 	mux.HandleFunc("GET /bookmarks/{id}", s.GetBookmarkByIDHandler)
 	mux.HandleFunc("POST /bookmarks", s.PostBookmarksHandler)
 	mux.HandleFunc("PUT /bookmarks/{id}", s.PutBookmarksHandler)
+	mux.HandleFunc("PATCH /bookmarks/{id}", s.PatchBookmarksHandler)
 	mux.HandleFunc("DELETE /bookmarks/{id}", s.DeleteBookmarksHandler)
 
-	// Health check endpoint (no auth required)
+	// Change feed
+	mux.HandleFunc("GET /events", s.EventsHandler)
+
+	// API token management
+	if s.tokensEnabled() {
+		mux.HandleFunc("GET /tokens", s.ListTokensHandler)
+		mux.HandleFunc("POST /tokens", s.CreateTokenHandler)
+		mux.HandleFunc("DELETE /tokens/{id}", s.RevokeTokenHandler)
+	}
+
+	// Prometheus metrics
+	mux.HandleFunc("GET /metrics", s.MetricsHandler)
+
+	// Health check and probe endpoints (no auth required)
 	mux.HandleFunc("GET /health", s.HealthHandler)
+	mux.HandleFunc("GET /livez", s.LivezHandler)
+	mux.HandleFunc("GET /readyz", s.ReadyzHandler)
+	mux.HandleFunc("GET /health/details", s.HealthDetailsHandler)
+
+	// Store status endpoint
+	mux.HandleFunc("GET /status", s.StatusHandler)
 
 	// Build middleware chain
 	middlewares := []Middleware{
+		MetricsMiddleware(s.metrics, mux),
 		RecoveryMiddleware(s.logger),
 		LoggingMiddleware(s.logger),
 		CORSMiddleware([]string{"*"}), // Allow all origins for personal project
 	}
 
-	// Add auth middleware if password is configured
-	if s.config.AuthPassword != "" {
+	// Limit clients by IP address before authenticating them, so failed
+	// sign-ins count against the limit
+	limits := RateLimits{Read: s.config.RateLimitRead, Write: s.config.RateLimitWrite}
+	rateLimited := limits.Read > 0 || limits.Write > 0
+	if rateLimited {
+		middlewares = append(middlewares, RateLimitMiddleware(limits, s.logger))
+	}
+
+	// API tokens are checked first, then client certificates; requests with
+	// neither fall through to password auth
+	if s.tokensEnabled() {
+		middlewares = append(middlewares, TokenAuthMiddleware(s.tokens, s.users, s.logger))
+	}
+	if s.config.TLSClientCAFile != "" {
+		required := s.users == nil && s.config.AuthPassword == ""
+		middlewares = append(middlewares, ClientCertAuthMiddleware(s.config.TLSClientIdentities, s.users, required, s.config.Public, s.logger))
+	}
+
+	// Add auth middleware if user accounts or a password are configured
+	switch {
+	case s.users != nil:
+		middlewares = append(middlewares, UserAuthMiddleware(s.users, s.logger))
+	case s.config.AuthPassword != "":
 		middlewares = append(middlewares, BasicAuthMiddleware(s.config.AuthPassword, s.config.Public, s.logger))
 	}
 
+	// Signed-in users are also limited by username, wherever they connect from
+	if rateLimited {
+		middlewares = append(middlewares, UserRateLimitMiddleware(limits, s.logger))
+	}
+
 	return Chain(mux, middlewares...)
 }
 
 // Start begins listening for HTTP requests (blocking).
+// With TLS, it serves HTTPS, and redirects plain HTTP if configured.
 func (s *Server) Start() error {
-	s.logger.Info("starting server", "addr", s.config.Addr())
+	if s.httpServer.TLSConfig == nil {
+		s.logger.Info("starting server", "addr", s.config.Addr())
+
+		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			return fmt.Errorf("server error: %w", err)
+		}
+		return nil
+	}
 
-	if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if s.redirectServer != nil {
+		s.logger.Info("redirecting HTTP to HTTPS", "addr", s.redirectServer.Addr)
+		go func() {
+			if err := s.redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				s.logger.Error("redirect server error", "error", err)
+			}
+		}()
+	}
+
+	s.logger.Info("starting server", "addr", s.config.Addr(), "tls", true)
+
+	// The certificate comes from TLSConfig.GetCertificate
+	if err := s.httpServer.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
 		return fmt.Errorf("server error: %w", err)
 	}
 
@@ -124,9 +261,11 @@ func (s *Server) Close() error {
 		close(s.snapshotDone)
 		s.ticker.Stop()
 
+		// End event streams, which would otherwise hold up the HTTP shutdown
+		close(s.closing)
+
 		// Final snapshot before shutdown
-		s.logger.Info("saving final snapshot")
-		if err := s.store.SaveSnapshot(); err != nil {
+		if err := s.saveFinalSnapshot(); err != nil {
 			s.logger.Error("failed to save final snapshot", "error", err)
 			s.shutdownErr = fmt.Errorf("final snapshot: %w", err)
 			return
@@ -136,6 +275,11 @@ func (s *Server) Close() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		if s.redirectServer != nil {
+			if err := s.redirectServer.Shutdown(ctx); err != nil {
+				s.logger.Error("redirect server shutdown error", "error", err)
+			}
+		}
 		if err := s.httpServer.Shutdown(ctx); err != nil {
 			s.logger.Error("http server shutdown error", "error", err)
 			s.shutdownErr = fmt.Errorf("http shutdown: %w", err)
@@ -155,10 +299,34 @@ func (s *Server) snapshotLoop() {
 	for {
 		select {
 		case <-s.ticker.C:
-			if err := s.store.SaveSnapshot(); err != nil {
+			if s.tokens != nil {
+				if err := s.tokens.SaveSnapshot(); err != nil {
+					s.logger.Error("token save failed", "error", err)
+				}
+			}
+			start := time.Now()
+			if s.users != nil {
+				err := s.users.SaveSnapshot()
+				s.metrics.observeSnapshot(time.Since(start), err)
+				if err != nil {
+					s.logger.Error("snapshot save failed", "error", err)
+				}
+				continue
+			}
+
+			before, _ := s.store.Persisted()
+			err := s.store.SaveSnapshot()
+			s.metrics.observeSnapshot(time.Since(start), err)
+			if err != nil {
 				s.logger.Error("snapshot save failed", "error", err)
-			} else {
-				s.logger.Debug("snapshot saved")
+				continue
+			}
+			// SaveSnapshot is a no-op when nothing changed, so only log actual writes
+			if generation, at := s.store.Persisted(); generation != before {
+				s.logger.Debug("snapshot saved",
+					"persisted_generation", generation,
+					"persisted_at", at,
+				)
 			}
 		case <-s.snapshotDone:
 			s.logger.Debug("snapshot loop stopped")
@@ -167,48 +335,197 @@ func (s *Server) snapshotLoop() {
 	}
 }
 
+// saveFinalSnapshot saves the store, or the namespaces of all users, before shutdown.
+func (s *Server) saveFinalSnapshot() error {
+	if s.tokens != nil {
+		if err := s.tokens.SaveSnapshot(); err != nil {
+			s.logger.Error("failed to save tokens", "error", err)
+		}
+	}
+
+	if s.users != nil {
+		s.logger.Info("saving final snapshots of user namespaces")
+		return s.users.SaveSnapshot()
+	}
+
+	s.logger.Info("saving final snapshot", "generation", s.store.CurrentGeneration())
+	if err := s.store.SaveSnapshot(); err != nil {
+		return err
+	}
+	persistedGeneration, persistedAt := s.store.Persisted()
+	s.logger.Info("final snapshot saved",
+		"persisted_generation", persistedGeneration,
+		"persisted_at", persistedAt,
+	)
+
+	return nil
+}
+
+// authEnabled reports whether requests must authenticate, with a password,
+// user account, or client certificate.
+func (s *Server) authEnabled() bool {
+	return s.users != nil || s.config.AuthPassword != "" || s.config.TLSClientCAFile != ""
+}
+
+// tokensEnabled reports whether API tokens are accepted and can be managed.
+// Without another way to authenticate, anyone could create a token, so tokens
+// are only enabled alongside one.
+func (s *Server) tokensEnabled() bool {
+	return s.tokens != nil && s.authEnabled()
+}
+
+// storeFor returns the store a request is served from: the namespace of the
+// signed-in user when serving user accounts, and the shared store otherwise.
+func (s *Server) storeFor(r *http.Request) StoreInterface {
+	if store, ok := r.Context().Value(storeKey).(StoreInterface); ok {
+		return store
+	}
+	return s.store
+}
+
 // HTTP Handlers
 
+// GetBookmarksHandler lists bookmarks.
+// Without query parameters it returns every bookmark as a map keyed by ID.
+// With any of the pagination, sorting, or filtering parameters it returns a
+// single ordered page along with the cursor for the next one.
+// The ETag changes with every store mutation, so pollers can send If-None-Match
+// and get a 304 when nothing changed.
 func (s *Server) GetBookmarksHandler(w http.ResponseWriter, r *http.Request) {
-	bookmarks := s.store.List()
-	writeJSON(w, bookmarks, http.StatusOK)
+	store := s.storeFor(r)
+
+	// Read the validators before the data, so a concurrent change can only make them stale, never ahead
+	etag := generationETag(store.CurrentGeneration())
+	modified := store.LastModified()
+
+	query := r.URL.Query()
+	if !internal.HasListParams(query) {
+		if notModified(w, r, etag, modified) {
+			return
+		}
+		bookmarks := store.List()
+		writeJSON(w, bookmarks, http.StatusOK)
+		return
+	}
+
+	opts, err := internal.ParseListOptions(query)
+	if err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if notModified(w, r, etag, modified) {
+		return
+	}
+
+	page, err := store.Query(opts)
+	if err != nil {
+		if errors.Is(err, internal.ErrInvalidCursor) {
+			writeJSONError(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		s.logger.Error("query failed", "error", err)
+		writeJSONError(w, "Failed to list bookmarks", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, page, http.StatusOK)
+}
+
+// searchResponse is the body returned by SearchBookmarksHandler.
+type searchResponse struct {
+	Query   string                  `json:"query"`
+	Results []internal.SearchResult `json:"results"`
+}
+
+// SearchBookmarksHandler runs a full-text query given in the q parameter.
+// The optional limit parameter caps the number of results.
+func (s *Server) SearchBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	store := s.storeFor(r)
+
+	query := r.URL.Query().Get("q")
+
+	limit := internal.DefaultPageSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeJSONError(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, internal.MaxPageSize)
+	}
+
+	results, err := store.Search(query, limit)
+	if err != nil {
+		if errors.Is(err, internal.ErrEmptyQuery) {
+			writeJSONError(w, "Search query is required", http.StatusBadRequest)
+			return
+		}
+		s.logger.Error("search failed", "error", err)
+		writeJSONError(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, searchResponse{Query: query, Results: results}, http.StatusOK)
 }
 
 func (s *Server) GetBookmarkByIDHandler(w http.ResponseWriter, r *http.Request) {
+	store := s.storeFor(r)
+
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
 		writeJSONError(w, "Invalid bookmark ID", http.StatusBadRequest)
 		return
 	}
 
-	bookmark, err := s.store.Get(id)
+	bookmark, err := store.Get(id)
 	if err != nil {
 		writeJSONError(w, "Bookmark not found", http.StatusNotFound)
 		return
 	}
 
+	if notModified(w, r, revisionETag(bookmark.Revision), time.Unix(bookmark.UpdatedAt, 0)) {
+		return
+	}
+
 	writeJSON(w, bookmark, http.StatusOK)
 }
 
 func (s *Server) PostBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	store := s.storeFor(r)
+
 	var bookmark internal.Bookmark
 	if err := json.NewDecoder(r.Body).Decode(&bookmark); err != nil {
 		writeJSONError(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
-	if bookmark.Name == "" {
-		writeJSONError(w, "Bookmark name is required", http.StatusBadRequest)
+	if err := internal.ValidateBookmark(&bookmark, s.config.NormalizeTags); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
-	id := s.store.Add(bookmark)
+	// Timestamps are owned by the server
+	now := time.Now().Unix()
+	bookmark.CreatedAt = now
+	bookmark.UpdatedAt = now
+
+	id, err := store.Add(bookmark)
+	if err != nil {
+		s.logger.Error("failed to add bookmark", "error", err)
+		writeJSONError(w, "Failed to save bookmark", http.StatusInternalServerError)
+		return
+	}
 
 	s.logger.Info("bookmark added", "id", id, "name", bookmark.Name)
 
+	// New bookmarks always start at revision 1
+	w.Header().Set("ETag", revisionETag(1))
 	writeJSON(w, map[string]int{"id": id}, http.StatusCreated)
 }
 
+// PutBookmarksHandler replaces a bookmark. CreatedAt is preserved and UpdatedAt is set by the server.
+// If-Match makes the update conditional on the bookmark's current ETag.
 func (s *Server) PutBookmarksHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
@@ -216,31 +533,99 @@ func (s *Server) PutBookmarksHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var bookmark internal.Bookmark
-	if err := json.NewDecoder(r.Body).Decode(&bookmark); err != nil {
+	var replacement internal.Bookmark
+	if err := json.NewDecoder(r.Body).Decode(&replacement); err != nil {
 		writeJSONError(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
-	if err := s.store.Update(id, bookmark); err != nil {
-		writeJSONError(w, "Bookmark not found", http.StatusNotFound)
+	if err := internal.ValidateBookmark(&replacement, s.config.NormalizeTags); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
-	s.logger.Info("bookmark updated", "id", id)
+	bookmark, err := s.replaceBookmark(r, id, func(internal.Bookmark) (internal.Bookmark, error) {
+		return replacement, nil
+	})
+	if err != nil {
+		s.writeUpdateError(w, err)
+		return
+	}
 
+	s.logger.Info("bookmark updated", "id", id, "revision", bookmark.Revision)
+
+	w.Header().Set("ETag", revisionETag(bookmark.Revision))
 	writeJSON(w, map[string]int{"id": id}, http.StatusOK)
 }
 
+// PatchBookmarksHandler applies a JSON Merge Patch (RFC 7386) to a bookmark,
+// changing only the fields present in the request, and returns the result.
+// CreatedAt is preserved and UpdatedAt is set by the server.
+// If-Match makes the update conditional on the bookmark's current ETag.
+func (s *Server) PatchBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSONError(w, "Invalid bookmark ID", http.StatusBadRequest)
+		return
+	}
+
+	switch mediaType(r) {
+	case "", "application/json", "application/merge-patch+json":
+		// Accepted
+	default:
+		writeJSONError(w, "Content-Type must be application/merge-patch+json", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeJSONError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	bookmark, err := s.replaceBookmark(r, id, func(existing internal.Bookmark) (internal.Bookmark, error) {
+		bookmark, err := applyMergePatch(existing, patch)
+		if err != nil {
+			return bookmark, err
+		}
+		return bookmark, internal.ValidateBookmark(&bookmark, s.config.NormalizeTags)
+	})
+	if err != nil {
+		s.writeUpdateError(w, err)
+		return
+	}
+
+	s.logger.Info("bookmark patched", "id", id, "revision", bookmark.Revision)
+
+	w.Header().Set("ETag", revisionETag(bookmark.Revision))
+	writeJSON(w, bookmark, http.StatusOK)
+}
+
+// DeleteBookmarksHandler removes a bookmark.
+// If-Match makes the deletion conditional on the bookmark's current ETag.
 func (s *Server) DeleteBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	store := s.storeFor(r)
+
 	id, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
 		writeJSONError(w, "Invalid bookmark ID", http.StatusBadRequest)
 		return
 	}
 
-	if err := s.store.Delete(id); err != nil {
-		writeJSONError(w, "Bookmark not found", http.StatusNotFound)
+	if conditions := ifMatch(r); conditions != "" {
+		existing, err := store.Get(id)
+		if err == nil && !etagMatches(conditions, existing.Revision) {
+			err = internal.ErrRevisionMismatch
+		}
+		if err == nil {
+			err = store.DeleteIfRevision(id, existing.Revision)
+		}
+		if err != nil {
+			s.writeStoreError(w, err)
+			return
+		}
+	} else if err := store.Delete(id); err != nil {
+		s.writeStoreError(w, err)
 		return
 	}
 
@@ -249,14 +634,77 @@ func (s *Server) DeleteBookmarksHandler(w http.ResponseWriter, r *http.Request)
 	writeJSON(w, map[string]int{"id": id}, http.StatusOK)
 }
 
-func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, map[string]string{"status": "healthy"}, http.StatusOK)
+// statusResponse reports the persistence state of the store.
+type statusResponse struct {
+	Generation          uint64 `json:"generation"`
+	PersistedGeneration uint64 `json:"persisted_generation"`
+	PersistedAt         int64  `json:"persisted_at"` // Unix seconds, 0 if never persisted
+}
+
+func (s *Server) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	store := s.storeFor(r)
+
+	persistedGeneration, persistedAt := store.Persisted()
+
+	status := statusResponse{
+		Generation:          store.CurrentGeneration(),
+		PersistedGeneration: persistedGeneration,
+	}
+	if !persistedAt.IsZero() {
+		status.PersistedAt = persistedAt.Unix()
+	}
+
+	writeJSON(w, status, http.StatusOK)
+}
+
+// writeStoreError maps an error returned by a store mutation to a response.
+// A missing bookmark is a 404 and a failed If-Match is a 412;
+// anything else means the write was not persisted.
+func (s *Server) writeStoreError(w http.ResponseWriter, err error) {
+	if errors.Is(err, internal.ErrNotFound) {
+		writeJSONError(w, "Bookmark not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, internal.ErrRevisionMismatch) {
+		writeJSONError(w, "Bookmark has been modified", http.StatusPreconditionFailed)
+		return
+	}
+
+	s.logger.Error("store write failed", "error", err)
+	writeJSONError(w, "Failed to save bookmark", http.StatusInternalServerError)
+}
+
+// writeUpdateError responds to a failed read-modify-write of a bookmark.
+func (s *Server) writeUpdateError(w http.ResponseWriter, err error) {
+	var verr *ValidationError
+	var perr *patchError
+	switch {
+	case errors.As(err, &verr):
+		writeValidationError(w, verr)
+	case errors.As(err, &perr):
+		writeJSONError(w, "Invalid merge patch: "+perr.Error(), http.StatusBadRequest)
+	default:
+		s.writeStoreError(w, err)
+	}
 }
 
 // ============================================================================
 // Helper functions for JSON responses
 // ============================================================================
 
+// mediaType returns the request's Content-Type without parameters such as charset.
+func mediaType(r *http.Request) string {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return ""
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mt
+}
+
 func writeJSON(w http.ResponseWriter, data any, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -269,3 +717,19 @@ func writeJSON(w http.ResponseWriter, data any, statusCode int) {
 func writeJSONError(w http.ResponseWriter, message string, statusCode int) {
 	writeJSON(w, map[string]string{"error": message}, statusCode)
 }
+
+// writeValidationError responds with 400 and the list of rejected fields.
+func writeValidationError(w http.ResponseWriter, err error) {
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
+	}{
+		Error:  "Invalid bookmark",
+		Fields: verr.Fields,
+	}, http.StatusBadRequest)
+}
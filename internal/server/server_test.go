@@ -1,16 +1,32 @@
 package server_test
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
 	"log/slog"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/t-eckert/fave/internal"
 	"github.com/t-eckert/fave/internal/server"
+	"github.com/t-eckert/fave/internal/storetest"
 )
 
 // Test Helpers
@@ -55,6 +71,16 @@ func testBookmark(name string) internal.Bookmark {
 	}
 }
 
+// MockStore Conformance Tests
+
+// TestMockStore checks that the mock behaves like a real store, so handler
+// tests against it hold for the real thing.
+func TestMockStore(t *testing.T) {
+	storetest.RunInMemory(t, func(t *testing.T, dir string) server.StoreInterface {
+		return NewMockStore()
+	})
+}
+
 // GET /bookmarks Tests
 
 func TestGetBookmarks_Empty(t *testing.T) {
@@ -108,6 +134,151 @@ func TestGetBookmarks_WithData(t *testing.T) {
 	}
 }
 
+func TestGetBookmarks_Paginated(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{
+		1: testBookmark("Charlie"),
+		2: testBookmark("Alpha"),
+		3: testBookmark("Bravo"),
+	})
+
+	srv := createTestServer(t, mockStore, testConfig())
+
+	req := httptest.NewRequest(http.MethodGet, "/bookmarks?sort=name&limit=2", nil)
+	w := httptest.NewRecorder()
+
+	srv.GetBookmarksHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var page internal.ListPage
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(page.Bookmarks) != 2 {
+		t.Fatalf("Expected 2 bookmarks, got %d", len(page.Bookmarks))
+	}
+	if page.Bookmarks[0].Name != "Alpha" || page.Bookmarks[0].ID != 2 {
+		t.Errorf("Expected first entry Alpha (ID 2), got %s (ID %d)", page.Bookmarks[0].Name, page.Bookmarks[0].ID)
+	}
+	if page.NextCursor == "" {
+		t.Fatal("Expected a next cursor")
+	}
+
+	// Fetch the second page
+	req = httptest.NewRequest(http.MethodGet, "/bookmarks?sort=name&limit=2&cursor="+page.NextCursor, nil)
+	w = httptest.NewRecorder()
+
+	srv.GetBookmarksHandler(w, req)
+
+	page = internal.ListPage{}
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(page.Bookmarks) != 1 || page.Bookmarks[0].Name != "Charlie" {
+		t.Errorf("Expected second page [Charlie], got %+v", page.Bookmarks)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("Expected no next cursor on last page, got %q", page.NextCursor)
+	}
+}
+
+func TestGetBookmarks_InvalidListParams(t *testing.T) {
+	mockStore := NewMockStore()
+	srv := createTestServer(t, mockStore, testConfig())
+
+	tests := []string{
+		"/bookmarks?limit=abc",
+		"/bookmarks?sort=color",
+		"/bookmarks?order=sideways",
+		"/bookmarks?created_after=yesterday",
+		"/bookmarks?cursor=garbage",
+	}
+
+	for _, target := range tests {
+		t.Run(target, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, target, nil)
+			w := httptest.NewRecorder()
+
+			srv.GetBookmarksHandler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
+
+// GET /bookmarks/search Tests
+
+func TestSearchBookmarks_Success(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{
+		1: testBookmark("Go Tutorial"),
+		2: testBookmark("Rust Book"),
+	})
+
+	srv := createTestServer(t, mockStore, testConfig())
+
+	req := httptest.NewRequest(http.MethodGet, "/bookmarks/search?q=tutorial", nil)
+	w := httptest.NewRecorder()
+
+	srv.SearchBookmarksHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var result struct {
+		Query   string                  `json:"query"`
+		Results []internal.SearchResult `json:"results"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(result.Results) != 1 || result.Results[0].ID != 1 {
+		t.Errorf("Expected only bookmark 1, got %+v", result.Results)
+	}
+	if result.Results[0].Name != "Go Tutorial" {
+		t.Errorf("Expected name 'Go Tutorial', got '%s'", result.Results[0].Name)
+	}
+}
+
+func TestSearchBookmarks_EmptyQuery(t *testing.T) {
+	mockStore := NewMockStore()
+	srv := createTestServer(t, mockStore, testConfig())
+
+	for _, target := range []string{"/bookmarks/search", "/bookmarks/search?q=go&limit=0"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		w := httptest.NewRecorder()
+
+		srv.SearchBookmarksHandler(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", target, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestSearchBookmarks_RouteTakesPrecedenceOverID(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("Searchable")})
+	srv := createTestServer(t, mockStore, testConfig())
+
+	req := httptest.NewRequest(http.MethodGet, "/bookmarks/search?q=searchable", nil)
+	w := httptest.NewRecorder()
+
+	srv.SetupRoutes().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
 // GET /bookmarks/{id} Tests
 
 func TestGetBookmarkByID_Success(t *testing.T) {
@@ -230,6 +401,147 @@ func TestPostBookmarks_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestPostBookmarks_StoreError(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.AddError = errors.New("disk full")
+	srv := createTestServer(t, mockStore, testConfig())
+
+	body, _ := json.Marshal(testBookmark("Test"))
+	req := httptest.NewRequest(http.MethodPost, "/bookmarks", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	srv.PostBookmarksHandler(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestPostBookmarks_ServerOwnsTimestamps(t *testing.T) {
+	mockStore := NewMockStore()
+	srv := createTestServer(t, mockStore, testConfig())
+
+	bookmark := testBookmark("Backdated")
+	bookmark.CreatedAt = 1
+	bookmark.UpdatedAt = 1
+	body, _ := json.Marshal(bookmark)
+
+	before := time.Now().Unix()
+	req := httptest.NewRequest(http.MethodPost, "/bookmarks", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	srv.PostBookmarksHandler(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	stored, _ := mockStore.Get(1)
+	if stored.CreatedAt < before || stored.UpdatedAt != stored.CreatedAt {
+		t.Errorf("Expected server-stamped timestamps, got created_at=%d updated_at=%d", stored.CreatedAt, stored.UpdatedAt)
+	}
+}
+
+func TestPostBookmarks_ValidationErrors(t *testing.T) {
+	manyTags := make([]string, server.MaxTags+1)
+	for i := range manyTags {
+		manyTags[i] = fmt.Sprintf("tag%d", i)
+	}
+
+	tests := []struct {
+		name     string
+		bookmark internal.Bookmark
+		fields   []string
+	}{
+		{"missing url", internal.Bookmark{Name: "Test"}, []string{"url"}},
+		{"relative url", internal.Bookmark{Name: "Test", Url: "not a url"}, []string{"url"}},
+		{"unsupported scheme", internal.Bookmark{Name: "Test", Url: "javascript:alert(1)"}, []string{"url"}},
+		{"missing host", internal.Bookmark{Name: "Test", Url: "https:///path"}, []string{"url"}},
+		{"long name", internal.Bookmark{Name: strings.Repeat("n", server.MaxNameLength+1), Url: "https://example.com"}, []string{"name"}},
+		{"long description", internal.Bookmark{Name: "Test", Url: "https://example.com", Description: strings.Repeat("d", server.MaxDescriptionLength+1)}, []string{"description"}},
+		{"too many tags", internal.Bookmark{Name: "Test", Url: "https://example.com", Tags: manyTags}, []string{"tags"}},
+		{"blank and long tags", internal.Bookmark{Name: "Test", Url: "https://example.com", Tags: []string{"ok", " ", strings.Repeat("t", server.MaxTagLength+1)}}, []string{"tags[1]", "tags[2]"}},
+		{"several fields", internal.Bookmark{Url: "ftp://example.com"}, []string{"name", "url"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := NewMockStore()
+			srv := createTestServer(t, mockStore, testConfig())
+
+			body, _ := json.Marshal(tt.bookmark)
+			req := httptest.NewRequest(http.MethodPost, "/bookmarks", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			srv.PostBookmarksHandler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+
+			var result struct {
+				Error  string              `json:"error"`
+				Fields []server.FieldError `json:"fields"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			var fields []string
+			for _, f := range result.Fields {
+				fields = append(fields, f.Field)
+			}
+			if !slices.Equal(fields, tt.fields) {
+				t.Errorf("Expected errors for fields %v, got %v", tt.fields, result.Fields)
+			}
+
+			if mockStore.Count() != 0 {
+				t.Errorf("Expected nothing stored, got %d bookmarks", mockStore.Count())
+			}
+		})
+	}
+}
+
+func TestPostBookmarks_AcceptsFileURL(t *testing.T) {
+	mockStore := NewMockStore()
+	srv := createTestServer(t, mockStore, testConfig())
+
+	body, _ := json.Marshal(internal.Bookmark{Name: "Notes", Url: "file:///home/me/notes.txt"})
+	req := httptest.NewRequest(http.MethodPost, "/bookmarks", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	srv.PostBookmarksHandler(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+}
+
+func TestPostBookmarks_NormalizeTags(t *testing.T) {
+	mockStore := NewMockStore()
+	cfg := testConfig()
+	cfg.NormalizeTags = true
+	srv := createTestServer(t, mockStore, cfg)
+
+	bookmark := testBookmark("Tagged")
+	bookmark.Tags = []string{" Go ", "go", "", "Web"}
+	body, _ := json.Marshal(bookmark)
+
+	req := httptest.NewRequest(http.MethodPost, "/bookmarks", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	srv.PostBookmarksHandler(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	stored, _ := mockStore.Get(1)
+	if want := []string{"go", "web"}; !slices.Equal(stored.Tags, want) {
+		t.Errorf("Expected tags %v, got %v", want, stored.Tags)
+	}
+}
+
 // PUT /bookmarks/{id} Tests
 
 func TestPutBookmarks_Success(t *testing.T) {
@@ -310,105 +622,1292 @@ func TestPutBookmarks_InvalidJSON(t *testing.T) {
 	}
 }
 
-// DELETE /bookmarks/{id} Tests
+func TestPutBookmarks_PreservesCreatedAt(t *testing.T) {
+	original := testBookmark("Original")
+	original.CreatedAt = 1000
+	original.UpdatedAt = 1000
 
-func TestDeleteBookmarks_Success(t *testing.T) {
 	mockStore := NewMockStore()
-	mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("Test")})
-
+	mockStore.Seed(map[int]internal.Bookmark{1: original})
 	srv := createTestServer(t, mockStore, testConfig())
 
-	req := httptest.NewRequest(http.MethodDelete, "/bookmarks/1", nil)
+	updated := testBookmark("Updated")
+	updated.CreatedAt = 5
+	body, _ := json.Marshal(updated)
+
+	req := httptest.NewRequest(http.MethodPut, "/bookmarks/1", bytes.NewReader(body))
 	req.SetPathValue("id", "1")
 	w := httptest.NewRecorder()
 
-	srv.DeleteBookmarksHandler(w, req)
+	srv.PutBookmarksHandler(w, req)
 
 	if w.Code != http.StatusOK {
-		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
 	}
 
-	if mockStore.Count() != 0 {
-		t.Errorf("Expected 0 bookmarks after delete, got %d", mockStore.Count())
+	bookmark, _ := mockStore.Get(1)
+	if bookmark.CreatedAt != 1000 {
+		t.Errorf("Expected CreatedAt 1000, got %d", bookmark.CreatedAt)
 	}
-}
-
-func TestDeleteBookmarks_NotFound(t *testing.T) {
-	mockStore := NewMockStore()
-	srv := createTestServer(t, mockStore, testConfig())
-
-	req := httptest.NewRequest(http.MethodDelete, "/bookmarks/999", nil)
-	req.SetPathValue("id", "999")
-	w := httptest.NewRecorder()
-
-	srv.DeleteBookmarksHandler(w, req)
-
-	if w.Code != http.StatusNotFound {
-		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	if bookmark.UpdatedAt <= 1000 {
+		t.Errorf("Expected UpdatedAt to be bumped, got %d", bookmark.UpdatedAt)
 	}
 }
 
-func TestDeleteBookmarks_InvalidID(t *testing.T) {
+func TestPutBookmarks_InvalidURL(t *testing.T) {
 	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("Original")})
 	srv := createTestServer(t, mockStore, testConfig())
 
-	req := httptest.NewRequest(http.MethodDelete, "/bookmarks/invalid", nil)
-	req.SetPathValue("id", "invalid")
+	updated := testBookmark("Updated")
+	updated.Url = "not a url"
+	body, _ := json.Marshal(updated)
+
+	req := httptest.NewRequest(http.MethodPut, "/bookmarks/1", bytes.NewReader(body))
+	req.SetPathValue("id", "1")
 	w := httptest.NewRecorder()
 
-	srv.DeleteBookmarksHandler(w, req)
+	srv.PutBookmarksHandler(w, req)
 
 	if w.Code != http.StatusBadRequest {
 		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
 	}
+
+	if bookmark, _ := mockStore.Get(1); bookmark.Name != "Original" {
+		t.Errorf("Expected bookmark to be unchanged, got name '%s'", bookmark.Name)
+	}
 }
 
-// Health Check Tests
+// PATCH /bookmarks/{id} Tests
+
+func patchRequest(id, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPatch, "/bookmarks/"+id, bytes.NewReader([]byte(body)))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.SetPathValue("id", id)
+	return req
+}
+
+func TestPatchBookmarks_Success(t *testing.T) {
+	original := testBookmark("Original")
+	original.Tags = []string{"a", "b"}
+	original.CreatedAt = 1000
+	original.UpdatedAt = 1000
 
-func TestHealth(t *testing.T) {
 	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: original})
 	srv := createTestServer(t, mockStore, testConfig())
 
-	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	w := httptest.NewRecorder()
-
-	srv.HealthHandler(w, req)
+	srv.PatchBookmarksHandler(w, patchRequest("1", `{"name":"Patched","created_at":5}`))
 
 	if w.Code != http.StatusOK {
-		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
 	}
 
-	var result map[string]string
+	var result internal.Bookmark
 	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
+	if result.Name != "Patched" {
+		t.Errorf("Expected name 'Patched' in response, got '%s'", result.Name)
+	}
 
-	if result["status"] != "healthy" {
-		t.Errorf("Expected status 'healthy', got '%s'", result["status"])
+	bookmark, _ := mockStore.Get(1)
+	if bookmark.Name != "Patched" {
+		t.Errorf("Expected name 'Patched', got '%s'", bookmark.Name)
+	}
+	if bookmark.Url != original.Url || bookmark.Description != original.Description {
+		t.Errorf("Expected untouched fields to be preserved, got %+v", bookmark)
+	}
+	if len(bookmark.Tags) != 2 {
+		t.Errorf("Expected tags to be preserved, got %v", bookmark.Tags)
+	}
+	if bookmark.CreatedAt != 1000 {
+		t.Errorf("Expected CreatedAt 1000, got %d", bookmark.CreatedAt)
+	}
+	if bookmark.UpdatedAt <= 1000 {
+		t.Errorf("Expected UpdatedAt to be bumped, got %d", bookmark.UpdatedAt)
 	}
 }
 
-// Public Mode Authentication Tests
-
-func TestPublicMode_Disabled_RequiresAuth(t *testing.T) {
+func TestPatchBookmarks_NullClearsField(t *testing.T) {
 	mockStore := NewMockStore()
-	mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("Test")})
+	mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("Original")})
+	srv := createTestServer(t, mockStore, testConfig())
 
-	cfg := testConfig()
-	cfg.AuthPassword = "secret123"
-	cfg.Public = false
+	w := httptest.NewRecorder()
+	srv.PatchBookmarksHandler(w, patchRequest("1", `{"description":null,"tags":[]}`))
 
-	srv := createTestServer(t, mockStore, cfg)
-	handler := srv.SetupRoutes()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	bookmark, _ := mockStore.Get(1)
+	if bookmark.Description != "" {
+		t.Errorf("Expected empty description, got '%s'", bookmark.Description)
+	}
+	if len(bookmark.Tags) != 0 {
+		t.Errorf("Expected no tags, got %v", bookmark.Tags)
+	}
+	if bookmark.Name != "Original" {
+		t.Errorf("Expected name 'Original', got '%s'", bookmark.Name)
+	}
+}
 
+func TestPatchBookmarks_Errors(t *testing.T) {
 	tests := []struct {
-		name   string
-		method string
-		path   string
+		name        string
+		id          string
+		contentType string
+		body        string
+		want        int
 	}{
-		{"GET /bookmarks", http.MethodGet, "/bookmarks"},
-		{"GET /bookmarks/1", http.MethodGet, "/bookmarks/1"},
-		{"POST /bookmarks", http.MethodPost, "/bookmarks"},
+		{"not found", "999", "application/merge-patch+json", `{"name":"x"}`, http.StatusNotFound},
+		{"invalid id", "invalid", "application/merge-patch+json", `{"name":"x"}`, http.StatusBadRequest},
+		{"invalid json", "1", "application/merge-patch+json", `{"name":`, http.StatusBadRequest},
+		{"not an object", "1", "application/merge-patch+json", `["name"]`, http.StatusBadRequest},
+		{"empty name", "1", "application/merge-patch+json", `{"name":null}`, http.StatusBadRequest},
+		{"invalid url", "1", "application/merge-patch+json", `{"name":"x","url":"not a url"}`, http.StatusBadRequest},
+		{"unsupported media type", "1", "text/plain", `{"name":"x"}`, http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := NewMockStore()
+			mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("Original")})
+			srv := createTestServer(t, mockStore, testConfig())
+
+			req := patchRequest(tt.id, tt.body)
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			srv.PatchBookmarksHandler(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+
+			if bookmark, _ := mockStore.Get(1); bookmark.Name != "Original" {
+				t.Errorf("Expected bookmark to be unchanged, got name '%s'", bookmark.Name)
+			}
+		})
+	}
+}
+
+// Conditional Request Tests
+
+func TestGetBookmarkByID_ReturnsETag(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: {Name: "Test", Url: "https://example.com", Revision: 7}})
+	srv := createTestServer(t, mockStore, testConfig())
+
+	req := httptest.NewRequest(http.MethodGet, "/bookmarks/1", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	srv.GetBookmarkByIDHandler(w, req)
+
+	if etag := w.Header().Get("ETag"); etag != `"7"` {
+		t.Errorf("Expected ETag \"7\", got %q", etag)
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		body    string
+		ifMatch string
+		want    int
+	}{
+		{"put matching", http.MethodPut, `{"name":"Changed","url":"https://example.com"}`, `"3"`, http.StatusOK},
+		{"put in list", http.MethodPut, `{"name":"Changed","url":"https://example.com"}`, `"1", "3"`, http.StatusOK},
+		{"put wildcard", http.MethodPut, `{"name":"Changed","url":"https://example.com"}`, `*`, http.StatusOK},
+		{"put stale", http.MethodPut, `{"name":"Changed","url":"https://example.com"}`, `"2"`, http.StatusPreconditionFailed},
+		{"put weak", http.MethodPut, `{"name":"Changed","url":"https://example.com"}`, `W/"3"`, http.StatusPreconditionFailed},
+		{"patch matching", http.MethodPatch, `{"name":"Changed"}`, `"3"`, http.StatusOK},
+		{"patch stale", http.MethodPatch, `{"name":"Changed"}`, `"2"`, http.StatusPreconditionFailed},
+		{"delete matching", http.MethodDelete, ``, `"3"`, http.StatusOK},
+		{"delete stale", http.MethodDelete, ``, `"2"`, http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := NewMockStore()
+			mockStore.Seed(map[int]internal.Bookmark{1: {Name: "Original", Url: "https://example.com", Revision: 3}})
+			srv := createTestServer(t, mockStore, testConfig())
+			handler := srv.SetupRoutes()
+
+			req := httptest.NewRequest(tt.method, "/bookmarks/1", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("If-Match", tt.ifMatch)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+
+			bookmark, err := mockStore.Get(1)
+			if tt.want == http.StatusPreconditionFailed {
+				if err != nil || bookmark.Name != "Original" || bookmark.Revision != 3 {
+					t.Errorf("Expected bookmark to be unchanged, got %+v (err %v)", bookmark, err)
+				}
+				return
+			}
+
+			if tt.method != http.MethodDelete {
+				if etag := w.Header().Get("ETag"); etag != `"4"` {
+					t.Errorf("Expected ETag \"4\", got %q", etag)
+				}
+				if bookmark.Name != "Changed" || bookmark.Revision != 4 {
+					t.Errorf("Expected Changed at revision 4, got %+v", bookmark)
+				}
+			}
+		})
+	}
+}
+
+func TestGetBookmarks_ConditionalGet(t *testing.T) {
+	mockStore := NewMockStore()
+	srv := createTestServer(t, mockStore, testConfig())
+	handler := srv.SetupRoutes()
+
+	if _, err := mockStore.Add(testBookmark("First")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	get := func(target string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, target := range []string{"/bookmarks", "/bookmarks?limit=10"} {
+		first := get(target, nil)
+		etag := first.Header().Get("ETag")
+		lastModified := first.Header().Get("Last-Modified")
+		if first.Code != http.StatusOK || etag == "" || lastModified == "" {
+			t.Fatalf("%s: expected 200 with validators, got %d (ETag %q, Last-Modified %q)", target, first.Code, etag, lastModified)
+		}
+
+		w := get(target, http.Header{"If-None-Match": {etag}})
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("%s: expected empty 304 for matching If-None-Match, got %d", target, w.Code)
+		}
+
+		w = get(target, http.Header{"If-Modified-Since": {lastModified}})
+		if w.Code != http.StatusNotModified {
+			t.Errorf("%s: expected 304 for If-Modified-Since, got %d", target, w.Code)
+		}
+
+		// If-None-Match wins over If-Modified-Since
+		w = get(target, http.Header{"If-None-Match": {`"g0"`}, "If-Modified-Since": {lastModified}})
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 for stale If-None-Match, got %d", target, w.Code)
+		}
+	}
+
+	etag := get("/bookmarks", nil).Header().Get("ETag")
+	if _, err := mockStore.Add(testBookmark("Second")); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	w := get("/bookmarks", http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 after a mutation, got %d", w.Code)
+	}
+	if w.Header().Get("ETag") == etag {
+		t.Errorf("Expected ETag to change after a mutation")
+	}
+}
+
+func TestGetBookmarkByID_ConditionalGet(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: {Name: "Test", Url: "https://example.com", UpdatedAt: 1700000000, Revision: 2}})
+	srv := createTestServer(t, mockStore, testConfig())
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"matching etag", "If-None-Match", `"2"`, http.StatusNotModified},
+		{"weak matching etag", "If-None-Match", `W/"2"`, http.StatusNotModified},
+		{"wildcard", "If-None-Match", `*`, http.StatusNotModified},
+		{"stale etag", "If-None-Match", `"1"`, http.StatusOK},
+		{"not modified since", "If-Modified-Since", time.Unix(1700000000, 0).UTC().Format(http.TimeFormat), http.StatusNotModified},
+		{"modified since", "If-Modified-Since", time.Unix(1699999999, 0).UTC().Format(http.TimeFormat), http.StatusOK},
+		{"invalid date", "If-Modified-Since", "yesterday", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/bookmarks/1", nil)
+			req.SetPathValue("id", "1")
+			req.Header.Set(tt.header, tt.value)
+			w := httptest.NewRecorder()
+
+			srv.GetBookmarkByIDHandler(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, w.Code)
+			}
+			if w.Header().Get("Last-Modified") != "Tue, 14 Nov 2023 22:13:20 GMT" {
+				t.Errorf("Expected Last-Modified from UpdatedAt, got %q", w.Header().Get("Last-Modified"))
+			}
+		})
+	}
+}
+
+// conflictingStore changes a bookmark behind the server's back the first time
+// it is conditionally updated, simulating a concurrent writer.
+type conflictingStore struct {
+	*MockStore
+	conflicted bool
+}
+
+func (c *conflictingStore) UpdateIfRevision(id int, bookmark internal.Bookmark, revision uint64) error {
+	if !c.conflicted {
+		c.conflicted = true
+		existing, _ := c.MockStore.Get(id)
+		existing.Tags = []string{"concurrent"}
+		c.MockStore.Update(id, existing)
+	}
+	return c.MockStore.UpdateIfRevision(id, bookmark, revision)
+}
+
+func TestPatchBookmarks_RetriesConcurrentChange(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: {Name: "Original", Url: "https://example.com", Revision: 1}})
+	store := &conflictingStore{MockStore: mockStore}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	srv, err := server.New(testConfig(), store, logger)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	defer srv.Close()
+
+	w := httptest.NewRecorder()
+	srv.PatchBookmarksHandler(w, patchRequest("1", `{"name":"Patched"}`))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// The patch was reapplied on top of the concurrent change instead of overwriting it
+	bookmark, _ := mockStore.Get(1)
+	if bookmark.Name != "Patched" || !slices.Equal(bookmark.Tags, []string{"concurrent"}) || bookmark.Revision != 3 {
+		t.Errorf("Expected patch merged with concurrent change at revision 3, got %+v", bookmark)
+	}
+}
+
+// DELETE /bookmarks/{id} Tests
+
+func TestDeleteBookmarks_Success(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("Test")})
+
+	srv := createTestServer(t, mockStore, testConfig())
+
+	req := httptest.NewRequest(http.MethodDelete, "/bookmarks/1", nil)
+	req.SetPathValue("id", "1")
+	w := httptest.NewRecorder()
+
+	srv.DeleteBookmarksHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if mockStore.Count() != 0 {
+		t.Errorf("Expected 0 bookmarks after delete, got %d", mockStore.Count())
+	}
+}
+
+func TestDeleteBookmarks_NotFound(t *testing.T) {
+	mockStore := NewMockStore()
+	srv := createTestServer(t, mockStore, testConfig())
+
+	req := httptest.NewRequest(http.MethodDelete, "/bookmarks/999", nil)
+	req.SetPathValue("id", "999")
+	w := httptest.NewRecorder()
+
+	srv.DeleteBookmarksHandler(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestDeleteBookmarks_InvalidID(t *testing.T) {
+	mockStore := NewMockStore()
+	srv := createTestServer(t, mockStore, testConfig())
+
+	req := httptest.NewRequest(http.MethodDelete, "/bookmarks/invalid", nil)
+	req.SetPathValue("id", "invalid")
+	w := httptest.NewRecorder()
+
+	srv.DeleteBookmarksHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// Import and Export Tests
+
+const importFile = `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<DL><p>
+    <DT><H3>Dev</H3>
+    <DL><p>
+        <DT><A HREF="https://go.dev" ADD_DATE="1600000000">Go</A>
+        <DT><A HREF="https://existing.example">Already here</A>
+        <DT><A HREF="not a url">Broken</A>
+        <DT><A HREF="https://go.dev">Go again</A>
+        <DT><A HREF="https://future.example" ADD_DATE="99999999999"></A>
+    </DL><p>
+</DL><p>
+`
+
+func TestImportBookmarks(t *testing.T) {
+	for _, dryRun := range []bool{true, false} {
+		t.Run(fmt.Sprintf("dry_run=%t", dryRun), func(t *testing.T) {
+			mockStore := NewMockStore()
+			mockStore.Seed(map[int]internal.Bookmark{1: {Name: "Existing", Url: "https://existing.example"}})
+			srv := createTestServer(t, mockStore, testConfig())
+
+			target := "/bookmarks/import?format=netscape"
+			want := http.StatusCreated
+			if dryRun {
+				target += "&dry_run=true"
+				want = http.StatusOK
+			}
+			req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(importFile))
+			w := httptest.NewRecorder()
+
+			srv.ImportBookmarksHandler(w, req)
+
+			if w.Code != want {
+				t.Fatalf("Expected status %d, got %d: %s", want, w.Code, w.Body.String())
+			}
+
+			var report internal.ImportReport
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if report.DryRun != dryRun || report.Total != 5 || report.Imported != 2 {
+				t.Errorf("Expected 2 of 5 imported, got %+v", report)
+			}
+			if len(report.Duplicates) != 2 || report.Duplicates[0].Index != 1 || report.Duplicates[1].Index != 3 {
+				t.Errorf("Expected duplicates at 1 and 3, got %+v", report.Duplicates)
+			}
+			if len(report.Invalid) != 1 || report.Invalid[0].Index != 2 {
+				t.Errorf("Expected invalid entry at 2, got %+v", report.Invalid)
+			}
+
+			if dryRun {
+				if mockStore.Count() != 1 || len(report.IDs) != 0 {
+					t.Errorf("Expected nothing stored in a dry run, got %d bookmarks", mockStore.Count())
+				}
+				return
+			}
+
+			if mockStore.Count() != 3 || len(report.IDs) != 2 {
+				t.Fatalf("Expected 2 bookmarks imported, got %d stored and IDs %v", mockStore.Count(), report.IDs)
+			}
+			goBookmark, _ := mockStore.Get(report.IDs[0])
+			if goBookmark.CreatedAt != 1600000000 || !slices.Equal(goBookmark.Tags, []string{"Dev"}) {
+				t.Errorf("Expected ADD_DATE and folder tag to be kept, got %+v", goBookmark)
+			}
+			future, _ := mockStore.Get(report.IDs[1])
+			if future.CreatedAt > time.Now().Unix() || future.Name != "https://future.example" {
+				t.Errorf("Expected future date clamped and URL used as name, got %+v", future)
+			}
+		})
+	}
+}
+
+func TestImportBookmarks_StoresNothingOnFailure(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.BatchError = errors.New("disk full")
+	srv := createTestServer(t, mockStore, testConfig())
+
+	req := httptest.NewRequest(http.MethodPost, "/bookmarks/import?format=netscape", strings.NewReader(importFile))
+	w := httptest.NewRecorder()
+
+	srv.ImportBookmarksHandler(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if mockStore.Count() != 0 {
+		t.Errorf("Expected no bookmarks stored, got %d", mockStore.Count())
+	}
+}
+
+func TestImportBookmarks_UnsupportedFormat(t *testing.T) {
+	srv := createTestServer(t, nil, testConfig())
+
+	req := httptest.NewRequest(http.MethodPost, "/bookmarks/import?format=csv", strings.NewReader("a,b"))
+	w := httptest.NewRecorder()
+
+	srv.ImportBookmarksHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestExportBookmarks(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{
+		2: {Name: "Second", Url: "https://second.example"},
+		1: {Name: "First", Url: "https://first.example", Tags: []string{"a"}},
+	})
+	srv := createTestServer(t, mockStore, testConfig())
+
+	req := httptest.NewRequest(http.MethodGet, "/bookmarks/export?format=netscape", nil)
+	w := httptest.NewRecorder()
+
+	srv.SetupRoutes().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Expected text/html, got %s", ct)
+	}
+
+	body := w.Body.String()
+	first := strings.Index(body, "https://first.example")
+	second := strings.Index(body, "https://second.example")
+	if first < 0 || second < 0 || first > second {
+		t.Errorf("Expected both bookmarks ordered by ID, got:\n%s", body)
+	}
+	if !strings.Contains(body, `TAGS="a"`) {
+		t.Errorf("Expected tags to be exported, got:\n%s", body)
+	}
+}
+
+// Batch Tests
+
+func postBatch(t *testing.T, srv *server.Server, body string) internal.BatchResponse {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/bookmarks/batch", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	srv.SetupRoutes().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var resp internal.BatchResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp
+}
+
+func batchStatuses(resp internal.BatchResponse) []int {
+	statuses := make([]int, len(resp.Results))
+	for i, result := range resp.Results {
+		statuses[i] = result.Status
+	}
+	return statuses
+}
+
+const mixedBatch = `{"atomic": %t, "operations": [
+	{"op": "create", "bookmark": {"name": "New", "url": "https://new.example"}},
+	{"op": "update", "id": 1, "bookmark": {"name": "Renamed", "url": "https://one.example"}},
+	{"op": "delete", "id": 99},
+	{"op": "create", "bookmark": {"name": "", "url": "https://invalid.example"}},
+	{"op": "delete", "id": 2, "if_revision": 1}
+]}`
+
+func TestBatchBookmarks_BestEffort(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{
+		1: {Name: "One", Url: "https://one.example", CreatedAt: 100, Revision: 1},
+		2: {Name: "Two", Url: "https://two.example", Revision: 1},
+	})
+	srv := createTestServer(t, mockStore, testConfig())
+
+	resp := postBatch(t, srv, fmt.Sprintf(mixedBatch, false))
+
+	want := []int{http.StatusCreated, http.StatusOK, http.StatusNotFound, http.StatusBadRequest, http.StatusOK}
+	if got := batchStatuses(resp); !slices.Equal(got, want) {
+		t.Fatalf("Expected statuses %v, got %v", want, got)
+	}
+	if resp.Applied != 3 || resp.Aborted {
+		t.Errorf("Expected 3 applied and not aborted, got %+v", resp)
+	}
+	if resp.Results[0].ID != 3 || resp.Results[0].Revision != 1 {
+		t.Errorf("Expected created bookmark 3 at revision 1, got %+v", resp.Results[0])
+	}
+	if len(resp.Results[3].Fields) != 1 || resp.Results[3].Fields[0].Field != "name" {
+		t.Errorf("Expected the name field to be rejected, got %+v", resp.Results[3])
+	}
+
+	renamed, _ := mockStore.Get(1)
+	if renamed.Name != "Renamed" || renamed.CreatedAt != 100 || renamed.Revision != 2 {
+		t.Errorf("Expected update to keep CreatedAt and bump the revision, got %+v", renamed)
+	}
+	if mockStore.Count() != 2 {
+		t.Errorf("Expected 2 bookmarks after the batch, got %d", mockStore.Count())
+	}
+}
+
+func TestBatchBookmarks_AtomicAbortsOnFailure(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{
+		1: {Name: "One", Url: "https://one.example", Revision: 1},
+		2: {Name: "Two", Url: "https://two.example", Revision: 1},
+	})
+	srv := createTestServer(t, mockStore, testConfig())
+
+	resp := postBatch(t, srv, fmt.Sprintf(mixedBatch, true))
+
+	want := []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusFailedDependency, http.StatusBadRequest, http.StatusFailedDependency}
+	if got := batchStatuses(resp); !slices.Equal(got, want) {
+		t.Fatalf("Expected statuses %v, got %v", want, got)
+	}
+	if resp.Applied != 0 || !resp.Aborted {
+		t.Errorf("Expected the batch to be aborted, got %+v", resp)
+	}
+
+	// A failure found by the store also rolls back the operations before it
+	resp = postBatch(t, srv, `{"atomic": true, "operations": [
+		{"op": "delete", "id": 1},
+		{"op": "delete", "id": 99}
+	]}`)
+	want = []int{http.StatusFailedDependency, http.StatusNotFound}
+	if got := batchStatuses(resp); !slices.Equal(got, want) {
+		t.Fatalf("Expected statuses %v, got %v", want, got)
+	}
+
+	if mockStore.Count() != 2 || mockStore.CurrentGeneration() != 0 {
+		t.Errorf("Expected nothing to change, got %d bookmarks at generation %d", mockStore.Count(), mockStore.CurrentGeneration())
+	}
+}
+
+func TestBatchBookmarks_InvalidRequests(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"invalid JSON", `{"operations": [`},
+		{"no operations", `{"operations": []}`},
+		{"too many operations", `{"operations": [` + strings.Repeat(`{"op": "delete", "id": 1},`, internal.MaxBatchSize) + `{"op": "delete", "id": 1}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := createTestServer(t, nil, testConfig())
+
+			req := httptest.NewRequest(http.MethodPost, "/bookmarks/batch", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+
+			srv.BatchBookmarksHandler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
+
+// Event Stream Tests
+
+// openEvents connects to GET /events on a test server and returns a reader over the stream.
+func openEvents(t *testing.T, url, lastEventID string) *bufio.Reader {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, url+"/events", nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %s", ct)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// readEvent reads the lines of the next event from an event stream.
+func readEvent(t *testing.T, stream *bufio.Reader) []string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestEvents_StreamsChanges(t *testing.T) {
+	mockStore := NewMockStore()
+	srv := createTestServer(t, mockStore, testConfig())
+	ts := httptest.NewServer(srv.SetupRoutes())
+	t.Cleanup(ts.Close) // After the streams opened below are closed
+
+	stream := openEvents(t, ts.URL, "")
+	if lines := readEvent(t, stream); !slices.Equal(lines, []string{"id: 0"}) {
+		t.Fatalf("Expected the stream to open with its starting ID, got %q", lines)
+	}
+
+	id, _ := mockStore.Add(testBookmark("Streamed"))
+	mockStore.Delete(id)
+
+	lines := readEvent(t, stream)
+	if len(lines) != 3 || lines[0] != "id: 1" || lines[1] != "event: add" || !strings.Contains(lines[2], `"name":"Streamed"`) {
+		t.Errorf("Unexpected add event: %q", lines)
+	}
+	lines = readEvent(t, stream)
+	if len(lines) != 3 || lines[0] != "id: 2" || lines[1] != "event: delete" {
+		t.Errorf("Unexpected delete event: %q", lines)
+	}
+}
+
+func TestEvents_ResumeWithLastEventID(t *testing.T) {
+	mockStore := NewMockStore()
+	srv := createTestServer(t, mockStore, testConfig())
+	ts := httptest.NewServer(srv.SetupRoutes())
+	t.Cleanup(ts.Close) // After the streams opened below are closed
+
+	mockStore.Add(testBookmark("First"))
+	mockStore.Add(testBookmark("Second"))
+
+	stream := openEvents(t, ts.URL, "1")
+	readEvent(t, stream) // Starting ID
+	if lines := readEvent(t, stream); len(lines) != 3 || lines[0] != "id: 2" || !strings.Contains(lines[2], "Second") {
+		t.Errorf("Expected missed event 2, got %q", lines)
+	}
+
+	// Changes that cannot be replayed start the stream with a reset
+	stream = openEvents(t, ts.URL, "99")
+	if lines := readEvent(t, stream); len(lines) != 3 || lines[0] != "id: 2" || lines[1] != "event: reset" {
+		t.Errorf("Expected reset event at the current sequence, got %q", lines)
+	}
+}
+
+func TestEvents_InvalidLastEventID(t *testing.T) {
+	srv := createTestServer(t, nil, testConfig())
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+
+	srv.EventsHandler(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// Health Check Tests
+
+func TestHealth(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("One"), 2: testBookmark("Two")})
+
+	cfg := testConfig()
+	cfg.StoreFileName = filepath.Join(t.TempDir(), "bookmarks.json")
+	srv := createTestServer(t, mockStore, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+
+	srv.HealthHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var report map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	// Served without authentication, so it reports nothing but the checks
+	if report["status"] != "healthy" || len(report) != 2 {
+		t.Errorf("Expected only a healthy status and checks, got %v", report)
+	}
+	checks, _ := report["checks"].(map[string]any)
+	for _, check := range []string{"shutdown", "snapshots", "storage"} {
+		if checks[check] != "ok" {
+			t.Errorf("Expected check %s to be ok, got %v", check, checks[check])
+		}
+	}
+}
+
+func TestHealthDetails(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("One"), 2: testBookmark("Two")})
+
+	cfg := testConfig()
+	cfg.AuthPassword = "secret123"
+	cfg.StoreFileName = filepath.Join(t.TempDir(), "bookmarks.json")
+	handler := createTestServer(t, mockStore, cfg).SetupRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/health/details", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d without credentials, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/health/details", nil)
+	req.SetBasicAuth("", "secret123")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var report internal.HealthReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if report.Status != "healthy" {
+		t.Errorf("Expected status 'healthy', got '%s'", report.Status)
+	}
+	if report.Version != internal.Version {
+		t.Errorf("Expected version %q, got %q", internal.Version, report.Version)
+	}
+	if report.Bookmarks == nil || *report.Bookmarks != 2 {
+		t.Errorf("Expected 2 bookmarks, got %v", report.Bookmarks)
+	}
+	if report.StartedAt == 0 || report.LastSnapshotAt != 0 {
+		t.Errorf("Expected a start time and no snapshot yet, got %d and %d", report.StartedAt, report.LastSnapshotAt)
+	}
+	for _, check := range []string{"shutdown", "snapshots", "storage"} {
+		if report.Checks[check] != "ok" {
+			t.Errorf("Expected check %s to be ok, got %q", check, report.Checks[check])
+		}
+	}
+}
+
+func TestLivez(t *testing.T) {
+	handler := createTestServer(t, nil, testConfig()).SetupRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	readyz := func(t *testing.T, srv *server.Server) (int, map[string]any) {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		w := httptest.NewRecorder()
+		srv.SetupRoutes().ServeHTTP(w, req)
+
+		var result map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return w.Code, result
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.AuthPassword = "secret123"
+		cfg.StoreFileName = filepath.Join(t.TempDir(), "bookmarks.json")
+
+		code, result := readyz(t, createTestServer(t, nil, cfg))
+		if code != http.StatusOK || result["status"] != "ready" {
+			t.Errorf("Expected 200 ready without credentials, got %d %v", code, result)
+		}
+	})
+
+	t.Run("store directory missing", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.StoreFileName = filepath.Join(t.TempDir(), "missing", "bookmarks.json")
+
+		code, result := readyz(t, createTestServer(t, nil, cfg))
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, code)
+		}
+		if checks, _ := result["checks"].(map[string]any); checks["storage"] == "ok" {
+			t.Errorf("Expected storage check to fail, got %v", result)
+		}
+	})
+
+	t.Run("snapshots failing", func(t *testing.T) {
+		mockStore := NewMockStore()
+		mockStore.SaveSnapshotError = errors.New("no space left on device")
+
+		cfg := testConfig()
+		cfg.SnapshotInterval = "5ms"
+		cfg.StoreFileName = filepath.Join(t.TempDir(), "bookmarks.json")
+		srv := createTestServer(t, mockStore, cfg)
+
+		// Wait for the snapshot loop to fail a few times
+		time.Sleep(50 * time.Millisecond)
+
+		code, result := readyz(t, srv)
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, code)
+		}
+		checks, _ := result["checks"].(map[string]any)
+		if msg, _ := checks["snapshots"].(string); !strings.Contains(msg, "no space left on device") {
+			t.Errorf("Expected snapshots check to report the error, got %v", result)
+		}
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.StoreFileName = filepath.Join(t.TempDir(), "bookmarks.json")
+		srv := createTestServer(t, nil, cfg)
+		srv.Close()
+
+		code, result := readyz(t, srv)
+		if code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, code)
+		}
+		if checks, _ := result["checks"].(map[string]any); checks["shutdown"] == "ok" {
+			t.Errorf("Expected shutdown check to fail, got %v", result)
+		}
+	})
+}
+
+func TestStatus_ReportsPersistedGeneration(t *testing.T) {
+	mockStore := NewMockStore()
+	srv := createTestServer(t, mockStore, testConfig())
+
+	mockStore.Add(testBookmark("First"))
+	mockStore.Add(testBookmark("Second"))
+	mockStore.SaveSnapshot()
+	mockStore.Add(testBookmark("Third"))
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	w := httptest.NewRecorder()
+
+	srv.StatusHandler(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var result struct {
+		Generation          uint64 `json:"generation"`
+		PersistedGeneration uint64 `json:"persisted_generation"`
+		PersistedAt         int64  `json:"persisted_at"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if result.Generation != 3 {
+		t.Errorf("Expected generation 3, got %d", result.Generation)
+	}
+	if result.PersistedGeneration != 2 {
+		t.Errorf("Expected persisted generation 2, got %d", result.PersistedGeneration)
+	}
+	if result.PersistedAt == 0 {
+		t.Error("Expected persisted_at to be set")
+	}
+}
+
+// TLS Tests
+
+func TestTLS_SelfSignedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	cfg := testConfig()
+	cfg.StoreFileName = filepath.Join(dir, "bookmarks.json")
+	cfg.TLSSelfSigned = true
+
+	createTestServer(t, nil, cfg)
+
+	// The certificate is kept next to the store, with a private key
+	certFile, keyFile := cfg.TLSFiles()
+	if filepath.Dir(certFile) != dir || filepath.Dir(keyFile) != dir {
+		t.Fatalf("Expected certificate files in %s, got %s and %s", dir, certFile, keyFile)
+	}
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatalf("Expected a key file: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("Expected key file mode 0600, got %o", perm)
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load generated certificate: %v", err)
+	}
+	if err := cert.Leaf.VerifyHostname("localhost"); err != nil {
+		t.Errorf("Expected certificate for localhost: %v", err)
+	}
+
+	// The certificate is reused on the next start
+	before, _ := os.ReadFile(certFile)
+	createTestServer(t, nil, cfg)
+	after, _ := os.ReadFile(certFile)
+	if !bytes.Equal(before, after) {
+		t.Error("Expected the certificate to be reused")
+	}
+}
+
+func TestTLS_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func(*server.Config)
+	}{
+		{"cert without key", func(cfg *server.Config) { cfg.TLSCertFile = "cert.pem" }},
+		{"key without cert", func(cfg *server.Config) { cfg.TLSKeyFile = "key.pem" }},
+		{"redirect without TLS", func(cfg *server.Config) { cfg.HTTPRedirectPort = "8081" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			tt.config(&cfg)
+			if err := cfg.Validate(); err == nil {
+				t.Error("Expected a validation error, got nil")
+			}
+		})
+	}
+}
+
+// testClientCert returns a client certificate with a common name and DNS names.
+func testClientCert(t *testing.T, commonName string, dnsNames ...string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+func TestClientCert_Identities(t *testing.T) {
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "client-ca.pem")
+	ca := testClientCert(t, "fave test CA")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0644); err != nil {
+		t.Fatalf("Failed to write client CA: %v", err)
+	}
+
+	cfg := testConfig()
+	cfg.StoreFileName = filepath.Join(dir, "bookmarks.json")
+	cfg.TLSSelfSigned = true
+	cfg.TLSClientCAFile = caFile
+	cfg.TLSClientIdentities = []server.ClientIdentity{
+		{Subject: "cn:reader", Scopes: []string{internal.ScopeRead}},
+		{Subject: "dns:ci.internal", Scopes: []string{internal.ScopeRead, internal.ScopeWrite}},
+	}
+
+	// Without a password, a client certificate is the only way in
+	handler := createTestServer(t, nil, cfg).SetupRoutes()
+
+	request := func(method, path string, cert *x509.Certificate) int {
+		body := strings.NewReader(`{"name":"Test","url":"https://example.com"}`)
+		req := httptest.NewRequest(method, path, body)
+		if cert != nil {
+			// The TLS handshake verified the certificate against the client CA bundle
+			req.TLS = &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{cert},
+				VerifiedChains:   [][]*x509.Certificate{{cert, ca}},
+			}
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	reader := testClientCert(t, "reader")
+	ci := testClientCert(t, "builder", "ci.internal")
+	stranger := testClientCert(t, "stranger")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		cert   *x509.Certificate
+		want   int
+	}{
+		{"no certificate", http.MethodGet, "/bookmarks", nil, http.StatusUnauthorized},
+		{"health without certificate", http.MethodGet, "/health", nil, http.StatusOK},
+		{"read by common name", http.MethodGet, "/bookmarks", reader, http.StatusOK},
+		{"write without scope", http.MethodPost, "/bookmarks", reader, http.StatusForbidden},
+		{"write by DNS name", http.MethodPost, "/bookmarks", ci, http.StatusCreated},
+		{"unknown certificate", http.MethodGet, "/bookmarks", stranger, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := request(tt.method, tt.path, tt.cert); got != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, got)
+			}
+		})
+	}
+
+	// With a password, clients without a certificate can use it instead
+	cfg.AuthPassword = "secret123"
+	handler = createTestServer(t, nil, cfg).SetupRoutes()
+
+	req := httptest.NewRequest(http.MethodGet, "/bookmarks", nil)
+	req.SetBasicAuth("user", "secret123")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d with a password, got %d", http.StatusOK, w.Code)
+	}
+	if got := request(http.MethodGet, "/bookmarks", reader); got != http.StatusOK {
+		t.Errorf("Expected status %d with a certificate, got %d", http.StatusOK, got)
+	}
+}
+
+func TestClientCert_InvalidIdentities(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity server.ClientIdentity
+	}{
+		{"unknown subject kind", server.ClientIdentity{Subject: "ou:ops", Scopes: []string{internal.ScopeRead}}},
+		{"empty subject value", server.ClientIdentity{Subject: "cn:", Scopes: []string{internal.ScopeRead}}},
+		{"no scopes", server.ClientIdentity{Subject: "cn:reader"}},
+		{"invalid scope", server.ClientIdentity{Subject: "cn:reader", Scopes: []string{"delete"}}},
+		{"user without user accounts", server.ClientIdentity{Subject: "cn:reader", User: "alice", Scopes: []string{internal.ScopeRead}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.TLSSelfSigned = true
+			cfg.TLSClientCAFile = "client-ca.pem"
+			cfg.TLSClientIdentities = []server.ClientIdentity{tt.identity}
+			if err := cfg.Validate(); err == nil {
+				t.Error("Expected a validation error, got nil")
+			}
+		})
+	}
+}
+
+// Metrics Tests
+
+func TestMetrics(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("One"), 2: testBookmark("Two")})
+
+	cfg := testConfig()
+	cfg.SnapshotInterval = "10ms"
+	handler := createTestServer(t, mockStore, cfg).SetupRoutes()
+
+	for _, path := range []string{"/bookmarks", "/bookmarks/1", "/bookmarks/1", "/bookmarks/99", "/nope"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// Wait for the snapshot loop to run
+	time.Sleep(50 * time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Expected Prometheus text format, got %q", ct)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`fave_http_requests_total{method="GET",route="/bookmarks",status="200"} 1`,
+		`fave_http_requests_total{method="GET",route="/bookmarks/{id}",status="200"} 2`,
+		`fave_http_requests_total{method="GET",route="/bookmarks/{id}",status="404"} 1`,
+		`fave_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`fave_http_request_duration_seconds_bucket{method="GET",route="/bookmarks/{id}",status="200",le="+Inf"} 2`,
+		`fave_http_request_duration_seconds_count{method="GET",route="/bookmarks/{id}",status="200"} 2`,
+		"# TYPE fave_http_request_duration_seconds histogram",
+		"fave_bookmarks 2",
+		"fave_snapshot_failures_total 0",
+		`fave_store_file_bytes{file="snapshot"}`,
+		"go_goroutines ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q", want)
+		}
+	}
+	if strings.Contains(body, "fave_snapshot_last_success_timestamp_seconds 0\n") {
+		t.Error("Expected a successful snapshot to be recorded")
+	}
+	if strings.Contains(body, "/nope") {
+		t.Error("Expected unmatched paths not to become labels")
+	}
+}
+
+// Public Mode Authentication Tests
+
+func TestPublicMode_Disabled_RequiresAuth(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("Test")})
+
+	cfg := testConfig()
+	cfg.AuthPassword = "secret123"
+	cfg.Public = false
+
+	srv := createTestServer(t, mockStore, cfg)
+	handler := srv.SetupRoutes()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"GET /bookmarks", http.MethodGet, "/bookmarks"},
+		{"GET /bookmarks/1", http.MethodGet, "/bookmarks/1"},
+		{"POST /bookmarks", http.MethodPost, "/bookmarks"},
 		{"PUT /bookmarks/1", http.MethodPut, "/bookmarks/1"},
 		{"DELETE /bookmarks/1", http.MethodDelete, "/bookmarks/1"},
 	}
@@ -546,6 +2045,7 @@ func TestPublicMode_HealthAlwaysAccessible(t *testing.T) {
 			cfg := testConfig()
 			cfg.AuthPassword = tt.password
 			cfg.Public = tt.public
+			cfg.StoreFileName = filepath.Join(t.TempDir(), "bookmarks.json")
 
 			srv := createTestServer(t, mockStore, cfg)
 			handler := srv.SetupRoutes()
@@ -559,14 +2059,516 @@ func TestPublicMode_HealthAlwaysAccessible(t *testing.T) {
 				t.Errorf("Expected status %d for /health, got %d", http.StatusOK, w.Code)
 			}
 
-			var result map[string]string
-			if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+			var report internal.HealthReport
+			if err := json.NewDecoder(w.Body).Decode(&report); err != nil {
 				t.Fatalf("Failed to decode response: %v", err)
 			}
 
-			if result["status"] != "healthy" {
-				t.Errorf("Expected status 'healthy', got '%s'", result["status"])
+			if report.Status != "healthy" {
+				t.Errorf("Expected status 'healthy', got '%s'", report.Status)
+			}
+		})
+	}
+}
+
+// Brute-Force Lockout Tests
+
+func TestBasicAuth_LocksOutRepeatedFailures(t *testing.T) {
+	cfg := testConfig()
+	cfg.AuthPassword = "secret123"
+
+	srv := createTestServer(t, nil, cfg)
+	handler := srv.SetupRoutes()
+
+	attempt := func(password, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/bookmarks", nil)
+		req.RemoteAddr = remoteAddr
+		req.SetBasicAuth("user", password)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// A success resets the count of failures
+	for range 4 {
+		attempt("wrong", "192.0.2.1:1234")
+	}
+	if w := attempt("secret123", "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d before lockout, got %d", http.StatusOK, w.Code)
+	}
+
+	for i := range 6 {
+		if w := attempt("wrong", "192.0.2.1:1234"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d: expected status %d, got %d", i+1, http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	// Locked out, even with the right password
+	w := attempt("secret123", "192.0.2.1:5678")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d while locked out, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Expected Retry-After 1, got %q", got)
+	}
+
+	// Other clients are unaffected
+	if w := attempt("secret123", "198.51.100.7:1234"); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d for another client, got %d", http.StatusOK, w.Code)
+	}
+
+	// The lockout ends
+	time.Sleep(1100 * time.Millisecond)
+	if w := attempt("secret123", "192.0.2.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d after lockout, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestUserAccounts_LocksOutRepeatedFailures(t *testing.T) {
+	users := NewMockUsers()
+	users.AddUser("alice", "alice-password")
+
+	handler := createUsersTestServer(t, users)
+
+	for range 6 {
+		req := httptest.NewRequest(http.MethodGet, "/bookmarks", nil)
+		req.SetBasicAuth("alice", "guess")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/bookmarks", nil)
+	req.SetBasicAuth("alice", "alice-password")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d while locked out, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header")
+	}
+}
+
+// Rate Limit Tests
+
+func TestRateLimit_ReadsAndWritesLimitedSeparately(t *testing.T) {
+	cfg := testConfig()
+	cfg.RateLimitRead = 3
+	cfg.RateLimitWrite = 1
+
+	srv := createTestServer(t, nil, cfg)
+	handler := srv.SetupRoutes()
+
+	request := func(method, remoteAddr string) *httptest.ResponseRecorder {
+		var body *strings.Reader
+		if method == http.MethodPost {
+			body = strings.NewReader(`{"name":"Test","url":"https://example.com"}`)
+		} else {
+			body = strings.NewReader("")
+		}
+		req := httptest.NewRequest(method, "/bookmarks", body)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := range 3 {
+		w := request(http.MethodGet, "192.0.2.1:1234")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Read %d: expected status %d, got %d", i+1, http.StatusOK, w.Code)
+		}
+		if got, want := w.Header().Get("RateLimit-Remaining"), fmt.Sprint(2-i); got != want {
+			t.Errorf("Read %d: expected RateLimit-Remaining %s, got %s", i+1, want, got)
+		}
+		if got := w.Header().Get("RateLimit-Limit"); got != "3" {
+			t.Errorf("Expected RateLimit-Limit 3, got %s", got)
+		}
+	}
+
+	w := request(http.MethodGet, "192.0.2.1:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d after the read limit, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "20" {
+		t.Errorf("Expected Retry-After 20, got %q", got)
+	}
+
+	// Writes have their own limit
+	if w := request(http.MethodPost, "192.0.2.1:1234"); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d for the first write, got %d", http.StatusCreated, w.Code)
+	}
+	if w := request(http.MethodPost, "192.0.2.1:1234"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status %d after the write limit, got %d", http.StatusTooManyRequests, w.Code)
+	}
+
+	// Other clients are unaffected
+	if w := request(http.MethodGet, "198.51.100.7:1234"); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d for another client, got %d", http.StatusOK, w.Code)
+	}
+
+	// Health checks are never limited
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d for /health, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestRateLimit_BeforeAuthentication(t *testing.T) {
+	cfg := testConfig()
+	cfg.AuthPassword = "secret123"
+	cfg.RateLimitRead = 2
+
+	srv := createTestServer(t, nil, cfg)
+	handler := srv.SetupRoutes()
+
+	request := func(password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/bookmarks", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req.SetBasicAuth("", password)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Failed sign-ins count against the limit of the address
+	for i := range 2 {
+		if w := request("wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Attempt %d: expected status %d, got %d", i+1, http.StatusUnauthorized, w.Code)
+		}
+	}
+	if w := request("wrong"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status %d once the limit is used up, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w := request("secret123"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the right password to be limited too, got %d", w.Code)
+	}
+}
+
+// User Account Tests
+
+func createUsersTestServer(t *testing.T, users *MockUsers) http.Handler {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError, // Quiet during tests
+	}))
+
+	srv, err := server.NewWithUsers(testConfig(), users, logger)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	t.Cleanup(func() {
+		srv.Close()
+	})
+
+	return srv.SetupRoutes()
+}
+
+func TestUserAccounts_SeparateNamespaces(t *testing.T) {
+	users := NewMockUsers()
+	alice := users.AddUser("alice", "alice-password")
+	bob := users.AddUser("bob", "bob-password")
+	bob.Seed(map[int]internal.Bookmark{1: testBookmark("Bob's")})
+
+	handler := createUsersTestServer(t, users)
+
+	body, _ := json.Marshal(testBookmark("Alice's"))
+	req := httptest.NewRequest(http.MethodPost, "/bookmarks", bytes.NewReader(body))
+	req.SetBasicAuth("alice", "alice-password")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	if alice.Count() != 1 || bob.Count() != 1 {
+		t.Errorf("Expected one bookmark in each namespace, got alice=%d bob=%d", alice.Count(), bob.Count())
+	}
+
+	for _, tt := range []struct {
+		username, password, name string
+	}{
+		{"alice", "alice-password", "Alice's"},
+		{"bob", "bob-password", "Bob's"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/bookmarks", nil)
+		req.SetBasicAuth(tt.username, tt.password)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		var bookmarks map[int]internal.Bookmark
+		if err := json.NewDecoder(w.Body).Decode(&bookmarks); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if len(bookmarks) != 1 || bookmarks[1].Name != tt.name {
+			t.Errorf("Expected %s to see only %q, got %+v", tt.username, tt.name, bookmarks)
+		}
+	}
+}
+
+func TestUserAccounts_RejectsInvalidCredentials(t *testing.T) {
+	users := NewMockUsers()
+	users.AddUser("alice", "alice-password")
+	users.AddUser("carol", "carol-password")
+	users.SetDisabled("carol", true)
+
+	handler := createUsersTestServer(t, users)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     int
+	}{
+		{"valid", "alice", "alice-password", http.StatusOK},
+		{"no credentials", "", "", http.StatusUnauthorized},
+		{"wrong password", "alice", "bob-password", http.StatusUnauthorized},
+		{"unknown user", "mallory", "alice-password", http.StatusUnauthorized},
+		{"disabled user", "carol", "carol-password", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/bookmarks", nil)
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+
+	// Health checks need no account
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d for /health, got %d", http.StatusOK, w.Code)
+	}
+}
+
+// API Token Tests
+
+func createTokensTestServer(t *testing.T, mockStore *MockStore, tokens *MockTokens, config server.Config) http.Handler {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		Level: slog.LevelError, // Quiet during tests
+	}))
+
+	srv, err := server.New(config, mockStore, logger, server.WithTokens(tokens))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	t.Cleanup(func() {
+		srv.Close()
+	})
+
+	return srv.SetupRoutes()
+}
+
+func TestTokens_Scopes(t *testing.T) {
+	mockStore := NewMockStore()
+	mockStore.Seed(map[int]internal.Bookmark{1: testBookmark("Test")})
+
+	tokens := NewMockTokens()
+	tokens.AddToken("read-token", internal.Token{ID: "r", Owner: internal.SharedOwner, Scopes: []string{internal.ScopeRead}})
+	tokens.AddToken("write-token", internal.Token{ID: "w", Owner: internal.SharedOwner, Scopes: []string{internal.ScopeWrite}})
+	tokens.AddToken("admin-token", internal.Token{ID: "a", Owner: internal.SharedOwner, Scopes: []string{internal.ScopeAdmin}})
+	tokens.AddToken("expired-token", internal.Token{ID: "e", Owner: internal.SharedOwner, Scopes: []string{internal.ScopeAdmin}, ExpiresAt: 1})
+	tokens.AddToken("ownerless-token", internal.Token{ID: "o", Scopes: []string{internal.ScopeAdmin}})
+
+	cfg := testConfig()
+	cfg.AuthPassword = "secret123"
+	handler := createTokensTestServer(t, mockStore, tokens, cfg)
+
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"read token can read", "read-token", http.MethodGet, "/bookmarks/1", "", http.StatusOK},
+		{"read token cannot write", "read-token", http.MethodPost, "/bookmarks", `{"name":"New","url":"https://new.com"}`, http.StatusForbidden},
+		{"write token can write", "write-token", http.MethodPost, "/bookmarks", `{"name":"New","url":"https://new.com"}`, http.StatusCreated},
+		{"write token cannot read", "write-token", http.MethodGet, "/bookmarks/1", "", http.StatusForbidden},
+		{"read token cannot manage tokens", "read-token", http.MethodGet, "/tokens", "", http.StatusForbidden},
+		{"admin token can do everything", "admin-token", http.MethodDelete, "/bookmarks/1", "", http.StatusOK},
+		{"admin token can manage tokens", "admin-token", http.MethodGet, "/tokens", "", http.StatusOK},
+		{"expired token", "expired-token", http.MethodGet, "/bookmarks", "", http.StatusUnauthorized},
+		{"unknown token", "guessed-token", http.MethodGet, "/bookmarks", "", http.StatusUnauthorized},
+		{"token without an owner", "ownerless-token", http.MethodGet, "/tokens", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d: %s", tt.want, w.Code, w.Body.String())
 			}
 		})
 	}
 }
+
+func TestTokens_CreateListRevoke(t *testing.T) {
+	cfg := testConfig()
+	cfg.AuthPassword = "secret123"
+	handler := createTokensTestServer(t, NewMockStore(), NewMockTokens(), cfg)
+
+	do := func(method, path, body string, auth func(*http.Request)) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		auth(req)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	password := func(req *http.Request) { req.SetBasicAuth("user", "secret123") }
+
+	w := do(http.MethodPost, "/tokens", `{"name":"CI","scopes":["read","read"]}`, password)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created internal.CreatedToken
+	if err := json.NewDecoder(w.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if created.Secret == "" || created.Name != "CI" || !slices.Equal(created.Scopes, []string{"read"}) {
+		t.Errorf("Unexpected token: %+v", created)
+	}
+	bearer := func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+created.Secret) }
+
+	if w := do(http.MethodGet, "/bookmarks", "", bearer); w.Code != http.StatusOK {
+		t.Errorf("Expected the new token to read, got %d", w.Code)
+	}
+
+	w = do(http.MethodGet, "/tokens", "", password)
+	var listed []internal.Token
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(listed) != 1 || listed[0].ID != created.ID {
+		t.Errorf("Expected the new token to be listed, got %+v", listed)
+	}
+	if strings.Contains(w.Body.String(), created.Secret) {
+		t.Error("Token list contains the secret")
+	}
+
+	if w := do(http.MethodDelete, "/tokens/"+created.ID, "", password); w.Code != http.StatusOK {
+		t.Errorf("Expected status %d revoking the token, got %d", http.StatusOK, w.Code)
+	}
+	if w := do(http.MethodGet, "/bookmarks", "", bearer); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a revoked token to be rejected, got %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/tokens/"+created.ID, "", password); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d revoking a revoked token, got %d", http.StatusNotFound, w.Code)
+	}
+
+	for _, body := range []string{
+		`{"name":"No scopes"}`,
+		`{"scopes":["everything"]}`,
+		`{"scopes":["read"],"expires_at":1}`,
+		`not json`,
+	} {
+		if w := do(http.MethodPost, "/tokens", body, password); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for %s, got %d", http.StatusBadRequest, body, w.Code)
+		}
+	}
+}
+
+func TestTokens_NeverPublic(t *testing.T) {
+	cfg := testConfig()
+	cfg.AuthPassword = "secret123"
+	cfg.Public = true
+	handler := createTokensTestServer(t, NewMockStore(), NewMockTokens(), cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/tokens", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d listing tokens without auth in public mode, got %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestTokens_DisabledWithoutAuth(t *testing.T) {
+	tokens := NewMockTokens()
+	tokens.AddToken("admin-token", internal.Token{ID: "a", Owner: internal.SharedOwner, Scopes: []string{internal.ScopeAdmin}})
+	handler := createTokensTestServer(t, NewMockStore(), tokens, testConfig())
+
+	// Anyone could create a token, so the endpoints are not served
+	req := httptest.NewRequest(http.MethodPost, "/tokens", strings.NewReader(`{"name":"sneaky","scopes":["admin"]}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound && w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected no token endpoints without auth, got status %d: %s", w.Code, w.Body.String())
+	}
+	if len(tokens.List(internal.SharedOwner)) != 1 {
+		t.Errorf("Expected no token to be created")
+	}
+}
+
+func TestTokens_UserAccounts(t *testing.T) {
+	users := NewMockUsers()
+	alice := users.AddUser("alice", "alice-password")
+	alice.Seed(map[int]internal.Bookmark{1: testBookmark("Alice's")})
+	users.AddUser("bob", "bob-password")
+
+	tokens := NewMockTokens()
+	tokens.AddToken("alice-token", internal.Token{ID: "a", Owner: "alice", Scopes: []string{internal.ScopeRead}})
+	tokens.AddToken("shared-token", internal.Token{ID: "s", Owner: internal.SharedOwner, Scopes: []string{internal.ScopeRead}})
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelError}))
+	srv, err := server.NewWithUsers(testConfig(), users, logger, server.WithTokens(tokens))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	handler := srv.SetupRoutes()
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// A token reads the namespace of its owner
+	if w := get("/bookmarks/1", "alice-token"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Alice's") {
+		t.Errorf("Expected alice's bookmark, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A token without an owner belongs to no account
+	if w := get("/bookmarks", "shared-token"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for a token without owner, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	// Tokens stop working when their owner is disabled
+	users.SetDisabled("alice", true)
+	if w := get("/bookmarks", "alice-token"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status %d for a disabled owner, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	// Users only see their own tokens
+	req := httptest.NewRequest(http.MethodGet, "/tokens", nil)
+	req.SetBasicAuth("bob", "bob-password")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("Expected bob to have no tokens, got %s", w.Body.String())
+	}
+}
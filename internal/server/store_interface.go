@@ -1,6 +1,10 @@
 package server
 
-import "github.com/t-eckert/fave/internal"
+import (
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+)
 
 // StoreInterface defines the contract for bookmark storage operations.
 // This interface allows for easier testing via mocks and decouples the
@@ -14,17 +18,102 @@ type StoreInterface interface {
 	// The returned map is keyed by bookmark ID.
 	List() map[int]internal.Bookmark
 
-	// Add creates a new bookmark and returns its assigned ID.
-	Add(bookmark internal.Bookmark) int
+	// Count returns the number of bookmarks in the store.
+	Count() int
 
-	// Update modifies an existing bookmark.
-	// Returns an error if the bookmark does not exist.
+	// Query returns one page of bookmarks, filtered, sorted, and paginated.
+	// Returns internal.ErrInvalidCursor if the cursor cannot be used.
+	Query(opts internal.ListOptions) (internal.ListPage, error)
+
+	// Search returns up to limit bookmarks matching a full-text query, best match first.
+	// Returns internal.ErrEmptyQuery if the query has nothing to match.
+	Search(query string, limit int) ([]internal.SearchResult, error)
+
+	// Add creates a new bookmark at revision 1 and returns its assigned ID.
+	// Returns an error if the bookmark could not be durably recorded.
+	Add(bookmark internal.Bookmark) (int, error)
+
+	// Update modifies an existing bookmark and increments its revision.
+	// Returns internal.ErrNotFound if the bookmark does not exist.
 	Update(id int, bookmark internal.Bookmark) error
 
+	// UpdateIfRevision modifies an existing bookmark only if it is at the given revision.
+	// Returns internal.ErrRevisionMismatch if it has been modified since.
+	UpdateIfRevision(id int, bookmark internal.Bookmark, revision uint64) error
+
 	// Delete removes a bookmark from the store.
-	// Returns an error if the bookmark does not exist.
+	// Returns internal.ErrNotFound if the bookmark does not exist.
 	Delete(id int) error
 
+	// DeleteIfRevision removes a bookmark only if it is at the given revision.
+	// Returns internal.ErrRevisionMismatch if it has been modified since.
+	DeleteIfRevision(id int, revision uint64) error
+
+	// Batch applies create, update, and delete operations under a single lock,
+	// returning one result per operation. With atomic set, either every operation
+	// is applied or none is. Returns an error if the batch could not be durably recorded.
+	Batch(ops []internal.BatchOp, atomic bool) ([]internal.BatchOpResult, error)
+
+	// Subscribe returns a channel of changes made after sequence number since,
+	// and a function that ends the subscription. The channel is closed if the
+	// subscriber falls behind. Returns internal.ErrEventsExpired if the changes
+	// after since are no longer available.
+	Subscribe(since uint64) (<-chan internal.Event, func(), error)
+
 	// SaveSnapshot persists the current store state to disk.
+	// It is a no-op if nothing changed since the last snapshot.
+	SaveSnapshot() error
+
+	// CurrentGeneration returns the mutation generation of the in-memory state.
+	CurrentGeneration() uint64
+
+	// LastModified returns when the in-memory state last changed.
+	LastModified() time.Time
+
+	// Persisted returns the last generation saved to disk and when it was saved.
+	Persisted() (uint64, time.Time)
+}
+
+// UserStore defines the contract for user accounts, each with a bookmark
+// namespace of its own. When the server is given a UserStore, requests are
+// authenticated against it and served from the namespace of the signed-in user.
+type UserStore interface {
+	// Authenticate checks a username and password.
+	// Returns internal.ErrInvalidCredentials if they do not match an account,
+	// and internal.ErrUserDisabled if the account is disabled.
+	Authenticate(username, password string) (internal.User, error)
+
+	// Lookup returns the account of a user.
+	// Returns internal.ErrUserNotFound if the user does not exist.
+	Lookup(username string) (internal.User, error)
+
+	// Namespace returns the bookmark store of a user.
+	// Returns internal.ErrUserNotFound if the user does not exist.
+	Namespace(username string) (StoreInterface, error)
+
+	// SaveSnapshot persists the namespaces of all users to disk.
+	SaveSnapshot() error
+}
+
+// TokenStore defines the contract for API tokens. When the server is given a
+// TokenStore, requests can authenticate with "Authorization: Bearer <token>"
+// and are limited to the token's scopes.
+type TokenStore interface {
+	// Create issues a token for owner and returns it along with its secret.
+	// expiresAt is a Unix time, or 0 for a token that never expires.
+	Create(owner, name string, scopes []string, expiresAt int64) (internal.Token, string, error)
+
+	// List returns the tokens of owner.
+	List(owner string) []internal.Token
+
+	// Revoke deletes a token of owner.
+	// Returns internal.ErrTokenNotFound if owner has no token with that ID.
+	Revoke(owner, id string) error
+
+	// Authenticate returns the token matching a secret and records its use.
+	// Returns internal.ErrInvalidToken or internal.ErrTokenExpired if it cannot be used.
+	Authenticate(secret string) (internal.Token, error)
+
+	// SaveSnapshot persists changes to tokens that are not yet on disk.
 	SaveSnapshot() error
 }
@@ -0,0 +1,283 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Self-signed certificates are valid for selfSignedValidity, and replaced when
+// the server starts with less than selfSignedRenewal of that left.
+const (
+	selfSignedValidity = 365 * 24 * time.Hour
+	selfSignedRenewal  = 30 * 24 * time.Hour
+)
+
+// The certificate files are checked for changes at most once every
+// certCheckInterval, and a failure to load them is logged at most once every
+// certFailureLogInterval while it lasts.
+const (
+	certCheckInterval      = 10 * time.Second
+	certFailureLogInterval = 5 * time.Minute
+)
+
+// tlsConfig returns the TLS configuration of the server, generating a
+// self-signed certificate first if one is configured and missing.
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	certFile, keyFile := s.config.TLSFiles()
+
+	if s.config.TLSSelfSigned {
+		generated, err := ensureSelfSignedCert(certFile, keyFile, s.config.Host)
+		if err != nil {
+			return nil, fmt.Errorf("generating self-signed certificate: %w", err)
+		}
+		if generated {
+			s.logger.Warn("generated self-signed TLS certificate; clients must trust it or skip verification",
+				"cert_file", certFile, "key_file", keyFile)
+		}
+	}
+
+	reloader, err := newCertReloader(certFile, keyFile, s.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	// Client certificates are optional in the handshake, so clients can still
+	// sign in with a token or password; ClientCertAuthMiddleware decides.
+	if s.config.TLSClientCAFile != "" {
+		pem, err := os.ReadFile(s.config.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA bundle %s", s.config.TLSClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return config, nil
+}
+
+// identityFor returns the first client identity whose subject matches a certificate.
+func identityFor(identities []ClientIdentity, cert *x509.Certificate) (ClientIdentity, bool) {
+	for _, identity := range identities {
+		if certMatches(cert, identity.Subject) {
+			return identity, true
+		}
+	}
+	return ClientIdentity{}, false
+}
+
+// certMatches reports whether a certificate matches a client identity subject.
+func certMatches(cert *x509.Certificate, subject string) bool {
+	kind, value, _ := parseCertSubject(subject)
+	switch kind {
+	case "cn":
+		return cert.Subject.CommonName == value
+	case "dns":
+		return slices.Contains(cert.DNSNames, value)
+	case "email":
+		return slices.Contains(cert.EmailAddresses, value)
+	case "uri":
+		return slices.ContainsFunc(cert.URIs, func(u *url.URL) bool { return u.String() == value })
+	}
+	return false
+}
+
+// parseCertSubject splits a client identity subject such as "cn:build-bot"
+// into its kind and value.
+func parseCertSubject(subject string) (kind, value string, ok bool) {
+	kind, value, ok = strings.Cut(subject, ":")
+	switch kind {
+	case "cn", "dns", "email", "uri":
+		return kind, value, ok && value != ""
+	}
+	return "", "", false
+}
+
+// redirectHandler redirects plain HTTP requests to the same URL over HTTPS.
+func (s *Server) redirectHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if s.config.Port != "443" {
+			host = net.JoinHostPort(host, s.config.Port)
+		}
+
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// certReloader serves the certificate in a pair of PEM files, loading it again
+// when either file changes, so renewed certificates are picked up without a
+// restart.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+
+	checkedAt    time.Time // When the files were last checked for changes
+	failLoggedAt time.Time // When a failure to load them was last logged, zero once they load
+
+	mutex sync.Mutex
+}
+
+// newCertReloader loads the certificate in certFile and keyFile.
+func newCertReloader(certFile, keyFile string, logger *slog.Logger) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := c.reload(); err != nil {
+		return nil, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	c.checkedAt = time.Now()
+	return c, nil
+}
+
+// GetCertificate returns the current certificate, for tls.Config.
+// The files are checked at most once every certCheckInterval, not on every
+// handshake. If they changed but cannot be loaded, for example because only
+// one of them has been replaced so far, the previous certificate is used.
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(c.checkedAt) < certCheckInterval {
+		return c.cert, nil
+	}
+	c.checkedAt = now
+
+	if err := c.reload(); err != nil {
+		if now.Sub(c.failLoggedAt) >= certFailureLogInterval {
+			c.logger.Error("failed to reload TLS certificate; keeping the current one", "error", err)
+			c.failLoggedAt = now
+		}
+	} else {
+		c.failLoggedAt = time.Time{}
+	}
+	return c.cert, nil
+}
+
+// reload loads the certificate again if either file changed since it was last loaded.
+// The caller must hold the lock, if the reloader is in use.
+func (c *certReloader) reload() error {
+	certInfo, err := os.Stat(c.certFile)
+	if err != nil {
+		return err
+	}
+	keyInfo, err := os.Stat(c.keyFile)
+	if err != nil {
+		return err
+	}
+	if certInfo.ModTime().Equal(c.certMod) && keyInfo.ModTime().Equal(c.keyMod) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+
+	if c.cert != nil {
+		c.logger.Info("TLS certificate reloaded", "cert_file", c.certFile)
+	}
+	c.cert = &cert
+	c.certMod, c.keyMod = certInfo.ModTime(), keyInfo.ModTime()
+
+	return nil
+}
+
+// ensureSelfSignedCert writes a new self-signed certificate for host, and for
+// localhost, to certFile and keyFile, unless they already hold one that is not
+// about to expire. It reports whether a certificate was generated.
+func ensureSelfSignedCert(certFile, keyFile, host string) (bool, error) {
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if time.Until(cert.Leaf.NotAfter) > selfSignedRenewal {
+			return false, nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return false, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"fave"}, CommonName: "fave self-signed"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !ip.IsUnspecified() {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		}
+	} else if host != "" && host != "localhost" {
+		template.DNSNames = append(template.DNSNames, host)
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return false, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return false, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0755); err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(filepath.Dir(certFile), 0755); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// maxTokenNameLength bounds the name given to an API token.
+const maxTokenNameLength = 100
+
+// CreateTokenHandler issues an API token for the signed-in user, or for the
+// server as a whole without user accounts. The token's secret is only ever
+// included in this response.
+func (s *Server) CreateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req internal.TokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Name) > maxTokenNameLength {
+		writeJSONError(w, "Token name is too long", http.StatusBadRequest)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		writeJSONError(w, "At least one scope is required", http.StatusBadRequest)
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !internal.ValidScope(scope) {
+			writeJSONError(w, "Invalid scope: "+scope, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.ExpiresAt != 0 && req.ExpiresAt <= time.Now().Unix() {
+		writeJSONError(w, "Expiry must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	token, secret, err := s.tokens.Create(s.tokenOwner(r), req.Name, scopes, req.ExpiresAt)
+	if err != nil {
+		s.logger.Error("failed to create token", "error", err)
+		writeJSONError(w, "Failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("token created", "token_id", token.ID, "owner", token.Owner, "scopes", token.Scopes)
+
+	writeJSON(w, internal.CreatedToken{Token: token, Secret: secret}, http.StatusCreated)
+}
+
+// ListTokensHandler lists the API tokens of the signed-in user, without their secrets.
+func (s *Server) ListTokensHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, s.tokens.List(s.tokenOwner(r)), http.StatusOK)
+}
+
+// RevokeTokenHandler deletes an API token of the signed-in user.
+// Requests using it are rejected from then on.
+func (s *Server) RevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if err := s.tokens.Revoke(s.tokenOwner(r), id); err != nil {
+		if errors.Is(err, internal.ErrTokenNotFound) {
+			writeJSONError(w, "Token not found", http.StatusNotFound)
+			return
+		}
+		s.logger.Error("failed to revoke token", "error", err)
+		writeJSONError(w, "Failed to revoke token", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("token revoked", "token_id", id)
+
+	writeJSON(w, map[string]string{"id": id}, http.StatusOK)
+}
+
+// ownerFor returns the user a request acts for, or "" without user accounts.
+func ownerFor(r *http.Request) string {
+	username, _ := r.Context().Value(userKey).(string)
+	return username
+}
+
+// tokenOwner returns the owner of the tokens a request manages: the signed-in
+// user with user accounts, and internal.SharedOwner otherwise.
+func (s *Server) tokenOwner(r *http.Request) string {
+	if s.users == nil {
+		return internal.SharedOwner
+	}
+	return ownerFor(r)
+}
+
+// isTokenPath reports whether a path belongs to the token management endpoints.
+func isTokenPath(path string) bool {
+	return path == "/tokens" || strings.HasPrefix(path, "/tokens/")
+}
+
+// requiredScope returns the token scope needed for a request: admin to manage
+// tokens, read for other GET requests, and write for everything else.
+func requiredScope(r *http.Request) string {
+	switch {
+	case isTokenPath(r.URL.Path):
+		return internal.ScopeAdmin
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return internal.ScopeRead
+	default:
+		return internal.ScopeWrite
+	}
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/t-eckert/fave/internal"
+	"github.com/t-eckert/fave/internal/netscape"
+)
+
+// maxImportSize caps the size of an uploaded bookmark file.
+const maxImportSize = 32 << 20
+
+// ImportBookmarksHandler adds every bookmark in an uploaded bookmark file.
+// The format query parameter selects the file format; only netscape is supported.
+// Bookmarks that fail validation or whose URL is already stored (or appears earlier
+// in the file) are skipped and listed in the report. With dry_run=true nothing
+// is stored and the report describes what an import would do.
+func (s *Server) ImportBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	store := s.storeFor(r)
+
+	if format := r.URL.Query().Get("format"); format != "" && format != netscape.Format {
+		writeJSONError(w, "Unsupported import format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeJSONError(w, "Invalid dry_run: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+
+	bookmarks, err := netscape.Parse(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		writeJSONError(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	report, err := internal.ImportBookmarks(store, bookmarks, dryRun, s.config.NormalizeTags)
+	if err != nil {
+		s.logger.Error("import failed", "error", err, "bookmarks", len(bookmarks))
+		writeJSONError(w, "Failed to save bookmarks", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("bookmarks imported",
+		"dry_run", dryRun,
+		"total", report.Total,
+		"imported", report.Imported,
+		"duplicates", len(report.Duplicates),
+		"invalid", len(report.Invalid),
+	)
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	writeJSON(w, report, status)
+}
+
+// ExportBookmarksHandler writes every bookmark, ordered by ID, as a bookmark file.
+// The format query parameter selects the file format; only netscape is supported.
+func (s *Server) ExportBookmarksHandler(w http.ResponseWriter, r *http.Request) {
+	store := s.storeFor(r)
+
+	if format := r.URL.Query().Get("format"); format != "" && format != netscape.Format {
+		writeJSONError(w, "Unsupported export format: "+format, http.StatusBadRequest)
+		return
+	}
+
+	bookmarks := store.List()
+	entries := make([]internal.BookmarkEntry, 0, len(bookmarks))
+	for _, id := range slices.Sorted(maps.Keys(bookmarks)) {
+		entries = append(entries, internal.BookmarkEntry{ID: id, Bookmark: bookmarks[id]})
+	}
+
+	w.Header().Set("Content-Type", netscape.ContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="bookmarks.html"`)
+	w.WriteHeader(http.StatusOK)
+	if err := netscape.Write(w, entries); err != nil {
+		s.logger.Error("export failed", "error", err)
+	}
+}
@@ -0,0 +1,19 @@
+package server
+
+import "github.com/t-eckert/fave/internal"
+
+// Limits on bookmark fields accepted by the server.
+const (
+	MaxURLLength         = internal.MaxURLLength
+	MaxNameLength        = internal.MaxNameLength
+	MaxDescriptionLength = internal.MaxDescriptionLength
+	MaxTags              = internal.MaxTags
+	MaxTagLength         = internal.MaxTagLength
+)
+
+// FieldError describes why a single field of a request was rejected.
+type FieldError = internal.FieldError
+
+// ValidationError is returned when a bookmark fails validation.
+// It lists every offending field rather than stopping at the first.
+type ValidationError = internal.ValidationError
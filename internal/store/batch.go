@@ -0,0 +1,121 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// Batch applies a list of create, update, and delete operations under a single
+// lock and records them in the write-ahead log with a single write.
+// Operations see the effects of earlier operations in the same batch.
+// Updates keep the CreatedAt of the bookmark they replace.
+//
+// When atomic is set and any operation fails, nothing is applied and every
+// other operation reports internal.ErrBatchAborted. Otherwise the operations
+// that succeed are applied and failures are reported in their results.
+// The returned error is only set if the batch could not be made durable.
+func (s *Store) Batch(ops []internal.BatchOp, atomic bool) ([]internal.BatchOpResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	results := make([]internal.BatchOpResult, len(ops))
+	batch := walRecord{Op: opBatch}
+
+	// Bookmarks changed by earlier operations in the batch; nil marks a deletion
+	pending := make(map[int]*internal.Bookmark)
+	lookup := func(id int) (internal.Bookmark, bool) {
+		if bookmark, ok := pending[id]; ok {
+			if bookmark == nil {
+				return internal.Bookmark{}, false
+			}
+			return *bookmark, true
+		}
+		bookmark, ok := s.Bookmarks[id]
+		return bookmark, ok
+	}
+
+	nextID := s.IdxCounter
+	generation := s.Generation
+	failed := false
+
+	for i, op := range ops {
+		rec, err := planBatchOp(op, nextID+1, lookup)
+		if err != nil {
+			results[i].ID = op.ID
+			results[i].Err = err
+			failed = true
+			continue
+		}
+
+		generation++
+		rec.Generation = generation
+		batch.Records = append(batch.Records, rec)
+		pending[rec.ID] = rec.Bookmark
+		if rec.ID > nextID {
+			nextID = rec.ID
+		}
+
+		results[i].ID = rec.ID
+		if rec.Bookmark != nil {
+			results[i].Revision = rec.Bookmark.Revision
+		}
+	}
+
+	if atomic && failed {
+		for i := range results {
+			if results[i].Err == nil {
+				results[i] = internal.BatchOpResult{ID: ops[i].ID, Err: internal.ErrBatchAborted}
+			}
+		}
+		return results, nil
+	}
+
+	if len(batch.Records) == 0 {
+		return results, nil
+	}
+
+	batch.Generation = generation
+	if err := s.wal.append(batch); err != nil {
+		return nil, err
+	}
+	s.apply(batch)
+
+	return results, nil
+}
+
+// planBatchOp checks one batch operation against the current state and returns
+// the log record that carries it out. newID is the ID a created bookmark gets.
+func planBatchOp(op internal.BatchOp, newID int, lookup func(int) (internal.Bookmark, bool)) (walRecord, error) {
+	switch op.Op {
+	case internal.BatchCreate:
+		if op.Bookmark == nil {
+			return walRecord{}, fmt.Errorf("create needs a bookmark")
+		}
+		bookmark := *op.Bookmark
+		bookmark.Revision = 1
+		return walRecord{Op: opAdd, ID: newID, Bookmark: &bookmark}, nil
+
+	case internal.BatchUpdate, internal.BatchDelete:
+		if op.Op == internal.BatchUpdate && op.Bookmark == nil {
+			return walRecord{}, fmt.Errorf("update needs a bookmark")
+		}
+		existing, exists := lookup(op.ID)
+		if !exists {
+			return walRecord{}, internal.ErrNotFound
+		}
+		if op.IfRevision != nil && existing.Revision != *op.IfRevision {
+			return walRecord{}, internal.ErrRevisionMismatch
+		}
+		if op.Op == internal.BatchDelete {
+			return walRecord{Op: opDelete, ID: op.ID}, nil
+		}
+		bookmark := *op.Bookmark
+		bookmark.CreatedAt = existing.CreatedAt
+		bookmark.Revision = existing.Revision + 1
+		return walRecord{Op: opUpdate, ID: op.ID, Bookmark: &bookmark}, nil
+
+	default:
+		return walRecord{}, fmt.Errorf("unknown batch operation %q", op.Op)
+	}
+}
@@ -0,0 +1,679 @@
+package store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"maps"
+	"os"
+	"slices"
+	"time"
+)
+
+// A B-tree file is a sequence of pageSize pages. The first two are meta pages,
+// each naming the root of the tree as of a committed transaction; the one with
+// the highest transaction ID that is intact is current. The rest hold the nodes
+// of a B+tree keyed by bookmark ID, each in one page or, if it holds a value too
+// large for one, in a run of consecutive pages.
+//
+// Nodes are never modified in place. A transaction writes the nodes it changes,
+// and their ancestors, to free pages, syncs them, and then writes the meta page
+// of the older transaction, so a crash at any point leaves the last committed
+// tree intact. Pages released by a transaction are reused from the next one on.
+const (
+	pageSize = 4096
+
+	btreeMagic   = "FAVEBTRE"
+	btreeVersion = 1
+
+	metaSize        = 68
+	nodeHeaderSize  = 12
+	leafEntrySize   = 12 // Key and value length, followed by the value
+	branchEntrySize = 16 // Key and page of the child
+
+	// Nodes left smaller than minFill by a transaction are merged with a sibling.
+	minFill = pageSize / 4
+)
+
+// Node types, stored in the first byte of a node.
+const (
+	leafNode   = 1
+	branchNode = 2
+)
+
+// pgid is the number of a page in a B-tree file.
+type pgid uint64
+
+// meta is the content of a meta page: the root of the tree and the store
+// counters as of one committed transaction.
+type meta struct {
+	txid       uint64
+	root       pgid   // 0 if the tree is empty
+	pageCount  uint64 // Pages in use or free, including the meta pages
+	idxCounter uint64
+	generation uint64
+	savedAt    int64 // Unix nanoseconds, 0 if never saved
+}
+
+func (m meta) encode() []byte {
+	buf := make([]byte, pageSize)
+	copy(buf, btreeMagic)
+	binary.LittleEndian.PutUint32(buf[8:], btreeVersion)
+	binary.LittleEndian.PutUint32(buf[12:], pageSize)
+	binary.LittleEndian.PutUint64(buf[16:], m.txid)
+	binary.LittleEndian.PutUint64(buf[24:], uint64(m.root))
+	binary.LittleEndian.PutUint64(buf[32:], m.pageCount)
+	binary.LittleEndian.PutUint64(buf[40:], m.idxCounter)
+	binary.LittleEndian.PutUint64(buf[48:], m.generation)
+	binary.LittleEndian.PutUint64(buf[56:], uint64(m.savedAt))
+	binary.LittleEndian.PutUint32(buf[64:], crc32.ChecksumIEEE(buf[:64]))
+	return buf
+}
+
+// decodeMeta parses a meta page, reporting false if it is torn or not a meta page.
+func decodeMeta(buf []byte) (meta, bool) {
+	if len(buf) < metaSize || string(buf[:8]) != btreeMagic {
+		return meta{}, false
+	}
+	if crc32.ChecksumIEEE(buf[:64]) != binary.LittleEndian.Uint32(buf[64:]) {
+		return meta{}, false
+	}
+	if binary.LittleEndian.Uint32(buf[8:]) != btreeVersion || binary.LittleEndian.Uint32(buf[12:]) != pageSize {
+		return meta{}, false
+	}
+	return meta{
+		txid:       binary.LittleEndian.Uint64(buf[16:]),
+		root:       pgid(binary.LittleEndian.Uint64(buf[24:])),
+		pageCount:  binary.LittleEndian.Uint64(buf[32:]),
+		idxCounter: binary.LittleEndian.Uint64(buf[40:]),
+		generation: binary.LittleEndian.Uint64(buf[48:]),
+		savedAt:    int64(binary.LittleEndian.Uint64(buf[56:])),
+	}, true
+}
+
+// node is a B+tree node read into memory for a transaction.
+type node struct {
+	leaf     bool
+	keys     []uint64
+	values   [][]byte // Leaf values
+	children []child  // Branch children; keys[i] is at most the smallest key under children[i]
+
+	pgid  pgid // Where the node is stored, 0 if it has not been written
+	span  int  // Pages the node occupies
+	dirty bool // Changed by the transaction in progress
+}
+
+// child is a reference from a branch to a node below it.
+type child struct {
+	pgid pgid
+	node *node // nil until read
+}
+
+func (n *node) entrySize(i int) int {
+	if n.leaf {
+		return leafEntrySize + len(n.values[i])
+	}
+	return branchEntrySize
+}
+
+func (n *node) size() int {
+	size := nodeHeaderSize
+	for i := range n.keys {
+		size += n.entrySize(i)
+	}
+	return size
+}
+
+// encode serializes a node, padded to whole pages.
+func (n *node) encode() []byte {
+	size := n.size()
+	span := (size + pageSize - 1) / pageSize
+	buf := make([]byte, span*pageSize)
+
+	buf[0] = branchNode
+	if n.leaf {
+		buf[0] = leafNode
+	}
+	binary.LittleEndian.PutUint16(buf[2:], uint16(len(n.keys)))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(span))
+
+	offset := nodeHeaderSize
+	for i, key := range n.keys {
+		binary.LittleEndian.PutUint64(buf[offset:], key)
+		if n.leaf {
+			binary.LittleEndian.PutUint32(buf[offset+8:], uint32(len(n.values[i])))
+			copy(buf[offset+leafEntrySize:], n.values[i])
+		} else {
+			binary.LittleEndian.PutUint64(buf[offset+8:], uint64(n.children[i].pgid))
+		}
+		offset += n.entrySize(i)
+	}
+
+	binary.LittleEndian.PutUint32(buf[8:], nodeChecksum(buf, size))
+	return buf
+}
+
+// nodeChecksum covers an encoded node of the given size, except the checksum itself.
+func nodeChecksum(buf []byte, size int) uint32 {
+	return crc32.Update(crc32.ChecksumIEEE(buf[:8]), crc32.IEEETable, buf[nodeHeaderSize:size])
+}
+
+// decodeNode parses a node stored at page id.
+func decodeNode(id pgid, buf []byte) (*node, error) {
+	corrupt := func(reason string) error {
+		return fmt.Errorf("corrupt btree node at page %d: %s", id, reason)
+	}
+
+	n := &node{
+		leaf: buf[0] == leafNode,
+		pgid: id,
+		span: int(binary.LittleEndian.Uint32(buf[4:])),
+	}
+	if buf[0] != leafNode && buf[0] != branchNode {
+		return nil, corrupt("unknown node type")
+	}
+
+	count := int(binary.LittleEndian.Uint16(buf[2:]))
+	offset := nodeHeaderSize
+	for range count {
+		if offset+branchEntrySize > len(buf) {
+			return nil, corrupt("entries overrun the node")
+		}
+		n.keys = append(n.keys, binary.LittleEndian.Uint64(buf[offset:]))
+		if n.leaf {
+			length := int(binary.LittleEndian.Uint32(buf[offset+8:]))
+			offset += leafEntrySize
+			if offset+length > len(buf) {
+				return nil, corrupt("value overruns the node")
+			}
+			n.values = append(n.values, slices.Clone(buf[offset:offset+length]))
+			offset += length
+		} else {
+			n.children = append(n.children, child{pgid: pgid(binary.LittleEndian.Uint64(buf[offset+8:]))})
+			offset += branchEntrySize
+		}
+	}
+
+	if nodeChecksum(buf, offset) != binary.LittleEndian.Uint32(buf[8:]) {
+		return nil, corrupt("checksum mismatch")
+	}
+	return n, nil
+}
+
+// split divides a node into nodes that each fit in a page, unless they hold a
+// single entry that does not. A node without entries splits into none.
+func (n *node) split() []*node {
+	var parts []*node
+	var current *node
+	size := 0
+	for i, key := range n.keys {
+		entry := n.entrySize(i)
+		if current == nil || size+entry > pageSize {
+			current = &node{leaf: n.leaf}
+			parts = append(parts, current)
+			size = nodeHeaderSize
+		}
+		current.keys = append(current.keys, key)
+		if n.leaf {
+			current.values = append(current.values, n.values[i])
+		} else {
+			current.children = append(current.children, n.children[i])
+		}
+		size += entry
+	}
+	return parts
+}
+
+// btree is an open B-tree file.
+// It is not safe for concurrent use; the store's mutex guards it.
+type btree struct {
+	file *os.File
+	meta meta
+	free []pgid // Pages not used by the current tree, sorted
+}
+
+// isBTreeFile reports whether the file at fileName is a B-tree file.
+// A missing or empty file is not.
+func isBTreeFile(fileName string) (bool, error) {
+	file, err := os.Open(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(btreeMagic))
+	if _, err := file.ReadAt(magic, 0); err != nil {
+		return false, nil
+	}
+	return string(magic) == btreeMagic, nil
+}
+
+// openBTree opens the B-tree file at fileName, creating an empty one if the
+// file does not exist or is empty. Call scan before committing to it, to find
+// the pages that are free.
+func openBTree(fileName string) (*btree, error) {
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+	t := &btree{file: file}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		t.meta = meta{pageCount: 2}
+		buf := t.meta.encode()
+		for slot := range 2 {
+			if _, err := file.WriteAt(buf, int64(slot)*pageSize); err != nil {
+				file.Close()
+				return nil, err
+			}
+		}
+		if err := file.Sync(); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return t, nil
+	}
+
+	found := false
+	buf := make([]byte, pageSize)
+	for slot := range 2 {
+		if _, err := file.ReadAt(buf, int64(slot)*pageSize); err != nil {
+			continue
+		}
+		if m, ok := decodeMeta(buf); ok && (!found || m.txid > t.meta.txid) {
+			t.meta = m
+			found = true
+		}
+	}
+	if !found {
+		file.Close()
+		return nil, fmt.Errorf("%s is not a btree file, or both of its meta pages are corrupt", fileName)
+	}
+
+	return t, nil
+}
+
+func (t *btree) close() error {
+	return t.file.Close()
+}
+
+// read reads the node stored at page id.
+func (t *btree) read(id pgid) (*node, error) {
+	if id < 2 || uint64(id) >= t.meta.pageCount {
+		return nil, fmt.Errorf("corrupt btree: page %d out of range", id)
+	}
+
+	buf := make([]byte, pageSize)
+	if _, err := t.file.ReadAt(buf, int64(id)*pageSize); err != nil {
+		return nil, fmt.Errorf("reading btree page %d: %w", id, err)
+	}
+	span := int(binary.LittleEndian.Uint32(buf[4:]))
+	if span < 1 {
+		return nil, fmt.Errorf("corrupt btree: node at page %d has no pages", id)
+	}
+	if span > 1 {
+		if uint64(id)+uint64(span) > t.meta.pageCount {
+			return nil, fmt.Errorf("corrupt btree: node at page %d overruns the file", id)
+		}
+		buf = make([]byte, span*pageSize)
+		if _, err := t.file.ReadAt(buf, int64(id)*pageSize); err != nil {
+			return nil, fmt.Errorf("reading btree page %d: %w", id, err)
+		}
+	}
+
+	return decodeNode(id, buf)
+}
+
+// scan calls fn with every entry of the tree in key order, and rebuilds the
+// list of free pages from the pages the tree does not use.
+func (t *btree) scan(fn func(key uint64, value []byte) error) error {
+	used := make([]bool, t.meta.pageCount)
+
+	var walk func(id pgid) error
+	walk = func(id pgid) error {
+		n, err := t.read(id)
+		if err != nil {
+			return err
+		}
+		for p := range n.span {
+			if used[int(id)+p] {
+				return fmt.Errorf("corrupt btree: page %d is used twice", int(id)+p)
+			}
+			used[int(id)+p] = true
+		}
+
+		for i, key := range n.keys {
+			if n.leaf {
+				if err := fn(key, n.values[i]); err != nil {
+					return err
+				}
+			} else if err := walk(n.children[i].pgid); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if t.meta.root != 0 {
+		if err := walk(t.meta.root); err != nil {
+			return err
+		}
+	}
+
+	t.free = nil
+	for id := 2; id < len(used); id++ {
+		if !used[id] {
+			t.free = append(t.free, pgid(id))
+		}
+	}
+	return nil
+}
+
+// commit applies changes to the tree, where a nil value deletes the key, and
+// records the store counters with them. Only the nodes on the path to a
+// changed key are written. On error, the tree is left as it was.
+func (t *btree) commit(changes map[uint64][]byte, idxCounter, generation uint64) error {
+	tx := &btreeTx{t: t, free: slices.Clone(t.free), pageCount: t.meta.pageCount}
+
+	root := &node{leaf: true}
+	if t.meta.root != 0 {
+		var err error
+		if root, err = t.read(t.meta.root); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(changes)) {
+		var err error
+		if value := changes[key]; value != nil {
+			err = tx.put(root, key, value)
+		} else {
+			_, err = tx.delete(root, key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	rootID, err := tx.spillRoot(root)
+	if err != nil {
+		return err
+	}
+
+	// The new nodes must be on stable storage before the meta page points to them.
+	if err := t.file.Sync(); err != nil {
+		return err
+	}
+
+	m := meta{
+		txid:       t.meta.txid + 1,
+		root:       rootID,
+		pageCount:  tx.pageCount,
+		idxCounter: idxCounter,
+		generation: generation,
+		savedAt:    time.Now().UnixNano(),
+	}
+	if _, err := t.file.WriteAt(m.encode(), int64(m.txid%2)*pageSize); err != nil {
+		return err
+	}
+	if err := t.file.Sync(); err != nil {
+		return err
+	}
+
+	t.meta = m
+	t.free = append(tx.free, tx.released...)
+	slices.Sort(t.free)
+
+	return nil
+}
+
+// btreeTx tracks the pages allocated and released by a commit in progress.
+type btreeTx struct {
+	t         *btree
+	free      []pgid // Pages free to allocate, sorted
+	released  []pgid // Pages used by the committed tree; free once the commit is done
+	pageCount uint64
+}
+
+// child returns the ith child of a branch, reading it if needed.
+func (tx *btreeTx) child(n *node, i int) (*node, error) {
+	if n.children[i].node == nil {
+		c, err := tx.t.read(n.children[i].pgid)
+		if err != nil {
+			return nil, err
+		}
+		n.children[i].node = c
+	}
+	return n.children[i].node, nil
+}
+
+// childIndex returns the index of the child of a branch that key belongs under.
+func childIndex(n *node, key uint64) int {
+	i, found := slices.BinarySearch(n.keys, key)
+	if !found && i > 0 {
+		i--
+	}
+	return i
+}
+
+// put sets the value of key in the subtree under n.
+func (tx *btreeTx) put(n *node, key uint64, value []byte) error {
+	n.dirty = true
+
+	if n.leaf {
+		i, found := slices.BinarySearch(n.keys, key)
+		if found {
+			n.values[i] = value
+		} else {
+			n.keys = slices.Insert(n.keys, i, key)
+			n.values = slices.Insert(n.values, i, value)
+		}
+		return nil
+	}
+
+	if len(n.keys) == 0 {
+		return fmt.Errorf("corrupt btree: empty branch at page %d", n.pgid)
+	}
+	i := childIndex(n, key)
+	c, err := tx.child(n, i)
+	if err != nil {
+		return err
+	}
+	if key < n.keys[i] {
+		n.keys[i] = key
+	}
+	return tx.put(c, key, value)
+}
+
+// delete removes key from the subtree under n, reporting whether it was there.
+func (tx *btreeTx) delete(n *node, key uint64) (bool, error) {
+	if n.leaf {
+		i, found := slices.BinarySearch(n.keys, key)
+		if found {
+			n.keys = slices.Delete(n.keys, i, i+1)
+			n.values = slices.Delete(n.values, i, i+1)
+			n.dirty = true
+		}
+		return found, nil
+	}
+
+	if len(n.keys) == 0 {
+		return false, nil
+	}
+	c, err := tx.child(n, childIndex(n, key))
+	if err != nil {
+		return false, err
+	}
+	deleted, err := tx.delete(c, key)
+	if deleted {
+		n.dirty = true
+	}
+	return deleted, err
+}
+
+// spillRoot writes the changed nodes of the tree under root and returns the
+// page of the new root, adding levels while the root splits and removing them
+// while it has a single child.
+func (tx *btreeTx) spillRoot(root *node) (pgid, error) {
+	if !root.dirty {
+		return root.pgid, nil
+	}
+
+	parts, err := tx.spill(root)
+	if err != nil {
+		return 0, err
+	}
+	for len(parts) > 1 {
+		parent := &node{dirty: true}
+		for _, p := range parts {
+			parent.keys = append(parent.keys, p.keys[0])
+			parent.children = append(parent.children, child{pgid: p.pgid, node: p})
+		}
+		if parts, err = tx.spill(parent); err != nil {
+			return 0, err
+		}
+	}
+	if len(parts) == 0 {
+		return 0, nil
+	}
+
+	top := parts[0]
+	for !top.leaf && len(top.children) == 1 {
+		tx.release(top.pgid, top.span)
+		if top, err = tx.child(top, 0); err != nil {
+			return 0, err
+		}
+	}
+	return top.pgid, nil
+}
+
+// spill writes a changed node, after the changed nodes below it, to newly
+// allocated pages, splitting it into as many nodes as it takes for each to
+// fit in a page. It returns the nodes written, none if n ended up empty.
+func (tx *btreeTx) spill(n *node) ([]*node, error) {
+	if !n.leaf {
+		if err := tx.rebalance(n); err != nil {
+			return nil, err
+		}
+
+		var keys []uint64
+		var children []child
+		for i, c := range n.children {
+			if c.node == nil || !c.node.dirty {
+				keys = append(keys, n.keys[i])
+				children = append(children, c)
+				continue
+			}
+			parts, err := tx.spill(c.node)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range parts {
+				keys = append(keys, p.keys[0])
+				children = append(children, child{pgid: p.pgid, node: p})
+			}
+		}
+		n.keys, n.children = keys, children
+	}
+
+	if n.pgid != 0 {
+		tx.release(n.pgid, n.span)
+	}
+
+	parts := n.split()
+	for _, p := range parts {
+		if err := tx.write(p); err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+// rebalance merges the changed children of a branch that are smaller than
+// minFill into a neighbour, so deletions do not leave the tree sparse.
+func (tx *btreeTx) rebalance(n *node) error {
+	for i := 0; i < len(n.children) && len(n.children) > 1; i++ {
+		c := n.children[i].node
+		if c == nil || !c.dirty || c.size() >= minFill {
+			continue
+		}
+
+		left, right := i, i+1
+		if right == len(n.children) {
+			left, right = i-1, i
+		}
+		l, err := tx.child(n, left)
+		if err != nil {
+			return err
+		}
+		r, err := tx.child(n, right)
+		if err != nil {
+			return err
+		}
+
+		l.keys = append(l.keys, r.keys...)
+		l.values = append(l.values, r.values...)
+		l.children = append(l.children, r.children...)
+		l.dirty = true
+		if r.pgid != 0 {
+			tx.release(r.pgid, r.span)
+		}
+		n.keys = slices.Delete(n.keys, right, right+1)
+		n.children = slices.Delete(n.children, right, right+1)
+
+		// Look at the merged node again; it may still be too small.
+		i = left - 1
+	}
+	return nil
+}
+
+// write stores a node in newly allocated pages.
+func (tx *btreeTx) write(n *node) error {
+	buf := n.encode()
+	n.span = len(buf) / pageSize
+	n.pgid = tx.allocate(n.span)
+	n.dirty = false
+
+	_, err := tx.t.file.WriteAt(buf, int64(n.pgid)*pageSize)
+	return err
+}
+
+// allocate returns the first page of a run of count free pages, growing the
+// file if there is no such run.
+func (tx *btreeTx) allocate(count int) pgid {
+	run := 0
+	for i, id := range tx.free {
+		if run > 0 && id == tx.free[i-1]+1 {
+			run++
+		} else {
+			run = 1
+		}
+		if run == count {
+			start := i - count + 1
+			first := tx.free[start]
+			tx.free = slices.Delete(tx.free, start, i+1)
+			return first
+		}
+	}
+
+	first := pgid(tx.pageCount)
+	tx.pageCount += uint64(count)
+	return first
+}
+
+// release frees the pages of a node of the committed tree once the commit is done.
+func (tx *btreeTx) release(id pgid, span int) {
+	for p := range span {
+		tx.released = append(tx.released, id+pgid(p))
+	}
+}
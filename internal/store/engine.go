@@ -0,0 +1,243 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// Storage engines, which decide how a store's snapshots are kept on disk.
+const (
+	// EngineJSON keeps the whole store in one JSON file, rewritten by every snapshot.
+	EngineJSON = "json"
+
+	// EngineBTree keeps bookmarks in a B-tree file, where a snapshot only
+	// writes the pages holding bookmarks that changed.
+	EngineBTree = "btree"
+)
+
+// FileEngine returns the storage engine of the file at fileName: EngineBTree
+// for a B-tree file, and EngineJSON otherwise, including when it does not exist.
+func FileEngine(fileName string) (string, error) {
+	btree, err := isBTreeFile(fileName)
+	if err != nil {
+		return "", err
+	}
+	if btree {
+		return EngineBTree, nil
+	}
+	return EngineJSON, nil
+}
+
+// engine persists the snapshots of a store.
+type engine interface {
+	// load reads the last snapshot into the store, setting its bookmarks,
+	// counters, and when the snapshot was saved and last modified.
+	load(s *Store) error
+
+	// save persists the state of the store, of which the bookmarks in s.dirty
+	// changed since the last snapshot. The caller must hold the write lock.
+	save(s *Store) error
+
+	close() error
+}
+
+// jsonEngine keeps snapshots as the JSON encoding of the store, after a header
+// with the schema version. Snapshots of older versions are migrated on load.
+type jsonEngine struct {
+	fileName string
+}
+
+func (e *jsonEngine) load(s *Store) error {
+	// Open the file for persistence.
+	file, err := os.OpenFile(e.fileName, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return err
+	}
+	defer file.Close() // Close after reading initial data
+
+	// Check if file has content.
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	// If file has content, read and unmarshal it.
+	if fileInfo.Size() > 0 {
+		if btree, err := isBTreeFile(e.fileName); err == nil && btree {
+			return fmt.Errorf("%s uses the %s storage engine", e.fileName, EngineBTree)
+		}
+
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		data, _, err = upgradeSnapshot(e.fileName, data)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &jsonSnapshot{Store: s}); err != nil {
+			return err
+		}
+		s.persistedAt = fileInfo.ModTime()
+	}
+	s.modifiedAt = fileInfo.ModTime()
+
+	return nil
+}
+
+func (e *jsonEngine) save(s *Store) error {
+	b, err := json.Marshal(jsonSnapshot{
+		snapshotHeader: snapshotHeader{Format: snapshotFormat, SchemaVersion: SchemaVersion},
+		Store:          s,
+	})
+	if err != nil {
+		return err
+	}
+
+	return replaceFile(e.fileName, "snapshot-*.json", b)
+}
+
+func (e *jsonEngine) close() error {
+	return nil
+}
+
+// btreeEngine keeps snapshots in a B-tree file holding each bookmark under its
+// ID, so saving one writes the changed bookmarks, not the whole store.
+// Bookmarks are still all held in memory, where they are queried and indexed.
+type btreeEngine struct {
+	tree *btree
+}
+
+// openBTreeEngine opens the B-tree file at fileName, first converting it if
+// it holds a JSON snapshot.
+func openBTreeEngine(fileName string) (*btreeEngine, error) {
+	if err := migrateToBTree(fileName); err != nil {
+		return nil, fmt.Errorf("migrating %s to the %s storage engine: %w", fileName, EngineBTree, err)
+	}
+
+	tree, err := openBTree(fileName)
+	if err != nil {
+		return nil, err
+	}
+	return &btreeEngine{tree: tree}, nil
+}
+
+func (e *btreeEngine) load(s *Store) error {
+	err := e.tree.scan(func(key uint64, value []byte) error {
+		var bookmark internal.Bookmark
+		if err := json.Unmarshal(value, &bookmark); err != nil {
+			return fmt.Errorf("decoding bookmark %d: %w", key, err)
+		}
+		s.Bookmarks[int(key)] = bookmark
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.IdxCounter = int(e.tree.meta.idxCounter)
+	s.Generation = e.tree.meta.generation
+	if e.tree.meta.savedAt != 0 {
+		s.persistedAt = time.Unix(0, e.tree.meta.savedAt)
+		s.modifiedAt = s.persistedAt
+	} else {
+		s.modifiedAt = time.Now()
+	}
+
+	return nil
+}
+
+func (e *btreeEngine) save(s *Store) error {
+	changes := make(map[uint64][]byte, len(s.dirty))
+	for id := range s.dirty {
+		bookmark, ok := s.Bookmarks[id]
+		if !ok {
+			changes[uint64(id)] = nil
+			continue
+		}
+		value, err := json.Marshal(bookmark)
+		if err != nil {
+			return err
+		}
+		changes[uint64(id)] = value
+	}
+
+	return e.tree.commit(changes, uint64(s.IdxCounter), s.Generation)
+}
+
+func (e *btreeEngine) close() error {
+	return e.tree.close()
+}
+
+// migrateToBTree converts a JSON snapshot at fileName into a B-tree file in
+// place, keeping the original as fileName.bak. A snapshot of an older schema
+// version is migrated on the way. Files that are missing, empty, or already
+// B-tree files are left alone. The write-ahead log needs no conversion; it is
+// replayed on top of the converted snapshot as usual.
+func migrateToBTree(fileName string) error {
+	info, err := os.Stat(fileName)
+	if os.IsNotExist(err) || (err == nil && info.Size() == 0) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if btree, err := isBTreeFile(fileName); err != nil || btree {
+		return err
+	}
+
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return err
+	}
+	current, _, err := migrateSnapshot(fileName, data)
+	if err != nil {
+		return err
+	}
+	var snapshot Store
+	if err := json.Unmarshal(current, &jsonSnapshot{Store: &snapshot}); err != nil {
+		return fmt.Errorf("reading JSON snapshot: %w", err)
+	}
+
+	// Keep the original until the converted file has replaced it.
+	if err := replaceFile(fileName+".bak", "backup-*.json", data); err != nil {
+		return fmt.Errorf("backing up JSON snapshot: %w", err)
+	}
+
+	tmpf, err := os.CreateTemp(filepath.Dir(fileName), "migrate-*.btree")
+	if err != nil {
+		return err
+	}
+	tmpName := tmpf.Name()
+	tmpf.Close()
+	defer os.Remove(tmpName) // No-op once renamed into place
+
+	tree, err := openBTree(tmpName)
+	if err != nil {
+		return err
+	}
+	changes := make(map[uint64][]byte, len(snapshot.Bookmarks))
+	for id, bookmark := range snapshot.Bookmarks {
+		value, err := json.Marshal(bookmark)
+		if err != nil {
+			tree.close()
+			return err
+		}
+		changes[uint64(id)] = value
+	}
+	if err := tree.commit(changes, uint64(snapshot.IdxCounter), snapshot.Generation); err != nil {
+		tree.close()
+		return err
+	}
+	if err := tree.close(); err != nil {
+		return err
+	}
+
+	return renameReplacing(tmpName, fileName)
+}
@@ -0,0 +1,17 @@
+package store
+
+import (
+	"errors"
+	"os"
+)
+
+// FailNextWALWrite makes the next write to a write-ahead log write only half of
+// its record and fail, as a write running out of disk space does.
+func FailNextWALWrite() {
+	write := writeWAL
+	writeWAL = func(file *os.File, b []byte) (int, error) {
+		writeWAL = write
+		n, _ := file.Write(b[:len(b)/2])
+		return n, errors.New("no space left on device")
+	}
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"slices"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// Sizes of the change feed buffers.
+const (
+	// feedBufferSize is how many recent events are kept for subscribers resuming the feed.
+	feedBufferSize = 1024
+
+	// subscriberBufferSize is how many events a subscriber may fall behind by
+	// before it is dropped and has to resume.
+	subscriberBufferSize = 256
+)
+
+// feed buffers recent change events and fans them out to subscribers.
+// It is not safe for concurrent use; the store's mutex guards it.
+type feed struct {
+	events      []internal.Event // Most recent events, oldest first
+	subscribers map[chan internal.Event]struct{}
+}
+
+func newFeed() *feed {
+	return &feed{subscribers: make(map[chan internal.Event]struct{})}
+}
+
+// publish buffers an event and sends it to every subscriber.
+// A subscriber whose channel is full is dropped by closing its channel.
+func (f *feed) publish(event internal.Event) {
+	f.events = append(f.events, event)
+	if len(f.events) >= 2*feedBufferSize {
+		f.events = slices.Clone(f.events[len(f.events)-feedBufferSize:])
+	}
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- event:
+		default:
+			f.unsubscribe(ch)
+		}
+	}
+}
+
+// since returns the buffered events with a sequence number greater than seq.
+// It reports false if some of those events have already been discarded.
+func (f *feed) since(seq, current uint64) ([]internal.Event, bool) {
+	if seq > current {
+		return nil, false
+	}
+	if seq == current {
+		return nil, true
+	}
+
+	i, _ := slices.BinarySearchFunc(f.events, seq+1, func(e internal.Event, seq uint64) int {
+		return cmpUint64(e.Seq, seq)
+	})
+	if i == len(f.events) || f.events[i].Seq != seq+1 {
+		return nil, false
+	}
+	return f.events[i:], true
+}
+
+func (f *feed) subscribe(backlog []internal.Event) chan internal.Event {
+	ch := make(chan internal.Event, len(backlog)+subscriberBufferSize)
+	for _, event := range backlog {
+		ch <- event
+	}
+	f.subscribers[ch] = struct{}{}
+	return ch
+}
+
+func (f *feed) unsubscribe(ch chan internal.Event) {
+	if _, ok := f.subscribers[ch]; ok {
+		delete(f.subscribers, ch)
+		close(ch)
+	}
+}
+
+// closeAll drops every subscriber.
+func (f *feed) closeAll() {
+	for ch := range f.subscribers {
+		f.unsubscribe(ch)
+	}
+}
+
+func cmpUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// eventFor describes the change made by a write-ahead log record.
+func eventFor(rec walRecord) internal.Event {
+	event := internal.Event{Seq: rec.Generation, ID: rec.ID, Time: time.Now().Unix()}
+	switch rec.Op {
+	case opAdd:
+		event.Type = internal.EventAdd
+	case opUpdate:
+		event.Type = internal.EventUpdate
+	case opDelete:
+		event.Type = internal.EventDelete
+	}
+	if rec.Bookmark != nil {
+		bookmark := *rec.Bookmark
+		event.Bookmark = &bookmark
+	}
+	return event
+}
+
+// Subscribe returns a channel that receives every change made after the change
+// with sequence number since, starting with those that already happened.
+// Sequence numbers are store generations, so passing CurrentGeneration()
+// subscribes to future changes only.
+//
+// The channel is closed when cancel is called, when the store is closed, or
+// when the subscriber falls too far behind; a dropped subscriber can resume
+// by subscribing again from the last event it received.
+// It returns internal.ErrEventsExpired if the changes after since are no longer buffered.
+func (s *Store) Subscribe(since uint64) (<-chan internal.Event, func(), error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	backlog, ok := s.feed.since(since, s.Generation)
+	if !ok {
+		return nil, nil, internal.ErrEventsExpired
+	}
+
+	ch := s.feed.subscribe(backlog)
+	cancel := func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+
+		s.feed.unsubscribe(ch)
+	}
+
+	return ch, cancel, nil
+}
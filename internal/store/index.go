@@ -0,0 +1,313 @@
+package store
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// Fields indexed for search, in the order they are tokenized.
+const (
+	fieldName = iota
+	fieldTags
+	fieldDescription
+	fieldURL
+	numFields
+)
+
+// fieldWeights controls how much a match in each field contributes to the score.
+var fieldWeights = [numFields]float64{
+	fieldName:        3,
+	fieldTags:        2,
+	fieldDescription: 1,
+	fieldURL:         1,
+}
+
+// indexedDoc is the tokenized form of a bookmark kept for phrase matching and removal.
+type indexedDoc struct {
+	fields [numFields][]string
+	tags   []string // Lowercased tags, matched exactly by tag: terms
+}
+
+// index is an in-process inverted index over bookmark names, descriptions, URLs, and tags.
+// It is not safe for concurrent use; the store's mutex guards it.
+type index struct {
+	// postings maps a term to the weighted number of occurrences in each bookmark.
+	postings map[string]map[int]float64
+	docs     map[int]indexedDoc
+}
+
+func newIndex() *index {
+	return &index{
+		postings: make(map[string]map[int]float64),
+		docs:     make(map[int]indexedDoc),
+	}
+}
+
+// tokenize lowercases text and splits it into runs of letters and digits.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// put indexes a bookmark, replacing any previous version with the same ID.
+func (idx *index) put(id int, bookmark internal.Bookmark) {
+	idx.remove(id)
+
+	var doc indexedDoc
+	doc.fields[fieldName] = tokenize(bookmark.Name)
+	doc.fields[fieldTags] = tokenize(strings.Join(bookmark.Tags, " "))
+	doc.fields[fieldDescription] = tokenize(bookmark.Description)
+	doc.fields[fieldURL] = tokenize(bookmark.Url)
+	for _, tag := range bookmark.Tags {
+		doc.tags = append(doc.tags, strings.ToLower(strings.TrimSpace(tag)))
+	}
+
+	for field, tokens := range doc.fields {
+		for _, token := range tokens {
+			postings, ok := idx.postings[token]
+			if !ok {
+				postings = make(map[int]float64)
+				idx.postings[token] = postings
+			}
+			postings[id] += fieldWeights[field]
+		}
+	}
+
+	idx.docs[id] = doc
+}
+
+// remove drops a bookmark from the index.
+func (idx *index) remove(id int) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, tokens := range doc.fields {
+		for _, token := range tokens {
+			if postings, ok := idx.postings[token]; ok {
+				delete(postings, id)
+				if len(postings) == 0 {
+					delete(idx.postings, token)
+				}
+			}
+		}
+	}
+
+	delete(idx.docs, id)
+}
+
+// searchQuery is a parsed search query.
+type searchQuery struct {
+	terms    []string   // Every term must match
+	phrases  [][]string // Every phrase must appear as consecutive tokens in one field
+	tags     []string   // Every tag must be present
+	excludes []string   // No excluded term may match
+	exPhrase [][]string // No excluded phrase may match
+	exTags   []string   // No excluded tag may be present
+}
+
+// parseSearchQuery splits a query into terms, "quoted phrases", tag:name filters,
+// and -excluded versions of each. An unterminated quote runs to the end of the query.
+func parseSearchQuery(q string) searchQuery {
+	var query searchQuery
+
+	for q = strings.TrimSpace(q); q != ""; q = strings.TrimSpace(q) {
+		exclude := false
+		if q[0] == '-' {
+			exclude = true
+			q = q[1:]
+		}
+
+		// Quoted phrase
+		if strings.HasPrefix(q, `"`) {
+			phrase, rest, _ := strings.Cut(q[1:], `"`)
+			q = rest
+			if tokens := tokenize(phrase); len(tokens) > 0 {
+				if exclude {
+					query.exPhrase = append(query.exPhrase, tokens)
+				} else {
+					query.phrases = append(query.phrases, tokens)
+				}
+			}
+			continue
+		}
+
+		word, rest, _ := strings.Cut(q, " ")
+		q = rest
+
+		// Tag filter
+		if name, ok := strings.CutPrefix(strings.ToLower(word), "tag:"); ok {
+			if name != "" {
+				if exclude {
+					query.exTags = append(query.exTags, name)
+				} else {
+					query.tags = append(query.tags, name)
+				}
+			}
+			continue
+		}
+
+		// Plain words may tokenize into several terms, e.g. "go.dev"
+		tokens := tokenize(word)
+		if exclude {
+			query.excludes = append(query.excludes, tokens...)
+		} else if len(tokens) > 1 {
+			query.phrases = append(query.phrases, tokens)
+		} else {
+			query.terms = append(query.terms, tokens...)
+		}
+	}
+
+	return query
+}
+
+// empty reports whether the query has no positive criteria.
+func (q searchQuery) empty() bool {
+	return len(q.terms) == 0 && len(q.phrases) == 0 && len(q.tags) == 0
+}
+
+// containsPhrase reports whether tokens contains phrase as a consecutive run.
+func containsPhrase(tokens, phrase []string) bool {
+	if len(phrase) == 0 || len(phrase) > len(tokens) {
+		return false
+	}
+	for i := 0; i+len(phrase) <= len(tokens); i++ {
+		if slices.Equal(tokens[i:i+len(phrase)], phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// phraseWeight returns the highest field weight among fields containing the phrase,
+// or zero if no field contains it.
+func (doc indexedDoc) phraseWeight(phrase []string) float64 {
+	var weight float64
+	for field, tokens := range doc.fields {
+		if containsPhrase(tokens, phrase) {
+			weight = max(weight, fieldWeights[field])
+		}
+	}
+	return weight
+}
+
+// search returns the IDs of bookmarks matching the query, best match first.
+// Scores sum the field weight of each matched term scaled by its inverse document frequency,
+// plus a bonus for each phrase based on the best field it appears in.
+func (idx *index) search(query searchQuery) []scoredID {
+	if query.empty() {
+		return nil
+	}
+
+	total := float64(len(idx.docs))
+	idf := func(term string) float64 {
+		return math.Log(1 + total/float64(len(idx.postings[term])))
+	}
+
+	// Start from the rarest required term to keep the candidate set small.
+	var candidates map[int]float64
+	seeded := false
+	narrow := func(postings map[int]float64) {
+		if !seeded || len(postings) < len(candidates) {
+			candidates = postings
+			seeded = true
+		}
+	}
+	for _, term := range query.terms {
+		narrow(idx.postings[term])
+	}
+	for _, phrase := range query.phrases {
+		narrow(idx.postings[phrase[0]])
+	}
+
+	var ids []int
+	if !seeded {
+		// Only tag filters: every document is a candidate.
+		for id := range idx.docs {
+			ids = append(ids, id)
+		}
+	} else {
+		for id := range candidates {
+			ids = append(ids, id)
+		}
+	}
+
+	var results []scoredID
+	for _, id := range ids {
+		doc := idx.docs[id]
+		score, ok := idx.score(id, doc, query, idf)
+		if ok {
+			results = append(results, scoredID{id: id, score: score})
+		}
+	}
+
+	slices.SortFunc(results, func(a, b scoredID) int {
+		if a.score != b.score {
+			if a.score > b.score {
+				return -1
+			}
+			return 1
+		}
+		return a.id - b.id
+	})
+
+	return results
+}
+
+// score checks a document against every criterion of the query and computes its score.
+func (idx *index) score(id int, doc indexedDoc, query searchQuery, idf func(string) float64) (float64, bool) {
+	var score float64
+
+	for _, term := range query.terms {
+		weight, ok := idx.postings[term][id]
+		if !ok {
+			return 0, false
+		}
+		score += weight * idf(term)
+	}
+
+	for _, phrase := range query.phrases {
+		weight := doc.phraseWeight(phrase)
+		if weight == 0 {
+			return 0, false
+		}
+		score += weight * float64(len(phrase))
+	}
+
+	for _, tag := range query.tags {
+		if !slices.Contains(doc.tags, tag) {
+			return 0, false
+		}
+		score += fieldWeights[fieldTags]
+	}
+
+	for _, term := range query.excludes {
+		if _, ok := idx.postings[term][id]; ok {
+			return 0, false
+		}
+	}
+	for _, phrase := range query.exPhrase {
+		if doc.phraseWeight(phrase) > 0 {
+			return 0, false
+		}
+	}
+	for _, tag := range query.exTags {
+		if slices.Contains(doc.tags, tag) {
+			return 0, false
+		}
+	}
+
+	return score, true
+}
+
+// scoredID is a search hit before it is joined with its bookmark.
+type scoredID struct {
+	id    int
+	score float64
+}
@@ -0,0 +1,177 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// lockFileName returns the path of the lock file for a snapshot file.
+func lockFileName(snapshotFileName string) string {
+	return snapshotFileName + ".lock"
+}
+
+// LockError is returned when opening a store whose file another process has
+// open. It matches internal.ErrStoreLocked with errors.Is.
+type LockError struct {
+	FileName string // Snapshot file of the store
+	PID      int    // Process holding the lock, 0 if unknown
+	Host     string // Host the process runs on, empty if unknown
+}
+
+// Error implements the error interface.
+func (e *LockError) Error() string {
+	switch {
+	case e.PID == 0:
+		return fmt.Sprintf("store file %s is locked by another process", e.FileName)
+	case e.Host == "":
+		return fmt.Sprintf("store file %s is locked by process %d", e.FileName, e.PID)
+	default:
+		return fmt.Sprintf("store file %s is locked by process %d on host %s", e.FileName, e.PID, e.Host)
+	}
+}
+
+// Unwrap returns internal.ErrStoreLocked.
+func (e *LockError) Unwrap() error {
+	return internal.ErrStoreLocked
+}
+
+// fileLock is an exclusive advisory lock held on a store's lock file for as
+// long as the store is open, so a second process, such as another server or a
+// CLI in local mode, cannot write to the same files.
+// The lock file holds the PID and hostname of the process holding the lock, and
+// is left in place when the lock is released.
+type fileLock struct {
+	file *os.File
+}
+
+// acquireLock takes the lock of the snapshot file at fileName without waiting.
+// It returns a *LockError if another process holds it.
+func acquireLock(fileName string) (*fileLock, error) {
+	file, err := os.OpenFile(lockFileName(fileName), os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		if errors.Is(err, internal.ErrStoreLocked) {
+			pid, host, _ := readLockHolder(fileName)
+			return nil, &LockError{FileName: fileName, PID: pid, Host: host}
+		}
+		return nil, fmt.Errorf("locking %s: %w", fileName, err)
+	}
+
+	// Record the holder for the error seen by other processes, and for
+	// ForceUnlock to check it is alive
+	if err := writeLockHolder(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("recording holder of %s: %w", lockFileName(fileName), err)
+	}
+
+	return &fileLock{file: file}, nil
+}
+
+// waitLock takes the lock of the file at fileName, waiting for another process
+// holding it to release it. It guards short updates, such as saving the
+// accounts file, so unlike acquireLock it does not record the holder.
+func waitLock(fileName string) (*fileLock, error) {
+	file, err := os.OpenFile(lockFileName(fileName), os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, fmt.Errorf("opening lock file: %w", err)
+	}
+
+	if err := lockFileWait(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("locking %s: %w", fileName, err)
+	}
+
+	return &fileLock{file: file}, nil
+}
+
+// release gives up the lock.
+func (l *fileLock) release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	err := l.file.Close() // Closing the file releases the lock
+	l.file = nil
+	return err
+}
+
+// writeLockHolder replaces the contents of the locked file with the PID of
+// this process and the name of its host, one per line, and syncs it.
+func writeLockHolder(file *os.File) error {
+	host, _ := os.Hostname() // Left empty if unknown, so ForceUnlock refuses the lock
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"+host+"\n"), 0); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// readLockHolder returns the PID and hostname recorded in the lock file of the
+// snapshot file at fileName. The PID is 0 if there is none, and the hostname
+// empty, as in lock files written before it was recorded.
+func readLockHolder(fileName string) (int, string, error) {
+	data, err := os.ReadFile(lockFileName(fileName))
+	if err != nil {
+		return 0, "", err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, "", nil
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, "", nil
+	}
+	if len(fields) < 2 {
+		return pid, "", nil
+	}
+	return pid, fields[1], nil
+}
+
+// onThisHost reports whether host names the host this process runs on.
+func onThisHost(host string) bool {
+	name, err := os.Hostname()
+	return err == nil && host != "" && host == name
+}
+
+// ForceUnlock breaks the lock on the snapshot file at fileName by removing its
+// lock file, so the next NewStore succeeds. It returns the lock that was
+// broken, or nil if the file was not locked.
+// The operating system releases a lock when its process exits, so a lock is
+// only broken when the process it names no longer exists, as happens when a
+// network file system loses track of a lock. A lock held by a live process,
+// or one whose holder is unknown, is refused with its *LockError: removing
+// it would let two processes write the same store.
+//
+// Whether the process exists can only be checked on this host, so a lock
+// taken on another host, or with no host recorded, is refused too. Processes
+// in containers that share a hostname but not a PID namespace cannot be told
+// apart; give such containers hostnames of their own.
+func ForceUnlock(fileName string) (*LockError, error) {
+	lock, err := acquireLock(fileName)
+	if err == nil {
+		return nil, lock.release()
+	}
+
+	var held *LockError
+	if !errors.As(err, &held) {
+		return nil, err
+	}
+	if held.PID == 0 || !onThisHost(held.Host) || processAlive(held.PID) {
+		return nil, held
+	}
+	if err := os.Remove(lockFileName(fileName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return held, nil
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+package store
+
+import "os"
+
+// lockFile does nothing on platforms without flock; stores are not protected
+// from being opened by two processes there.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// lockFileWait does nothing on platforms without flock.
+func lockFileWait(file *os.File) error {
+	return nil
+}
+
+// processAlive assumes the process pid exists, since there is no portable way
+// to check.
+func processAlive(pid int) bool {
+	return true
+}
@@ -0,0 +1,37 @@
+//go:build unix
+
+package store
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// lockFile takes an exclusive flock on file, failing at once if it is held.
+func lockFile(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return internal.ErrStoreLocked
+	}
+	return err
+}
+
+// lockFileWait takes an exclusive flock on file, waiting until it is free.
+func lockFileWait(file *os.File) error {
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if !errors.Is(err, syscall.EINTR) {
+			return err
+		}
+	}
+}
+
+// processAlive reports whether the process pid exists. A process owned by
+// another user counts as alive.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,242 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Snapshot schema. A JSON snapshot starts with a header naming its format and
+// the version of the schema it was written in. Snapshots written before the
+// header was introduced have neither, and are schema version 0.
+const (
+	snapshotFormat = "fave-store"
+
+	// SchemaVersion is the schema version of the snapshots written by this build.
+	// Older snapshots are migrated to it when a store is opened.
+	SchemaVersion = 1
+)
+
+// snapshotHeader identifies a JSON snapshot and the schema version of its contents.
+type snapshotHeader struct {
+	Format        string `json:"format"`
+	SchemaVersion int    `json:"schema_version"`
+}
+
+// jsonSnapshot is the layout of a JSON snapshot: the header, then the store.
+type jsonSnapshot struct {
+	snapshotHeader
+	*Store
+}
+
+// snapshotDoc is a JSON snapshot decoded without the types of this build,
+// which may no longer match the layout of an old snapshot. Numbers are kept
+// as json.Number so they are written back exactly.
+type snapshotDoc map[string]any
+
+// migration upgrades a snapshot document to version from the version before it.
+type migration struct {
+	version     int
+	description string
+
+	// migrate changes doc in place and returns a summary of what it changed.
+	migrate func(doc snapshotDoc) (string, error)
+}
+
+// migrations upgrade snapshots to SchemaVersion, one version at a time and in
+// order. To change the schema, increment SchemaVersion and add a migration
+// producing the new version from the previous one.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "start bookmarks saved before revisions were tracked at revision 1",
+		migrate:     migrateRevisions,
+	},
+}
+
+// MigrationReport describes the migrations a snapshot needs, or has been through.
+type MigrationReport struct {
+	FileName    string
+	FromVersion int
+	ToVersion   int
+	Steps       []MigrationStep
+	BackupFile  string // Copy of the snapshot before it was migrated; empty in a dry run
+}
+
+// MigrationStep is one migration applied to a snapshot.
+type MigrationStep struct {
+	Version     int    // Schema version the migration produces
+	Description string // What the migration does to any snapshot
+	Changes     string // What it changed in this one
+}
+
+// Migrated reports whether the snapshot needs, or went through, any migration.
+func (r *MigrationReport) Migrated() bool {
+	return len(r.Steps) > 0
+}
+
+// MigrateFile upgrades the snapshot at fileName to SchemaVersion, as NewStore
+// does when it opens a store, and reports what changed. The original is kept
+// next to it as `fileName`.v<version>.bak. With dryRun set, the file is only
+// read, and the report describes what migrating it would do.
+// B-tree files, and missing or empty files, need no migration.
+func MigrateFile(fileName string, dryRun bool) (*MigrationReport, error) {
+	report := &MigrationReport{FileName: fileName, FromVersion: SchemaVersion, ToVersion: SchemaVersion}
+
+	if btree, err := isBTreeFile(fileName); err != nil || btree {
+		return report, err
+	}
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(data) == 0) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		_, report, err := migrateSnapshot(fileName, data)
+		return report, err
+	}
+
+	lock, err := acquireLock(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.release()
+
+	// Read it again, now that no store can be writing it
+	data, err = os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	_, report, err = upgradeSnapshot(fileName, data)
+	return report, err
+}
+
+// upgradeSnapshot migrates the snapshot data read from fileName, if it needs
+// it, and replaces the file with the result, first backing up the original.
+// It returns the snapshot as it is now. The caller must hold the lock.
+func upgradeSnapshot(fileName string, data []byte) ([]byte, *MigrationReport, error) {
+	migrated, report, err := migrateSnapshot(fileName, data)
+	if err != nil || !report.Migrated() {
+		return migrated, report, err
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", fileName, report.FromVersion)
+	if err := replaceFile(backup, "backup-*.json", data); err != nil {
+		return nil, nil, fmt.Errorf("backing up %s: %w", fileName, err)
+	}
+	if err := replaceFile(fileName, "snapshot-*.json", migrated); err != nil {
+		return nil, nil, fmt.Errorf("writing migrated %s: %w", fileName, err)
+	}
+	report.BackupFile = backup
+
+	return migrated, report, nil
+}
+
+// migrateSnapshot runs the migrations that the snapshot data needs and returns
+// the migrated snapshot along with a report. Data that needs no migration is
+// returned as is.
+func migrateSnapshot(fileName string, data []byte) ([]byte, *MigrationReport, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc snapshotDoc
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("reading snapshot %s: %w", fileName, err)
+	}
+
+	version, err := doc.header()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading snapshot %s: %w", fileName, err)
+	}
+	if version > SchemaVersion {
+		return nil, nil, fmt.Errorf("snapshot %s has schema version %d, but this version of fave only reads up to %d; upgrade fave", fileName, version, SchemaVersion)
+	}
+
+	report := &MigrationReport{FileName: fileName, FromVersion: version, ToVersion: SchemaVersion}
+	if version == SchemaVersion {
+		return data, report, nil
+	}
+
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		changes, err := m.migrate(doc)
+		if err != nil {
+			return nil, nil, fmt.Errorf("migrating snapshot %s to schema version %d: %w", fileName, m.version, err)
+		}
+		report.Steps = append(report.Steps, MigrationStep{Version: m.version, Description: m.description, Changes: changes})
+	}
+
+	doc["format"] = snapshotFormat
+	doc["schema_version"] = SchemaVersion
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return migrated, report, nil
+}
+
+// header returns the schema version of the snapshot, checking its format.
+func (doc snapshotDoc) header() (int, error) {
+	if format, ok := doc["format"]; ok && format != snapshotFormat {
+		return 0, fmt.Errorf("unknown snapshot format %v", format)
+	}
+
+	raw, ok := doc["schema_version"]
+	if !ok {
+		return 0, nil
+	}
+	number, ok := raw.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid schema version %v", raw)
+	}
+	version, err := number.Int64()
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid schema version %v", raw)
+	}
+	return int(version), nil
+}
+
+// bookmarks returns the bookmarks of the snapshot, keyed by ID.
+func (doc snapshotDoc) bookmarks() (map[string]any, error) {
+	raw, ok := doc["bookmarks"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	bookmarks, ok := raw.(map[string]any)
+	if !ok {
+		return nil, errors.New("bookmarks is not an object")
+	}
+	return bookmarks, nil
+}
+
+// migrateRevisions produces schema version 1. Bookmarks saved before
+// revisions were tracked have none, which reads as revision 0, while new
+// bookmarks start at 1; give them revision 1.
+func migrateRevisions(doc snapshotDoc) (string, error) {
+	bookmarks, err := doc.bookmarks()
+	if err != nil {
+		return "", err
+	}
+
+	changed := 0
+	for id, raw := range bookmarks {
+		bookmark, ok := raw.(map[string]any)
+		if !ok {
+			return "", fmt.Errorf("bookmark %s is not an object", id)
+		}
+		if revision, ok := bookmark["revision"].(json.Number); ok && revision.String() != "0" {
+			continue
+		}
+		bookmark["revision"] = 1
+		changed++
+	}
+
+	return fmt.Sprintf("set revision 1 on %d of %d bookmarks", changed, len(bookmarks)), nil
+}
@@ -1,11 +1,12 @@
 package store
 
 import (
-	"encoding/json"
 	"errors"
+	"fmt"
 	"maps"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sync"
 	"time"
 
@@ -13,54 +14,118 @@ import (
 )
 
 // Store contains an in-memory store of all bookmarks.
-// It holds a pointer to a storage file for persistence.
+// It holds a pointer to a storage file for persistence, in the format of its
+// storage engine.
+// Every mutation is appended to a write-ahead log next to the storage file
+// before it is applied, so acknowledged writes survive a crash between snapshots.
+//
+// Each mutation also increments Generation. Snapshots record the generation
+// they persisted, so saving a snapshot when nothing has changed is a no-op.
+// Mutations are published as events, numbered by generation, to subscribers
+// of the change feed.
 type Store struct {
 	Bookmarks  map[int]internal.Bookmark `json:"bookmarks"`
 	IdxCounter int                       `json:"idx_counter"`
+	Generation uint64                    `json:"generation"`
 
-	fileName string
-	file     *os.File
+	engine engine
+	wal    *wal
+	lock   *fileLock
+	index  *index
+	feed   *feed
+	dirty  map[int]struct{} // IDs of bookmarks changed since the last snapshot
+
+	persistedGeneration uint64
+	persistedAt         time.Time
+	modifiedAt          time.Time
 
 	mutex sync.RWMutex
 }
 
-// NewStore initializes a new store with the file at `fileName` as the backing file.
-// If the file does not exist, it will be created.
+// NewStore initializes a new store with the file at `fileName` as the backing file,
+// kept as JSON. If the file does not exist, it will be created.
 // If the file exists and contains data, it will be read and loaded into the store.
+// Any mutations left in the write-ahead log are then replayed on top of it.
+// Until it is closed, the store holds a lock on `fileName`.lock, and opening the
+// same file again, in this process or another, fails with internal.ErrStoreLocked.
 func NewStore(fileName string) (*Store, error) {
-	// Open the file for persistence.
-	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_RDWR, 0666)
+	return NewStoreWithEngine(fileName, EngineJSON)
+}
+
+// NewStoreWithEngine is like NewStore, but keeps the backing file in the format
+// of the given storage engine, EngineJSON or EngineBTree. Opening a JSON file
+// with EngineBTree converts it, keeping the original as `fileName`.bak.
+func NewStoreWithEngine(fileName, engineName string) (*Store, error) {
+	lock, err := acquireLock(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := openStore(fileName, engineName)
 	if err != nil {
+		lock.release()
 		return nil, err
 	}
-	defer file.Close() // Close after reading initial data
+	store.lock = lock
 
+	return store, nil
+}
+
+// openStore loads the store kept in fileName, whose lock the caller holds.
+func openStore(fileName, engineName string) (*Store, error) {
 	store := &Store{
 		Bookmarks:  make(map[int]internal.Bookmark),
 		IdxCounter: 0,
-		fileName:   fileName,
-		file:       nil, // No longer keep file handle open
+		wal:        &wal{fileName: walFileName(fileName)},
+		index:      newIndex(),
+		feed:       newFeed(),
+		dirty:      make(map[int]struct{}),
 		mutex:      sync.RWMutex{},
 	}
 
-	// Check if file has content.
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return nil, err
-	}
-
-	// If file has content, read and unmarshal it.
-	if fileInfo.Size() > 0 {
-		decoder := json.NewDecoder(file)
-		err = decoder.Decode(store)
+	switch engineName {
+	case EngineJSON:
+		store.engine = &jsonEngine{fileName: fileName}
+	case EngineBTree:
+		engine, err := openBTreeEngine(fileName)
 		if err != nil {
 			return nil, err
 		}
+		store.engine = engine
+	default:
+		return nil, fmt.Errorf("unknown storage engine %q", engineName)
+	}
+
+	if err := store.engine.load(store); err != nil {
+		store.engine.close()
+		return nil, err
+	}
+	store.persistedGeneration = store.Generation
+
+	for id, bookmark := range store.Bookmarks {
+		store.index.put(id, bookmark)
+	}
+
+	// Replay mutations acknowledged after the last snapshot.
+	if err := store.wal.replay(store.apply); err != nil {
+		store.engine.close()
+		return nil, err
 	}
 
 	return store, nil
 }
 
+// Close releases the write-ahead log and the lock on the storage file, and ends
+// every change feed subscription.
+// It does not save a snapshot; call SaveSnapshot first to fold the log into the storage file.
+func (s *Store) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.feed.closeAll()
+	return errors.Join(s.wal.close(), s.engine.close(), s.lock.release())
+}
+
 // Get retrieves a bookmark from the in-memory store.
 // If the bookmark cannot be found, it returns an error.
 func (s *Store) Get(id int) (internal.Bookmark, error) {
@@ -69,7 +134,7 @@ func (s *Store) Get(id int) (internal.Bookmark, error) {
 
 	bookmark, exists := s.Bookmarks[id]
 	if !exists {
-		return internal.Bookmark{}, errors.New("bookmark not found")
+		return internal.Bookmark{}, internal.ErrNotFound
 	}
 
 	return bookmark, nil
@@ -84,84 +149,270 @@ func (s *Store) List() map[int]internal.Bookmark {
 	return maps.Clone(s.Bookmarks)
 }
 
+// Count returns the number of bookmarks in the store, without copying them.
+func (s *Store) Count() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return len(s.Bookmarks)
+}
+
+// Query returns one page of bookmarks, filtered, sorted, and paginated according to opts.
+// It returns internal.ErrInvalidCursor if the cursor in opts cannot be used.
+func (s *Store) Query(opts internal.ListOptions) (internal.ListPage, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return internal.Paginate(s.Bookmarks, opts)
+}
+
+// Search returns the bookmarks matching a full-text query, best match first.
+// The query may contain plain terms, "quoted phrases", tag:name filters, and
+// -excluded terms, phrases, or tags; every positive criterion must match.
+// At most limit results are returned; a limit of 0 means no limit.
+// It returns internal.ErrEmptyQuery if the query has no positive criteria.
+func (s *Store) Search(query string, limit int) ([]internal.SearchResult, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	parsed := parseSearchQuery(query)
+	if parsed.empty() {
+		return nil, internal.ErrEmptyQuery
+	}
+
+	hits := s.index.search(parsed)
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+
+	results := make([]internal.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		results = append(results, internal.SearchResult{
+			BookmarkEntry: internal.BookmarkEntry{ID: hit.id, Bookmark: s.Bookmarks[hit.id]},
+			Score:         hit.score,
+		})
+	}
+
+	return results, nil
+}
+
 // Add inserts a new bookmark.
 // This bookmark will be given a unique ID by incrementing a counter on the store.
-// The ID of the bookmark is returned.
-func (s *Store) Add(bookmark internal.Bookmark) int {
+// The ID of the bookmark is returned once the addition is durable in the write-ahead log.
+func (s *Store) Add(bookmark internal.Bookmark) (int, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	s.IdxCounter++
-	s.Bookmarks[s.IdxCounter] = bookmark
+	bookmark.Revision = 1
+
+	rec := walRecord{Op: opAdd, ID: s.IdxCounter + 1, Generation: s.Generation + 1, Bookmark: &bookmark}
+	if err := s.wal.append(rec); err != nil {
+		return 0, err
+	}
+	s.apply(rec)
 
-	return s.IdxCounter
+	return rec.ID, nil
 }
 
 // Update swaps the bookmark at the given ID with the bookmark passed in.
 // If no bookmark is found with the given ID, an error is returned.
-// The update is not persisted until the next snapshot is saved.
+// The update is durable in the write-ahead log when Update returns.
 func (s *Store) Update(id int, bookmark internal.Bookmark) error {
+	return s.update(id, bookmark, nil)
+}
+
+// UpdateIfRevision is like Update, but only replaces the bookmark if it is still at the given revision.
+// Otherwise it returns internal.ErrRevisionMismatch.
+func (s *Store) UpdateIfRevision(id int, bookmark internal.Bookmark, revision uint64) error {
+	return s.update(id, bookmark, &revision)
+}
+
+func (s *Store) update(id int, bookmark internal.Bookmark, revision *uint64) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	_, exists := s.Bookmarks[id]
+	existing, exists := s.Bookmarks[id]
 	if !exists {
-		return errors.New("bookmark not found")
+		return internal.ErrNotFound
+	}
+	if revision != nil && existing.Revision != *revision {
+		return internal.ErrRevisionMismatch
 	}
 
-	s.Bookmarks[id] = bookmark
+	bookmark.Revision = existing.Revision + 1
+
+	rec := walRecord{Op: opUpdate, ID: id, Generation: s.Generation + 1, Bookmark: &bookmark}
+	if err := s.wal.append(rec); err != nil {
+		return err
+	}
+	s.apply(rec)
+
 	return nil
 }
 
 // Delete removes the bookmark at the given ID from the in-memory bookmarks.
-// The deletion is not persisted until the next snapshot is saved.
+// The deletion is durable in the write-ahead log when Delete returns.
 func (s *Store) Delete(id int) error {
+	return s.delete(id, nil)
+}
+
+// DeleteIfRevision is like Delete, but only removes the bookmark if it is still at the given revision.
+// Otherwise it returns internal.ErrRevisionMismatch.
+func (s *Store) DeleteIfRevision(id int, revision uint64) error {
+	return s.delete(id, &revision)
+}
+
+func (s *Store) delete(id int, revision *uint64) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	_, exists := s.Bookmarks[id]
+	existing, exists := s.Bookmarks[id]
 	if !exists {
-		return errors.New("bookmark not found")
+		return internal.ErrNotFound
 	}
+	if revision != nil && existing.Revision != *revision {
+		return internal.ErrRevisionMismatch
+	}
+
+	rec := walRecord{Op: opDelete, ID: id, Generation: s.Generation + 1}
+	if err := s.wal.append(rec); err != nil {
+		return err
+	}
+	s.apply(rec)
 
-	delete(s.Bookmarks, id)
 	return nil
 }
 
-// SaveSnapshot atomically saves the in-memory store to disk.
-// On Unix-like systems, this is fully atomic. On Windows, there's a small
-// window between removing the old file and renaming the temp file where the
-// file doesn't exist, but this is necessary for cross-platform compatibility.
+// apply mutates the in-memory state according to a write-ahead log record.
+// Applying a record is idempotent, so replaying records that already made it
+// into the snapshot (a crash between saving it and truncating the log) is safe.
+// Records that are not already contained in the state are published to the change feed.
+// The caller must hold the write lock.
+func (s *Store) apply(rec walRecord) {
+	if rec.Op != opBatch && rec.Generation > s.Generation {
+		event := eventFor(rec)
+		if existing, ok := s.Bookmarks[rec.ID]; ok && rec.Op == opDelete {
+			event.Bookmark = &existing
+		}
+		s.feed.publish(event)
+	}
+
+	switch rec.Op {
+	case opAdd, opUpdate:
+		if rec.Bookmark != nil {
+			s.Bookmarks[rec.ID] = *rec.Bookmark
+			s.index.put(rec.ID, *rec.Bookmark)
+			s.dirty[rec.ID] = struct{}{}
+		}
+	case opDelete:
+		delete(s.Bookmarks, rec.ID)
+		s.index.remove(rec.ID)
+		s.dirty[rec.ID] = struct{}{}
+	case opBatch:
+		for _, r := range rec.Records {
+			s.apply(r)
+		}
+	}
+
+	if rec.ID > s.IdxCounter {
+		s.IdxCounter = rec.ID
+	}
+	if rec.Generation > s.Generation {
+		s.Generation = rec.Generation
+	}
+	s.modifiedAt = time.Now()
+}
+
+// CurrentGeneration returns the generation of the in-memory state.
+// It increases by one with every successful mutation.
+func (s *Store) CurrentGeneration() uint64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.Generation
+}
+
+// LastModified returns when the in-memory state last changed.
+// For a freshly loaded store it is the time the storage file was written,
+// or the load time if mutations were replayed from the write-ahead log.
+func (s *Store) LastModified() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.modifiedAt
+}
+
+// Persisted returns the generation contained in the storage file and when it was written.
+// The time is zero if no snapshot has been written yet.
+func (s *Store) Persisted() (uint64, time.Time) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.persistedGeneration, s.persistedAt
+}
+
+// SaveSnapshot atomically saves the in-memory store to disk and then truncates
+// the write-ahead log, whose records are now contained in the snapshot.
+// If nothing has changed since the last snapshot, SaveSnapshot does nothing.
 func (s *Store) SaveSnapshot() error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	b, err := json.Marshal(s)
-	if err != nil {
+	if s.Generation == s.persistedGeneration {
+		return nil
+	}
+
+	if err := s.engine.save(s); err != nil {
 		return err
 	}
 
-	tmpf, err := os.CreateTemp(filepath.Dir(s.fileName), "snapshot-*.json")
+	clear(s.dirty)
+	s.persistedGeneration = s.Generation
+	s.persistedAt = time.Now()
+
+	return s.wal.truncate()
+}
+
+// replaceFile atomically replaces the contents of fileName with data by writing
+// a temporary file, named after pattern as in os.CreateTemp, next to it and
+// renaming it into place.
+// On Unix-like systems, this is fully atomic. On Windows, there's a small
+// window between removing the old file and renaming the temp file where the
+// file doesn't exist, but this is necessary for cross-platform compatibility.
+func replaceFile(fileName, pattern string, data []byte) error {
+	tmpf, err := os.CreateTemp(filepath.Dir(fileName), pattern)
 	if err != nil {
 		return err
 	}
 	defer tmpf.Close()
 
-	if _, err := tmpf.Write(b); err != nil {
+	if _, err := tmpf.Write(data); err != nil {
+		return err
+	}
+	// The data must be on stable storage before the caller relies on it.
+	if err := tmpf.Sync(); err != nil {
 		return err
 	}
 	if err := tmpf.Close(); err != nil {
 		return err
 	}
 
-	// On Windows, os.Rename fails if target exists, so remove it first
-	// This sacrifices some atomicity on Windows, but maintains compatibility
-	if _, err := os.Stat(s.fileName); err == nil {
+	return renameReplacing(tmpf.Name(), fileName)
+}
+
+// renameReplacing renames the file at from to fileName, replacing any file
+// there, and syncs the directory holding it.
+func renameReplacing(from, fileName string) error {
+	// On Windows, os.Rename may fail if the target is still open, so remove it
+	// first. This sacrifices atomicity, so it is only done there: elsewhere
+	// the rename replaces the target atomically, and removing it first would
+	// leave nothing on disk if the process crashed in between.
+	if _, err := os.Stat(fileName); err == nil && runtime.GOOS == "windows" {
 		// On Windows, file handles may not be immediately released after close
 		// Retry removal a few times with exponential backoff
 		var removeErr error
 		for i := 0; i < 5; i++ {
-			removeErr = os.Remove(s.fileName)
+			removeErr = os.Remove(fileName)
 			if removeErr == nil {
 				break
 			}
@@ -175,5 +426,10 @@ func (s *Store) SaveSnapshot() error {
 		}
 	}
 
-	return os.Rename(tmpf.Name(), s.fileName)
+	if err := os.Rename(from, fileName); err != nil {
+		return err
+	}
+	// The rename must be on stable storage before the caller relies on it,
+	// for example by truncating the write-ahead log.
+	return syncDir(filepath.Dir(fileName))
 }
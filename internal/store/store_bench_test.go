@@ -2,6 +2,7 @@ package store_test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/t-eckert/fave/internal/store"
@@ -39,7 +40,7 @@ func BenchmarkDelete_WithoutSnapshot(b *testing.B) {
 	poolSize := 10000
 	ids := make([]int, poolSize)
 	for i := 0; i < poolSize; i++ {
-		ids[i] = s.Add(testBookmark())
+		ids[i] = mustAdd(b, s, testBookmark())
 	}
 
 	b.ResetTimer()
@@ -59,7 +60,7 @@ func BenchmarkDelete_WithSnapshot(b *testing.B) {
 	poolSize := 10000
 	ids := make([]int, poolSize)
 	for i := 0; i < poolSize; i++ {
-		ids[i] = s.Add(testBookmark())
+		ids[i] = mustAdd(b, s, testBookmark())
 	}
 
 	b.ResetTimer()
@@ -76,7 +77,7 @@ func BenchmarkUpdate_CurrentBehavior(b *testing.B) {
 	s, filename := createBenchStore(b)
 	defer os.Remove(filename)
 
-	id := s.Add(testBookmark())
+	id := mustAdd(b, s, testBookmark())
 
 	b.ResetTimer()
 	for b.Loop() {
@@ -116,6 +117,31 @@ func BenchmarkSaveSnapshot_LargeDataset(b *testing.B) {
 	}
 }
 
+// BenchmarkSaveSnapshot_OneChange benchmarks snapshots of a single update to
+// 10000 bookmarks with each storage engine
+func BenchmarkSaveSnapshot_OneChange(b *testing.B) {
+	for _, engine := range []string{store.EngineJSON, store.EngineBTree} {
+		b.Run(engine, func(b *testing.B) {
+			s, err := store.NewStoreWithEngine(filepath.Join(b.TempDir(), "bench-store"), engine)
+			if err != nil {
+				b.Fatalf("Failed to create store: %v", err)
+			}
+			defer s.Close()
+
+			for i := 0; i < 10000; i++ {
+				mustAdd(b, s, testBookmark())
+			}
+			s.SaveSnapshot()
+
+			b.ResetTimer()
+			for b.Loop() {
+				s.Update(5000, testBookmark())
+				s.SaveSnapshot()
+			}
+		})
+	}
+}
+
 // BenchmarkMixedOperations_WithSnapshot simulates realistic workload with snapshotting
 func BenchmarkMixedOperations_WithSnapshot(b *testing.B) {
 	s, filename := createBenchStore(b)
@@ -123,7 +149,7 @@ func BenchmarkMixedOperations_WithSnapshot(b *testing.B) {
 
 	b.ResetTimer()
 	for b.Loop() {
-		id := s.Add(testBookmark())
+		id := mustAdd(b, s, testBookmark())
 		s.SaveSnapshot()
 		s.Update(id, testBookmark())
 		s.SaveSnapshot()
@@ -139,7 +165,7 @@ func BenchmarkMixedOperations_WithoutSnapshot(b *testing.B) {
 
 	b.ResetTimer()
 	for b.Loop() {
-		id := s.Add(testBookmark())
+		id := mustAdd(b, s, testBookmark())
 		s.Update(id, testBookmark())
 		s.Delete(id)
 	}
@@ -160,5 +186,11 @@ func createBenchStore(b *testing.B) (*store.Store, string) {
 		b.Fatalf("Failed to create store: %v", err)
 	}
 
+	b.Cleanup(func() {
+		s.Close()
+		os.Remove(tmpFile.Name() + ".wal")
+		os.Remove(tmpFile.Name() + ".lock")
+	})
+
 	return s, tmpFile.Name()
 }
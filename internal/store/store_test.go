@@ -2,15 +2,21 @@ package store_test
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/t-eckert/fave/internal"
+	"github.com/t-eckert/fave/internal/server"
 	"github.com/t-eckert/fave/internal/store"
+	"github.com/t-eckert/fave/internal/storetest"
 )
 
 // ============================================================================
@@ -34,22 +40,39 @@ func createTempStore(t *testing.T) (*store.Store, string) {
 	}
 
 	t.Cleanup(func() {
+		s.Close()
 		os.Remove(tmpFile.Name())
+		os.Remove(tmpFile.Name() + ".wal")
+		os.Remove(tmpFile.Name() + ".lock")
 	})
 
 	return s, tmpFile.Name()
 }
 
 // reloadStore reloads a store from disk to verify persistence.
-func reloadStore(t *testing.T, filename string) *store.Store {
+// The previous store is closed first, without saving a snapshot, as if its
+// process had exited, so that its lock on the file is released.
+func reloadStore(t *testing.T, previous *store.Store, filename string) *store.Store {
 	t.Helper()
+	previous.Close()
 	s, err := store.NewStore(filename)
 	if err != nil {
 		t.Fatalf("Failed to reload store: %v", err)
 	}
+	t.Cleanup(func() { s.Close() })
 	return s
 }
 
+// mustAdd adds a bookmark to the store and fails the test if it cannot be persisted.
+func mustAdd(t testing.TB, s *store.Store, bookmark internal.Bookmark) int {
+	t.Helper()
+	id, err := s.Add(bookmark)
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	return id
+}
+
 // assertBookmarkEqual checks deep equality of bookmarks.
 func assertBookmarkEqual(t *testing.T, expected, actual internal.Bookmark) {
 	t.Helper()
@@ -103,6 +126,7 @@ func TestNewStore_WithExistingEmptyFile(t *testing.T) {
 	}
 	defer f.Close()
 	defer os.Remove(f.Name())
+	defer os.Remove(f.Name() + ".lock")
 
 	store, err := store.NewStore(f.Name())
 	if err != nil {
@@ -130,6 +154,8 @@ func TestNewStore_WithExistingFileWithData(t *testing.T) {
 	}
 	defer f.Close()
 	defer os.Remove(f.Name())
+	defer os.Remove(f.Name() + ".lock")
+	defer os.Remove(f.Name() + ".v0.bak")
 
 	// Write test data to the file
 	testData := struct {
@@ -208,7 +234,7 @@ func TestGet_ExistingBookmark(t *testing.T) {
 	s, _ := createTempStore(t)
 
 	bookmark := testBookmark()
-	id := s.Add(bookmark)
+	id := mustAdd(t, s, bookmark)
 
 	result, err := s.Get(id)
 	if err != nil {
@@ -230,7 +256,7 @@ func TestGet_NonExistentBookmark(t *testing.T) {
 func TestGet_AfterDelete(t *testing.T) {
 	s, _ := createTempStore(t)
 
-	id := s.Add(testBookmark())
+	id := mustAdd(t, s, testBookmark())
 	err := s.Delete(id)
 	if err != nil {
 		t.Fatalf("Delete failed: %v", err)
@@ -260,9 +286,9 @@ func TestList_EmptyStore(t *testing.T) {
 func TestList_MultipleBookmarks(t *testing.T) {
 	s, _ := createTempStore(t)
 
-	id1 := s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "First" }))
-	id2 := s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "Second" }))
-	id3 := s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "Third" }))
+	id1 := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "First" }))
+	id2 := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Second" }))
+	id3 := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Third" }))
 
 	bookmarks := s.List()
 	if len(bookmarks) != 3 {
@@ -283,7 +309,7 @@ func TestList_MultipleBookmarks(t *testing.T) {
 func TestList_ReturnsCopy(t *testing.T) {
 	s, _ := createTempStore(t)
 
-	id := s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "Original" }))
+	id := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Original" }))
 
 	bookmarks := s.List()
 	// Modify the returned map
@@ -296,13 +322,339 @@ func TestList_ReturnsCopy(t *testing.T) {
 	}
 }
 
+// Query Tests
+
+func TestQuery_DefaultOrderByID(t *testing.T) {
+	s, _ := createTempStore(t)
+
+	for i := 0; i < 5; i++ {
+		mustAdd(t, s, testBookmark())
+	}
+
+	page, err := s.Query(internal.ListOptions{})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	if len(page.Bookmarks) != 5 {
+		t.Fatalf("Expected 5 bookmarks, got %d", len(page.Bookmarks))
+	}
+	for i, entry := range page.Bookmarks {
+		if entry.ID != i+1 {
+			t.Errorf("Expected ID %d at position %d, got %d", i+1, i, entry.ID)
+		}
+	}
+	if page.NextCursor != "" {
+		t.Errorf("Expected no next cursor, got %q", page.NextCursor)
+	}
+}
+
+func TestQuery_SortByNameDesc(t *testing.T) {
+	s, _ := createTempStore(t)
+
+	mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "banana" }))
+	mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Apple" }))
+	mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "cherry" }))
+
+	page, err := s.Query(internal.ListOptions{Sort: internal.SortName, Desc: true})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	var names []string
+	for _, entry := range page.Bookmarks {
+		names = append(names, entry.Name)
+	}
+	if !slicesEqual(names, []string{"cherry", "banana", "Apple"}) {
+		t.Errorf("Expected [cherry banana Apple], got %v", names)
+	}
+}
+
+func TestQuery_Filters(t *testing.T) {
+	s, _ := createTempStore(t)
+
+	mustAdd(t, s, testBookmark(func(b *internal.Bookmark) {
+		b.Url = "https://go.dev/doc"
+		b.Tags = []string{"golang", "docs"}
+		b.CreatedAt = 100
+	}))
+	mustAdd(t, s, testBookmark(func(b *internal.Bookmark) {
+		b.Url = "https://GO.dev:443/blog"
+		b.Tags = []string{"golang"}
+		b.CreatedAt = 200
+	}))
+	mustAdd(t, s, testBookmark(func(b *internal.Bookmark) {
+		b.Url = "https://example.com"
+		b.Tags = []string{"golang", "docs"}
+		b.CreatedAt = 300
+	}))
+
+	tests := []struct {
+		name string
+		opts internal.ListOptions
+		want []int
+	}{
+		{"single tag", internal.ListOptions{Tags: []string{"golang"}}, []int{1, 2, 3}},
+		{"all tags", internal.ListOptions{Tags: []string{"golang", "docs"}}, []int{1, 3}},
+		{"created after", internal.ListOptions{CreatedAfter: 100}, []int{2, 3}},
+		{"created before", internal.ListOptions{CreatedBefore: 300}, []int{1, 2}},
+		{"url host", internal.ListOptions{URLHost: "go.dev"}, []int{1, 2}},
+		{"combined", internal.ListOptions{URLHost: "go.dev", Tags: []string{"docs"}}, []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := s.Query(tt.opts)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+
+			var ids []int
+			for _, entry := range page.Bookmarks {
+				ids = append(ids, entry.ID)
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+				t.Errorf("Expected IDs %v, got %v", tt.want, ids)
+			}
+		})
+	}
+}
+
+func TestQuery_PaginationWithCursor(t *testing.T) {
+	s, _ := createTempStore(t)
+
+	for i := 0; i < 7; i++ {
+		mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.CreatedAt = int64(1000 - i) }))
+	}
+
+	opts := internal.ListOptions{Limit: 3, Sort: internal.SortCreatedAt}
+	var ids []int
+	pages := 0
+	for {
+		page, err := s.Query(opts)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		pages++
+		for _, entry := range page.Bookmarks {
+			ids = append(ids, entry.ID)
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+
+		// Inserting a bookmark that sorts before the cursor must not shift later pages
+		if pages == 1 {
+			mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.CreatedAt = 1 }))
+		}
+	}
+
+	if pages != 3 {
+		t.Errorf("Expected 3 pages, got %d", pages)
+	}
+	if fmt.Sprint(ids) != fmt.Sprint([]int{7, 6, 5, 4, 3, 2, 1}) {
+		t.Errorf("Expected IDs [7 6 5 4 3 2 1], got %v", ids)
+	}
+}
+
+func TestQuery_InvalidCursor(t *testing.T) {
+	s, _ := createTempStore(t)
+
+	mustAdd(t, s, testBookmark())
+	mustAdd(t, s, testBookmark())
+
+	_, err := s.Query(internal.ListOptions{Cursor: "not-a-cursor"})
+	if !errors.Is(err, internal.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+
+	// A cursor issued for one sort order cannot be used with another
+	page, err := s.Query(internal.ListOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	_, err = s.Query(internal.ListOptions{Limit: 1, Cursor: page.NextCursor, Sort: internal.SortName})
+	if !errors.Is(err, internal.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor for mismatched sort, got %v", err)
+	}
+
+	// Nor can a cursor issued for one set of filters be used with another
+	page, err = s.Query(internal.ListOptions{Limit: 1, Tags: []string{"test"}})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	for _, opts := range []internal.ListOptions{
+		{Limit: 1, Cursor: page.NextCursor},
+		{Limit: 1, Cursor: page.NextCursor, Tags: []string{"other"}},
+		{Limit: 1, Cursor: page.NextCursor, Tags: []string{"test"}, URLHost: "example.com"},
+	} {
+		if _, err := s.Query(opts); !errors.Is(err, internal.ErrInvalidCursor) {
+			t.Errorf("Expected ErrInvalidCursor for mismatched filters %+v, got %v", opts, err)
+		}
+	}
+	if _, err := s.Query(internal.ListOptions{Limit: 1, Cursor: page.NextCursor, Tags: []string{"test", "test"}}); err != nil {
+		t.Errorf("Expected the cursor to work with the same filters: %v", err)
+	}
+}
+
+// Search Tests
+
+// seedSearchStore adds a small corpus for search tests and returns the store.
+func seedSearchStore(t *testing.T) *store.Store {
+	t.Helper()
+	s, _ := createTempStore(t)
+
+	mustAdd(t, s, internal.Bookmark{ // 1
+		Name:        "Effective Go",
+		Url:         "https://go.dev/doc/effective_go",
+		Description: "Tips for writing clear, idiomatic Go code",
+		Tags:        []string{"golang", "guide"},
+	})
+	mustAdd(t, s, internal.Bookmark{ // 2
+		Name:        "Go by Example",
+		Url:         "https://gobyexample.com",
+		Description: "Hands-on introduction to Go using annotated example programs",
+		Tags:        []string{"golang", "tutorial"},
+	})
+	mustAdd(t, s, internal.Bookmark{ // 3
+		Name:        "Error handling in Rust",
+		Url:         "https://doc.rust-lang.org/book/ch09-00-error-handling.html",
+		Description: "The Rust book chapter on errors",
+		Tags:        []string{"rust", "guide"},
+	})
+	mustAdd(t, s, internal.Bookmark{ // 4
+		Name:        "Go error handling video",
+		Url:         "https://youtube.com/watch?v=go-errors",
+		Description: "A talk about error handling",
+		Tags:        []string{"golang", "video"},
+	})
+
+	return s
+}
+
+func searchIDs(t *testing.T, s *store.Store, query string) []int {
+	t.Helper()
+	results, err := s.Search(query, 0)
+	if err != nil {
+		t.Fatalf("Search(%q) failed: %v", query, err)
+	}
+	var ids []int
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+func TestSearch_Queries(t *testing.T) {
+	s := seedSearchStore(t)
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"rust", []int{3}},
+		{"GOLANG", []int{1, 2, 4}},
+		{"error handling", []int{3, 4}},
+		{`"error handling" rust`, []int{3}},
+		{`"handling error"`, nil},
+		{"tag:guide", []int{1, 3}},
+		{"go tag:guide", []int{1}},
+		{"error -video", []int{3}},
+		{`error -"handling video"`, []int{3}},
+		{"tag:golang -tag:video", []int{1, 2}},
+		{"gobyexample.com", []int{2}},
+		{"nonexistent", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := searchIDs(t, s, tt.query)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearch_RanksNameAboveDescription(t *testing.T) {
+	s, _ := createTempStore(t)
+
+	descID := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) {
+		b.Name = "Something else"
+		b.Description = "mentions kubernetes once"
+		b.Tags = nil
+	}))
+	nameID := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) {
+		b.Name = "Kubernetes docs"
+		b.Description = ""
+		b.Tags = nil
+	}))
+
+	ids := searchIDs(t, s, "kubernetes")
+	if fmt.Sprint(ids) != fmt.Sprint([]int{nameID, descID}) {
+		t.Errorf("Expected name match first %v, got %v", []int{nameID, descID}, ids)
+	}
+}
+
+func TestSearch_IndexFollowsMutations(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	id := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Original title" }))
+	if ids := searchIDs(t, s, "original"); len(ids) != 1 {
+		t.Fatalf("Expected 1 result before update, got %v", ids)
+	}
+
+	if err := s.Update(id, testBookmark(func(b *internal.Bookmark) { b.Name = "Renamed title" })); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if ids := searchIDs(t, s, "original"); len(ids) != 0 {
+		t.Errorf("Expected no results for old name after update, got %v", ids)
+	}
+	if ids := searchIDs(t, s, "renamed"); len(ids) != 1 {
+		t.Errorf("Expected 1 result for new name, got %v", ids)
+	}
+
+	// The index is rebuilt from the snapshot and WAL on reload
+	s2 := reloadStore(t, s, filename)
+	if ids := searchIDs(t, s2, "renamed"); len(ids) != 1 {
+		t.Errorf("Expected 1 result after reload, got %v", ids)
+	}
+
+	if err := s.Delete(id); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if ids := searchIDs(t, s, "renamed"); len(ids) != 0 {
+		t.Errorf("Expected no results after delete, got %v", ids)
+	}
+}
+
+func TestSearch_LimitAndEmptyQuery(t *testing.T) {
+	s := seedSearchStore(t)
+
+	results, err := s.Search("golang", 2)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Errorf("Expected 2 results with limit, got %d", len(results))
+	}
+
+	for _, query := range []string{"", "   ", "-golang", `""`} {
+		if _, err := s.Search(query, 0); !errors.Is(err, internal.ErrEmptyQuery) {
+			t.Errorf("Search(%q): expected ErrEmptyQuery, got %v", query, err)
+		}
+	}
+}
+
 // Add Tests
 
 func TestAdd_SingleBookmark(t *testing.T) {
 	s, _ := createTempStore(t)
 
 	bookmark := testBookmark()
-	id := s.Add(bookmark)
+	id := mustAdd(t, s, bookmark)
 
 	if id != 1 {
 		t.Fatalf("Expected first ID to be 1, got %d", id)
@@ -319,9 +671,9 @@ func TestAdd_SingleBookmark(t *testing.T) {
 func TestAdd_MultipleBookmarks(t *testing.T) {
 	s, _ := createTempStore(t)
 
-	id1 := s.Add(testBookmark())
-	id2 := s.Add(testBookmark())
-	id3 := s.Add(testBookmark())
+	id1 := mustAdd(t, s, testBookmark())
+	id2 := mustAdd(t, s, testBookmark())
+	id3 := mustAdd(t, s, testBookmark())
 
 	if id1 != 1 || id2 != 2 || id3 != 3 {
 		t.Fatalf("Expected sequential IDs 1,2,3, got %d,%d,%d", id1, id2, id3)
@@ -333,7 +685,7 @@ func TestAdd_IDIncrement(t *testing.T) {
 
 	var ids []int
 	for i := 0; i < 10; i++ {
-		ids = append(ids, s.Add(testBookmark()))
+		ids = append(ids, mustAdd(t, s, testBookmark()))
 	}
 
 	// Verify all IDs are unique and sequential
@@ -355,7 +707,7 @@ func TestAdd_WithSpecialCharacters(t *testing.T) {
 		b.Tags = []string{"日本語", "español", "русский"}
 	})
 
-	id := s.Add(bookmark)
+	id := mustAdd(t, s, bookmark)
 	result, err := s.Get(id)
 	if err != nil {
 		t.Fatalf("Get failed: %v", err)
@@ -369,7 +721,7 @@ func TestAdd_WithSpecialCharacters(t *testing.T) {
 func TestUpdate_ExistingBookmark(t *testing.T) {
 	s, _ := createTempStore(t)
 
-	id := s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "Original" }))
+	id := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Original" }))
 
 	updated := testBookmark(func(b *internal.Bookmark) {
 		b.Name = "Updated"
@@ -401,7 +753,7 @@ func TestUpdate_NonExistentBookmark(t *testing.T) {
 func TestUpdate_Persistence(t *testing.T) {
 	s, filename := createTempStore(t)
 
-	id := s.Add(testBookmark())
+	id := mustAdd(t, s, testBookmark())
 	updated := testBookmark(func(b *internal.Bookmark) { b.Name = "Updated Name" })
 
 	err := s.Update(id, updated)
@@ -416,7 +768,7 @@ func TestUpdate_Persistence(t *testing.T) {
 	}
 
 	// Reload from disk
-	s2 := reloadStore(t, filename)
+	s2 := reloadStore(t, s, filename)
 
 	// Verify persisted
 	bookmark, err := s2.Get(id)
@@ -432,7 +784,7 @@ func TestUpdate_Persistence(t *testing.T) {
 func TestDelete_ExistingBookmark(t *testing.T) {
 	s, _ := createTempStore(t)
 
-	id := s.Add(testBookmark())
+	id := mustAdd(t, s, testBookmark())
 
 	err := s.Delete(id)
 	if err != nil {
@@ -458,7 +810,7 @@ func TestDelete_NonExistentBookmark(t *testing.T) {
 func TestDelete_NotInList(t *testing.T) {
 	s, _ := createTempStore(t)
 
-	id := s.Add(testBookmark())
+	id := mustAdd(t, s, testBookmark())
 	err := s.Delete(id)
 	if err != nil {
 		t.Fatalf("Delete failed: %v", err)
@@ -470,173 +822,1651 @@ func TestDelete_NotInList(t *testing.T) {
 	}
 }
 
-// Persistence Tests
+// Revision Tests
 
-func TestSaveSnapshot_BasicPersistence(t *testing.T) {
+func TestRevision_StartsAtOneAndIncrements(t *testing.T) {
 	s, filename := createTempStore(t)
 
-	bookmark := testBookmark()
-	id := s.Add(bookmark)
-
-	err := s.SaveSnapshot()
-	if err != nil {
-		t.Fatalf("SaveSnapshot failed: %v", err)
+	// The revision passed in is ignored
+	id := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Revision = 42 }))
+	if got, _ := s.Get(id); got.Revision != 1 {
+		t.Fatalf("Expected revision 1 after add, got %d", got.Revision)
 	}
 
-	// Reload from disk
-	s2 := reloadStore(t, filename)
-
-	result, err := s2.Get(id)
-	if err != nil {
-		t.Fatalf("Get failed after reload: %v", err)
+	for i := 0; i < 2; i++ {
+		if err := s.Update(id, testBookmark()); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+	if got, _ := s.Get(id); got.Revision != 3 {
+		t.Fatalf("Expected revision 3 after two updates, got %d", got.Revision)
 	}
 
-	assertBookmarkEqual(t, bookmark, result)
-
-	if s2.IdxCounter != s.IdxCounter {
-		t.Errorf("Expected IdxCounter=%d after reload, got %d", s.IdxCounter, s2.IdxCounter)
+	// Revisions are recorded in the write-ahead log
+	s2 := reloadStore(t, s, filename)
+	defer s2.Close()
+	if got, _ := s2.Get(id); got.Revision != 3 {
+		t.Errorf("Expected revision 3 after reload, got %d", got.Revision)
 	}
 }
 
-func TestSaveSnapshot_AtomicWrite(t *testing.T) {
-	s, filename := createTempStore(t)
+func TestUpdateIfRevision(t *testing.T) {
+	s, _ := createTempStore(t)
 
-	s.Add(testBookmark())
+	id := mustAdd(t, s, testBookmark())
 
-	err := s.SaveSnapshot()
-	if err != nil {
-		t.Fatalf("SaveSnapshot failed: %v", err)
+	stale := testBookmark(func(b *internal.Bookmark) { b.Name = "Stale" })
+	if err := s.UpdateIfRevision(id, stale, 2); !errors.Is(err, internal.ErrRevisionMismatch) {
+		t.Fatalf("Expected ErrRevisionMismatch, got %v", err)
+	}
+	if got, _ := s.Get(id); got.Name == "Stale" || got.Revision != 1 {
+		t.Fatalf("Expected bookmark to be unchanged, got %+v", got)
 	}
 
-	// Verify no temp files left behind
-	dir := filepath.Dir(filename)
-	files, err := os.ReadDir(dir)
-	if err != nil {
-		t.Fatalf("Failed to read directory: %v", err)
+	fresh := testBookmark(func(b *internal.Bookmark) { b.Name = "Fresh" })
+	if err := s.UpdateIfRevision(id, fresh, 1); err != nil {
+		t.Fatalf("UpdateIfRevision failed: %v", err)
+	}
+	if got, _ := s.Get(id); got.Name != "Fresh" || got.Revision != 2 {
+		t.Errorf("Expected Fresh at revision 2, got %+v", got)
 	}
 
-	for _, file := range files {
-		if strings.HasPrefix(file.Name(), "snapshot-") && strings.HasSuffix(file.Name(), ".json") {
-			t.Errorf("Temporary snapshot file not cleaned up: %s", file.Name())
-		}
+	if err := s.UpdateIfRevision(999, fresh, 1); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
 	}
 }
 
-func TestReloadAfterAdd(t *testing.T) {
-	s, filename := createTempStore(t)
-
-	bookmark1 := testBookmark(func(b *internal.Bookmark) { b.Name = "First" })
-	bookmark2 := testBookmark(func(b *internal.Bookmark) { b.Name = "Second" })
-
-	id1 := s.Add(bookmark1)
-	id2 := s.Add(bookmark2)
-
-	s.SaveSnapshot()
+func TestDeleteIfRevision(t *testing.T) {
+	s, _ := createTempStore(t)
 
-	// Reload
-	s2 := reloadStore(t, filename)
+	id := mustAdd(t, s, testBookmark())
+	if err := s.Update(id, testBookmark()); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
 
-	// Verify both bookmarks
-	result1, err := s2.Get(id1)
-	if err != nil {
-		t.Fatalf("Get bookmark 1 failed: %v", err)
+	if err := s.DeleteIfRevision(id, 1); !errors.Is(err, internal.ErrRevisionMismatch) {
+		t.Fatalf("Expected ErrRevisionMismatch, got %v", err)
+	}
+	if _, err := s.Get(id); err != nil {
+		t.Fatalf("Expected bookmark to survive a stale delete: %v", err)
 	}
-	assertBookmarkEqual(t, bookmark1, result1)
 
-	result2, err := s2.Get(id2)
-	if err != nil {
-		t.Fatalf("Get bookmark 2 failed: %v", err)
+	if err := s.DeleteIfRevision(id, 2); err != nil {
+		t.Fatalf("DeleteIfRevision failed: %v", err)
+	}
+	if _, err := s.Get(id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected bookmark to be deleted, got %v", err)
 	}
-	assertBookmarkEqual(t, bookmark2, result2)
 }
 
-func TestReloadAfterUpdate(t *testing.T) {
-	s, filename := createTempStore(t)
+// Batch Tests
 
-	id := s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "Original" }))
+func TestBatch_BestEffort(t *testing.T) {
+	s, filename := createTempStore(t)
 
-	// Update (no longer persists automatically)
-	updated := testBookmark(func(b *internal.Bookmark) { b.Name = "Updated" })
-	err := s.Update(id, updated)
+	id := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.CreatedAt = 100 }))
+	stale := uint64(7)
+	created := testBookmark(func(b *internal.Bookmark) { b.Name = "Created" })
+	renamed := testBookmark(func(b *internal.Bookmark) { b.Name = "Renamed"; b.CreatedAt = 500 })
+
+	results, err := s.Batch([]internal.BatchOp{
+		{Op: internal.BatchCreate, Bookmark: &created},
+		{Op: internal.BatchUpdate, ID: id, Bookmark: &renamed},
+		{Op: internal.BatchDelete, ID: id, IfRevision: &stale},
+		{Op: internal.BatchDelete, ID: 999},
+	}, false)
 	if err != nil {
-		t.Fatalf("Update failed: %v", err)
+		t.Fatalf("Batch failed: %v", err)
 	}
 
-	// Manually save
-	s.SaveSnapshot()
+	if results[0].Err != nil || results[0].ID != id+1 || results[0].Revision != 1 {
+		t.Errorf("Expected create of ID %d at revision 1, got %+v", id+1, results[0])
+	}
+	if results[1].Err != nil || results[1].Revision != 2 {
+		t.Errorf("Expected update to revision 2, got %+v", results[1])
+	}
+	if !errors.Is(results[2].Err, internal.ErrRevisionMismatch) {
+		t.Errorf("Expected ErrRevisionMismatch, got %v", results[2].Err)
+	}
+	if !errors.Is(results[3].Err, internal.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", results[3].Err)
+	}
+	if gen := s.CurrentGeneration(); gen != 3 {
+		t.Errorf("Expected generation 3 after add and two batch writes, got %d", gen)
+	}
 
-	// Reload
-	s2 := reloadStore(t, filename)
+	// The batch is replayed from the write-ahead log
+	s2 := reloadStore(t, s, filename)
+	defer s2.Close()
+	got, err := s2.Get(id)
+	if err != nil || got.Name != "Renamed" || got.CreatedAt != 100 || got.Revision != 2 {
+		t.Errorf("Expected renamed bookmark with original CreatedAt after reload, got %+v (%v)", got, err)
+	}
+	if _, err := s2.Get(id + 1); err != nil {
+		t.Errorf("Expected created bookmark after reload: %v", err)
+	}
+}
 
-	result, err := s2.Get(id)
+func TestBatch_SeesEarlierOperations(t *testing.T) {
+	s, _ := createTempStore(t)
+
+	id := mustAdd(t, s, testBookmark())
+	updated := testBookmark()
+	revision := uint64(2)
+
+	results, err := s.Batch([]internal.BatchOp{
+		{Op: internal.BatchUpdate, ID: id, Bookmark: &updated},
+		{Op: internal.BatchDelete, ID: id, IfRevision: &revision},
+		{Op: internal.BatchDelete, ID: id},
+	}, false)
 	if err != nil {
-		t.Fatalf("Get failed after reload: %v", err)
+		t.Fatalf("Batch failed: %v", err)
 	}
 
-	assertBookmarkEqual(t, updated, result)
+	if results[0].Err != nil || results[1].Err != nil {
+		t.Fatalf("Expected update then conditional delete to succeed, got %+v", results)
+	}
+	if !errors.Is(results[2].Err, internal.ErrNotFound) {
+		t.Errorf("Expected second delete to find nothing, got %v", results[2].Err)
+	}
 }
 
-func TestReloadAfterDelete_WithSnapshot(t *testing.T) {
+func TestBatch_AtomicRollsBack(t *testing.T) {
 	s, filename := createTempStore(t)
 
-	id := s.Add(testBookmark())
-	s.SaveSnapshot()
+	id := mustAdd(t, s, testBookmark())
+	created := testBookmark()
 
-	err := s.Delete(id)
+	results, err := s.Batch([]internal.BatchOp{
+		{Op: internal.BatchCreate, Bookmark: &created},
+		{Op: internal.BatchDelete, ID: id},
+		{Op: internal.BatchDelete, ID: 999},
+	}, true)
 	if err != nil {
-		t.Fatalf("Delete failed: %v", err)
+		t.Fatalf("Batch failed: %v", err)
 	}
 
-	s.SaveSnapshot()
-
-	// Reload
-	s2 := reloadStore(t, filename)
+	for i, want := range []error{internal.ErrBatchAborted, internal.ErrBatchAborted, internal.ErrNotFound} {
+		if !errors.Is(results[i].Err, want) {
+			t.Errorf("Result %d: expected %v, got %v", i, want, results[i].Err)
+		}
+	}
+	if len(s.List()) != 1 || s.CurrentGeneration() != 1 {
+		t.Errorf("Expected nothing applied, got %d bookmarks at generation %d", len(s.List()), s.CurrentGeneration())
+	}
 
-	_, err = s2.Get(id)
-	if err == nil {
-		t.Fatal("Deleted bookmark should not exist after reload")
+	s2 := reloadStore(t, s, filename)
+	defer s2.Close()
+	if len(s2.List()) != 1 {
+		t.Errorf("Expected nothing logged, got %d bookmarks after reload", len(s2.List()))
 	}
 }
 
-func TestReloadAfterDelete_WithoutSnapshot(t *testing.T) {
-	s, filename := createTempStore(t)
+// Change Feed Tests
 
-	bookmark := testBookmark()
-	id := s.Add(bookmark)
-	s.SaveSnapshot()
+// receive reads the next event from a subscription or fails after a timeout.
+func receive(t *testing.T, events <-chan internal.Event) internal.Event {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("Subscription closed unexpectedly")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for event")
+	}
+	return internal.Event{}
+}
 
-	// Delete but don't snapshot
-	err := s.Delete(id)
+func TestSubscribe_PublishesMutations(t *testing.T) {
+	s, _ := createTempStore(t)
+
+	events, cancel, err := s.Subscribe(s.CurrentGeneration())
 	if err != nil {
-		t.Fatalf("Delete failed: %v", err)
+		t.Fatalf("Subscribe failed: %v", err)
 	}
+	defer cancel()
 
-	// Reload without saving
-	s2 := reloadStore(t, filename)
+	id := mustAdd(t, s, testBookmark())
+	if err := s.Update(id, testBookmark(func(b *internal.Bookmark) { b.Name = "Renamed" })); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	created := testBookmark()
+	if _, err := s.Batch([]internal.BatchOp{
+		{Op: internal.BatchCreate, Bookmark: &created},
+		{Op: internal.BatchDelete, ID: id},
+	}, true); err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
 
-	// Bookmark should still exist (delete wasn't persisted)
-	result, err := s2.Get(id)
-	if err != nil {
-		t.Fatal("Expected bookmark to still exist (delete not persisted)")
+	want := []struct {
+		seq  uint64
+		kind string
+		id   int
+	}{
+		{1, internal.EventAdd, id},
+		{2, internal.EventUpdate, id},
+		{3, internal.EventAdd, id + 1},
+		{4, internal.EventDelete, id},
+	}
+	for _, w := range want {
+		event := receive(t, events)
+		if event.Seq != w.seq || event.Type != w.kind || event.ID != w.id {
+			t.Errorf("Expected %s of %d at seq %d, got %+v", w.kind, w.id, w.seq, event)
+		}
 	}
 
-	assertBookmarkEqual(t, bookmark, result)
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("Expected channel to be closed after cancel")
+	}
 }
 
-func TestNewStoreWithInvalidJSON(t *testing.T) {
-	tmpFile, err := os.CreateTemp("", "invalid-*.json")
+func TestSubscribe_ResumesFromSequence(t *testing.T) {
+	s, _ := createTempStore(t)
+
+	for i := 0; i < 3; i++ {
+		mustAdd(t, s, testBookmark())
+	}
+
+	events, cancel, err := s.Subscribe(1)
 	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
+		t.Fatalf("Subscribe failed: %v", err)
 	}
-	defer os.Remove(tmpFile.Name())
+	defer cancel()
+
+	for _, seq := range []uint64{2, 3} {
+		if event := receive(t, events); event.Seq != seq || event.Bookmark == nil || event.Bookmark.Revision != 1 {
+			t.Errorf("Expected buffered add at seq %d, got %+v", seq, event)
+		}
+	}
+}
+
+func TestSubscribe_ExpiredSequence(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	mustAdd(t, s, testBookmark())
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	s.Close()
+
+	// The event for generation 1 was folded into the snapshot and is gone after a restart
+	s2 := reloadStore(t, s, filename)
+	defer s2.Close()
+
+	if _, _, err := s2.Subscribe(0); !errors.Is(err, internal.ErrEventsExpired) {
+		t.Errorf("Expected ErrEventsExpired for a discarded event, got %v", err)
+	}
+	if _, _, err := s2.Subscribe(5); !errors.Is(err, internal.ErrEventsExpired) {
+		t.Errorf("Expected ErrEventsExpired for a future sequence, got %v", err)
+	}
+
+	events, _, err := s2.Subscribe(1)
+	if err != nil {
+		t.Fatalf("Subscribe at the current generation failed: %v", err)
+	}
+	s2.Close()
+	if _, ok := <-events; ok {
+		t.Error("Expected channel to be closed when the store closes")
+	}
+}
+
+// User Account Tests
+
+// openTempUsers opens user accounts kept in a temporary directory.
+func openTempUsers(t *testing.T) (*store.Users, string) {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), "users.json")
+
+	users, err := store.OpenUsers(fileName)
+	if err != nil {
+		t.Fatalf("Failed to open users: %v", err)
+	}
+	t.Cleanup(func() { users.Close() })
+
+	return users, fileName
+}
+
+func TestUsers_CreateAndAuthenticate(t *testing.T) {
+	users, fileName := openTempUsers(t)
+
+	if err := users.Create("alice", "correct horse"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := users.Create("alice", "another password"); !errors.Is(err, internal.ErrUserExists) {
+		t.Errorf("Expected ErrUserExists for a taken username, got %v", err)
+	}
+	if err := users.Create("Bob/..", "correct horse"); err == nil {
+		t.Error("Expected an error for an invalid username")
+	}
+	if err := users.Create("bob", "short"); err == nil {
+		t.Error("Expected an error for a short password")
+	}
+
+	if _, err := users.Authenticate("alice", "correct horse"); err != nil {
+		t.Errorf("Authenticate with the right password failed: %v", err)
+	}
+	if _, err := users.Authenticate("alice", "wrong password"); !errors.Is(err, internal.ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for a wrong password, got %v", err)
+	}
+	if _, err := users.Authenticate("nobody", "correct horse"); !errors.Is(err, internal.ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for an unknown user, got %v", err)
+	}
+
+	// Only the hash of the password is stored
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Failed to read users file: %v", err)
+	}
+	if strings.Contains(string(data), "correct horse") {
+		t.Error("Users file contains the plain text password")
+	}
+}
+
+func TestUsers_AuthenticateUnknownUserTakesAsLong(t *testing.T) {
+	users, _ := openTempUsers(t)
+	if err := users.Create("alice", "correct horse"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	start := time.Now()
+	users.Authenticate("alice", "wrong password")
+	known := time.Since(start)
+
+	start = time.Now()
+	if _, err := users.Authenticate("nobody", "wrong password"); !errors.Is(err, internal.ErrInvalidCredentials) {
+		t.Fatalf("Expected ErrInvalidCredentials for an unknown user, got %v", err)
+	}
+	unknown := time.Since(start)
+
+	// Both run bcrypt at the same cost; allow generous slack for a busy machine
+	if unknown < known/4 {
+		t.Errorf("Expected rejecting an unknown user to take about as long as a wrong password, took %v against %v", unknown, known)
+	}
+}
+
+func TestUsers_DisableAndReset(t *testing.T) {
+	users, _ := openTempUsers(t)
+
+	if err := users.Create("alice", "correct horse"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if err := users.SetDisabled("alice", true); err != nil {
+		t.Fatalf("SetDisabled failed: %v", err)
+	}
+	if _, err := users.Authenticate("alice", "correct horse"); !errors.Is(err, internal.ErrUserDisabled) {
+		t.Errorf("Expected ErrUserDisabled, got %v", err)
+	}
+	if _, err := users.Authenticate("alice", "wrong password"); !errors.Is(err, internal.ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials for a wrong password of a disabled user, got %v", err)
+	}
+
+	if err := users.SetDisabled("alice", false); err != nil {
+		t.Fatalf("SetDisabled failed: %v", err)
+	}
+	if err := users.SetPassword("alice", "battery staple"); err != nil {
+		t.Fatalf("SetPassword failed: %v", err)
+	}
+	if _, err := users.Authenticate("alice", "correct horse"); !errors.Is(err, internal.ErrInvalidCredentials) {
+		t.Errorf("Expected the old password to be rejected, got %v", err)
+	}
+	if _, err := users.Authenticate("alice", "battery staple"); err != nil {
+		t.Errorf("Authenticate with the new password failed: %v", err)
+	}
+
+	if err := users.SetPassword("nobody", "battery staple"); !errors.Is(err, internal.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if err := users.SetDisabled("nobody", true); !errors.Is(err, internal.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestUsers_ChangesFromAnotherProcess(t *testing.T) {
+	users, fileName := openTempUsers(t)
+
+	// A second instance on the same file stands in for the fave user command
+	admin, err := store.OpenUsers(fileName)
+	if err != nil {
+		t.Fatalf("Failed to open users: %v", err)
+	}
+	defer admin.Close()
+
+	if err := admin.Create("alice", "correct horse"); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := users.Authenticate("alice", "correct horse"); err != nil {
+		t.Errorf("Expected a user created by another process to authenticate, got %v", err)
+	}
+
+	if err := admin.SetDisabled("alice", true); err != nil {
+		t.Fatalf("SetDisabled failed: %v", err)
+	}
+	if _, err := users.Authenticate("alice", "correct horse"); !errors.Is(err, internal.ErrUserDisabled) {
+		t.Errorf("Expected a user disabled by another process to be rejected, got %v", err)
+	}
+
+	list, err := users.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 1 || list[0].Username != "alice" || !list[0].Disabled {
+		t.Errorf("Expected disabled alice, got %+v", list)
+	}
+}
+
+func TestUsers_ConcurrentChangesFromAnotherProcess(t *testing.T) {
+	users, fileName := openTempUsers(t)
+
+	admin, err := store.OpenUsers(fileName)
+	if err != nil {
+		t.Fatalf("Failed to open users: %v", err)
+	}
+	defer admin.Close()
+
+	// Accounts created by both instances at once must all be kept
+	var wg sync.WaitGroup
+	for i, instance := range []*store.Users{users, admin} {
+		for j := range 5 {
+			wg.Go(func() {
+				if err := instance.Create(fmt.Sprintf("user-%d-%d", i, j), "correct horse"); err != nil {
+					t.Errorf("Create failed: %v", err)
+				}
+			})
+		}
+	}
+	wg.Wait()
+
+	list, err := users.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 10 {
+		t.Errorf("Expected 10 users, got %d", len(list))
+	}
+}
+
+func TestUsers_NamespacesAreSeparate(t *testing.T) {
+	users, fileName := openTempUsers(t)
+
+	for _, name := range []string{"alice", "bob"} {
+		if err := users.Create(name, "correct horse"); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	alice, err := users.Namespace("alice")
+	if err != nil {
+		t.Fatalf("Namespace failed: %v", err)
+	}
+	bob, err := users.Namespace("bob")
+	if err != nil {
+		t.Fatalf("Namespace failed: %v", err)
+	}
+	if _, err := users.Namespace("nobody"); !errors.Is(err, internal.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+
+	id := mustAdd(t, alice, testBookmark())
+	if _, err := bob.Get(id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected bob not to see alice's bookmark, got %v", err)
+	}
+	if len(bob.List()) != 0 {
+		t.Errorf("Expected bob's namespace to be empty, got %d bookmarks", len(bob.List()))
+	}
+
+	// Namespaces are saved to files of their own and reopened with their bookmarks
+	if err := users.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	if err := users.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(fileName), "users", "alice.json")); err != nil {
+		t.Errorf("Expected alice's bookmarks in users/alice.json: %v", err)
+	}
+
+	reopened, err := store.OpenUsers(fileName)
+	if err != nil {
+		t.Fatalf("Failed to reopen users: %v", err)
+	}
+	defer reopened.Close()
+
+	alice, err = reopened.Namespace("alice")
+	if err != nil {
+		t.Fatalf("Namespace failed: %v", err)
+	}
+	if _, err := alice.Get(id); err != nil {
+		t.Errorf("Expected alice's bookmark after reopening, got %v", err)
+	}
+}
+
+// API Token Tests
+
+func TestTokens_CreateAuthenticateRevoke(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "tokens.json")
+	tokens, err := store.OpenTokens(fileName)
+	if err != nil {
+		t.Fatalf("Failed to open tokens: %v", err)
+	}
+
+	token, secret, err := tokens.Create("alice", "CI", []string{internal.ScopeRead}, 0)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.HasPrefix(secret, "fave_") {
+		t.Errorf("Expected the secret to start with fave_, got %q", secret)
+	}
+
+	authenticated, err := tokens.Authenticate(secret)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if authenticated.ID != token.ID || authenticated.Owner != "alice" || authenticated.LastUsedAt == 0 {
+		t.Errorf("Unexpected token: %+v", authenticated)
+	}
+	if _, err := tokens.Authenticate(secret + "x"); !errors.Is(err, internal.ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken, got %v", err)
+	}
+
+	if got := tokens.List("alice"); len(got) != 1 || got[0].ID != token.ID {
+		t.Errorf("Expected alice's token to be listed, got %+v", got)
+	}
+	if got := tokens.List("bob"); len(got) != 0 {
+		t.Errorf("Expected bob to have no tokens, got %+v", got)
+	}
+
+	// Last-used times are written by SaveSnapshot, and only hashes are stored
+	if err := tokens.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatalf("Failed to read tokens file: %v", err)
+	}
+	if strings.Contains(string(data), secret) {
+		t.Error("Tokens file contains the secret")
+	}
+
+	reopened, err := store.OpenTokens(fileName)
+	if err != nil {
+		t.Fatalf("Failed to reopen tokens: %v", err)
+	}
+	if got := reopened.List("alice"); len(got) != 1 || got[0].LastUsedAt == 0 {
+		t.Errorf("Expected the token with its last-used time after reopening, got %+v", got)
+	}
+
+	if err := reopened.Revoke("bob", token.ID); !errors.Is(err, internal.ErrTokenNotFound) {
+		t.Errorf("Expected ErrTokenNotFound revoking another user's token, got %v", err)
+	}
+	if err := reopened.Revoke("alice", token.ID); err != nil {
+		t.Fatalf("Revoke failed: %v", err)
+	}
+	if _, err := reopened.Authenticate(secret); !errors.Is(err, internal.ErrInvalidToken) {
+		t.Errorf("Expected a revoked token to be rejected, got %v", err)
+	}
+}
+
+func TestTokens_Expiry(t *testing.T) {
+	tokens, err := store.OpenTokens(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatalf("Failed to open tokens: %v", err)
+	}
+
+	_, expired, err := tokens.Create("", "Old", []string{internal.ScopeRead}, time.Now().Add(-time.Minute).Unix())
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	_, valid, err := tokens.Create("", "New", []string{internal.ScopeRead}, time.Now().Add(time.Hour).Unix())
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if _, err := tokens.Authenticate(expired); !errors.Is(err, internal.ErrTokenExpired) {
+		t.Errorf("Expected ErrTokenExpired, got %v", err)
+	}
+	if _, err := tokens.Authenticate(valid); err != nil {
+		t.Errorf("Expected an unexpired token to authenticate, got %v", err)
+	}
+}
+
+// Persistence Tests
+
+func TestSaveSnapshot_BasicPersistence(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	bookmark := testBookmark()
+	id := mustAdd(t, s, bookmark)
+
+	err := s.SaveSnapshot()
+	if err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	// Reload from disk
+	s2 := reloadStore(t, s, filename)
+
+	result, err := s2.Get(id)
+	if err != nil {
+		t.Fatalf("Get failed after reload: %v", err)
+	}
+
+	assertBookmarkEqual(t, bookmark, result)
+
+	if s2.IdxCounter != s.IdxCounter {
+		t.Errorf("Expected IdxCounter=%d after reload, got %d", s.IdxCounter, s2.IdxCounter)
+	}
+}
+
+func TestSaveSnapshot_AtomicWrite(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	mustAdd(t, s, testBookmark())
+
+	err := s.SaveSnapshot()
+	if err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	// Verify no temp files left behind
+	dir := filepath.Dir(filename)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+
+	for _, file := range files {
+		if strings.HasPrefix(file.Name(), "snapshot-") && strings.HasSuffix(file.Name(), ".json") {
+			t.Errorf("Temporary snapshot file not cleaned up: %s", file.Name())
+		}
+	}
+}
+
+func TestReloadAfterAdd(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	bookmark1 := testBookmark(func(b *internal.Bookmark) { b.Name = "First" })
+	bookmark2 := testBookmark(func(b *internal.Bookmark) { b.Name = "Second" })
+
+	id1 := mustAdd(t, s, bookmark1)
+	id2 := mustAdd(t, s, bookmark2)
+
+	s.SaveSnapshot()
+
+	// Reload
+	s2 := reloadStore(t, s, filename)
+
+	// Verify both bookmarks
+	result1, err := s2.Get(id1)
+	if err != nil {
+		t.Fatalf("Get bookmark 1 failed: %v", err)
+	}
+	assertBookmarkEqual(t, bookmark1, result1)
+
+	result2, err := s2.Get(id2)
+	if err != nil {
+		t.Fatalf("Get bookmark 2 failed: %v", err)
+	}
+	assertBookmarkEqual(t, bookmark2, result2)
+}
+
+func TestReloadAfterUpdate(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	id := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Original" }))
+
+	// Update (no longer persists automatically)
+	updated := testBookmark(func(b *internal.Bookmark) { b.Name = "Updated" })
+	err := s.Update(id, updated)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// Manually save
+	s.SaveSnapshot()
+
+	// Reload
+	s2 := reloadStore(t, s, filename)
+
+	result, err := s2.Get(id)
+	if err != nil {
+		t.Fatalf("Get failed after reload: %v", err)
+	}
+
+	assertBookmarkEqual(t, updated, result)
+}
+
+func TestReloadAfterDelete_WithSnapshot(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	id := mustAdd(t, s, testBookmark())
+	s.SaveSnapshot()
+
+	err := s.Delete(id)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	s.SaveSnapshot()
+
+	// Reload
+	s2 := reloadStore(t, s, filename)
+
+	_, err = s2.Get(id)
+	if err == nil {
+		t.Fatal("Deleted bookmark should not exist after reload")
+	}
+}
+
+func TestReloadAfterDelete_WithoutSnapshot(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	id := mustAdd(t, s, testBookmark())
+	s.SaveSnapshot()
+
+	// Delete but don't snapshot
+	err := s.Delete(id)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// Reload without saving
+	s2 := reloadStore(t, s, filename)
+
+	// The delete was recorded in the write-ahead log and must be replayed
+	_, err = s2.Get(id)
+	if err == nil {
+		t.Fatal("Deleted bookmark should not exist after reload (delete is in the WAL)")
+	}
+}
+
+func TestNewStoreWithInvalidJSON(t *testing.T) {
+	tmpFile, err := os.CreateTemp("", "invalid-*.json")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer os.Remove(tmpFile.Name() + ".lock")
 
 	// Write invalid JSON
 	tmpFile.WriteString("{invalid json content")
 	tmpFile.Close()
 
-	_, err = store.NewStore(tmpFile.Name())
-	if err == nil {
-		t.Fatal("Expected error when loading invalid JSON, got nil")
+	_, err = store.NewStore(tmpFile.Name())
+	if err == nil {
+		t.Fatal("Expected error when loading invalid JSON, got nil")
+	}
+}
+
+// Dirty Tracking Tests
+
+func TestGeneration_IncrementsOnMutation(t *testing.T) {
+	s, _ := createTempStore(t)
+
+	if gen := s.CurrentGeneration(); gen != 0 {
+		t.Fatalf("Expected generation 0 for new store, got %d", gen)
+	}
+
+	id := mustAdd(t, s, testBookmark())
+	s.Update(id, testBookmark())
+	s.Delete(id)
+
+	if gen := s.CurrentGeneration(); gen != 3 {
+		t.Errorf("Expected generation 3 after three mutations, got %d", gen)
+	}
+
+	// Failed mutations do not change the generation
+	s.Delete(999)
+	if gen := s.CurrentGeneration(); gen != 3 {
+		t.Errorf("Expected generation 3 after failed delete, got %d", gen)
+	}
+}
+
+func TestSaveSnapshot_SkipsWhenUnchanged(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	mustAdd(t, s, testBookmark())
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	gen, at := s.Persisted()
+	if gen != 1 {
+		t.Errorf("Expected persisted generation 1, got %d", gen)
+	}
+	if at.IsZero() {
+		t.Error("Expected persisted time to be set")
+	}
+
+	// Replace the snapshot with a marker; an unchanged store must not rewrite it
+	if err := os.WriteFile(filename, []byte("marker"), 0666); err != nil {
+		t.Fatalf("Failed to write marker: %v", err)
+	}
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	if string(data) != "marker" {
+		t.Error("Expected SaveSnapshot to skip writing an unchanged store")
+	}
+
+	// A new mutation makes the store dirty again
+	mustAdd(t, s, testBookmark())
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	if gen, _ := s.Persisted(); gen != 2 {
+		t.Errorf("Expected persisted generation 2, got %d", gen)
+	}
+}
+
+func TestGeneration_Persistence(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	mustAdd(t, s, testBookmark())
+	mustAdd(t, s, testBookmark())
+	s.SaveSnapshot()
+	mustAdd(t, s, testBookmark())
+
+	// Generation 2 is in the snapshot, generation 3 only in the WAL
+	s2 := reloadStore(t, s, filename)
+
+	if gen := s2.CurrentGeneration(); gen != 3 {
+		t.Errorf("Expected generation 3 after reload, got %d", gen)
+	}
+	if gen, _ := s2.Persisted(); gen != 2 {
+		t.Errorf("Expected persisted generation 2 after reload, got %d", gen)
+	}
+}
+
+func TestLastModified_AdvancesOnMutation(t *testing.T) {
+	s, _ := createTempStore(t)
+
+	loaded := s.LastModified()
+	if loaded.IsZero() {
+		t.Fatal("Expected LastModified to be set on load")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	mustAdd(t, s, testBookmark())
+	added := s.LastModified()
+	if !added.After(loaded) {
+		t.Errorf("Expected LastModified to advance after Add, got %v then %v", loaded, added)
+	}
+
+	// Reads do not count as modifications
+	s.List()
+	if _, err := s.Search("test", 0); err != nil && !errors.Is(err, internal.ErrEmptyQuery) {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if got := s.LastModified(); !got.Equal(added) {
+		t.Errorf("Expected LastModified unchanged by reads, got %v", got)
+	}
+}
+
+// Write-Ahead Log Tests
+
+func TestWAL_ReplayWithoutSnapshot(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	bookmark1 := testBookmark(func(b *internal.Bookmark) { b.Name = "First" })
+	bookmark2 := testBookmark(func(b *internal.Bookmark) { b.Name = "Second" })
+	updated := testBookmark(func(b *internal.Bookmark) { b.Name = "Second Updated" })
+
+	id1 := mustAdd(t, s, bookmark1)
+	id2 := mustAdd(t, s, bookmark2)
+	if err := s.Update(id2, updated); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := s.Delete(id1); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// Simulate a crash: no snapshot is ever saved
+	s2 := reloadStore(t, s, filename)
+
+	if _, err := s2.Get(id1); err == nil {
+		t.Error("Expected deleted bookmark to stay deleted after replay")
+	}
+
+	result, err := s2.Get(id2)
+	if err != nil {
+		t.Fatalf("Get failed after replay: %v", err)
+	}
+	assertBookmarkEqual(t, updated, result)
+
+	if s2.IdxCounter != 2 {
+		t.Errorf("Expected IdxCounter=2 after replay, got %d", s2.IdxCounter)
+	}
+}
+
+func TestWAL_TruncatedAfterSnapshot(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	mustAdd(t, s, testBookmark())
+
+	info, err := os.Stat(filename + ".wal")
+	if err != nil {
+		t.Fatalf("Expected WAL file to exist: %v", err)
+	}
+	if info.Size() == 0 {
+		t.Fatal("Expected WAL to contain the add")
+	}
+
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	info, err = os.Stat(filename + ".wal")
+	if err != nil {
+		t.Fatalf("Stat WAL failed: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Errorf("Expected WAL to be empty after snapshot, got %d bytes", info.Size())
+	}
+}
+
+func TestWAL_ReplayAfterSnapshotIsIdempotent(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	id := mustAdd(t, s, testBookmark())
+
+	// Copy the WAL, snapshot, then restore the WAL to simulate a crash
+	// between saving the snapshot and truncating the log.
+	walData, err := os.ReadFile(filename + ".wal")
+	if err != nil {
+		t.Fatalf("Failed to read WAL: %v", err)
+	}
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	if err := os.WriteFile(filename+".wal", walData, 0666); err != nil {
+		t.Fatalf("Failed to restore WAL: %v", err)
+	}
+
+	s2 := reloadStore(t, s, filename)
+
+	bookmarks := s2.List()
+	if len(bookmarks) != 1 {
+		t.Fatalf("Expected 1 bookmark after replay, got %d", len(bookmarks))
+	}
+	if _, exists := bookmarks[id]; !exists {
+		t.Errorf("Expected bookmark %d after replay", id)
+	}
+}
+
+func TestWAL_TornRecordDiscarded(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	bookmark := testBookmark()
+	id := mustAdd(t, s, bookmark)
+
+	// Simulate a crash in the middle of appending a second record
+	f, err := os.OpenFile(filename+".wal", os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+	f.WriteString(`{"op":"add","id":2,"bookmark":{"na`)
+	f.Close()
+
+	s2 := reloadStore(t, s, filename)
+
+	bookmarks := s2.List()
+	if len(bookmarks) != 1 {
+		t.Fatalf("Expected only the acknowledged bookmark, got %d", len(bookmarks))
+	}
+
+	// Appends after recovery must still be replayable
+	id2 := mustAdd(t, s2, testBookmark(func(b *internal.Bookmark) { b.Name = "After" }))
+	s2.Close()
+
+	s3 := reloadStore(t, s2, filename)
+	if _, err := s3.Get(id); err != nil {
+		t.Errorf("Expected bookmark %d after second replay: %v", id, err)
+	}
+	result, err := s3.Get(id2)
+	if err != nil {
+		t.Fatalf("Expected bookmark %d after second replay: %v", id2, err)
+	}
+	if result.Name != "After" {
+		t.Errorf("Expected Name='After', got '%s'", result.Name)
+	}
+}
+
+func TestWAL_FailedWriteCutOff(t *testing.T) {
+	s, filename := createTempStore(t)
+	id := mustAdd(t, s, testBookmark())
+
+	// The disk fills up halfway through the next record
+	store.FailNextWALWrite()
+	if _, err := s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "Lost" })); err == nil {
+		t.Fatal("Expected Add to fail when the WAL write fails")
+	}
+
+	// Space is freed, and writing goes on
+	id2 := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "After" }))
+
+	s2 := reloadStore(t, s, filename)
+	bookmarks := s2.List()
+	if len(bookmarks) != 2 {
+		t.Fatalf("Expected the 2 acknowledged bookmarks after replay, got %d", len(bookmarks))
+	}
+	if _, err := s2.Get(id); err != nil {
+		t.Errorf("Expected bookmark %d after replay: %v", id, err)
+	}
+	result, err := s2.Get(id2)
+	if err != nil {
+		t.Fatalf("Expected bookmark %d after replay: %v", id2, err)
+	}
+	if result.Name != "After" {
+		t.Errorf("Expected Name='After', got '%s'", result.Name)
+	}
+}
+
+func TestWAL_CorruptRecord(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	mustAdd(t, s, testBookmark())
+
+	f, err := os.OpenFile(filename+".wal", os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatalf("Failed to open WAL: %v", err)
+	}
+	f.WriteString("{not json}\n")
+	f.Close()
+
+	_, err = store.NewStore(filename)
+	if err == nil {
+		t.Fatal("Expected error when WAL contains a corrupt record, got nil")
+	}
+}
+
+// File Lock Tests
+
+func TestLock_SecondOpenFails(t *testing.T) {
+	s, filename := createTempStore(t)
+
+	if _, err := store.NewStore(filename); !errors.Is(err, internal.ErrStoreLocked) {
+		t.Fatalf("Expected ErrStoreLocked while the store is open, got %v", err)
+	}
+	if _, err := store.NewStoreWithEngine(filename, store.EngineBTree); !errors.Is(err, internal.ErrStoreLocked) {
+		t.Fatalf("Expected ErrStoreLocked before migrating, got %v", err)
+	}
+
+	// The file is left untouched by the failed opens
+	id := mustAdd(t, s, testBookmark())
+
+	s2 := reloadStore(t, s, filename)
+	if _, err := s2.Get(id); err != nil {
+		t.Errorf("Expected bookmark %d after the lock was released: %v", id, err)
+	}
+}
+
+func TestLock_ErrorNamesHolder(t *testing.T) {
+	_, filename := createTempStore(t)
+
+	_, err := store.NewStore(filename)
+	var lockErr *store.LockError
+	if !errors.As(err, &lockErr) {
+		t.Fatalf("Expected a LockError, got %v", err)
+	}
+	if lockErr.PID != os.Getpid() {
+		t.Errorf("Expected the lock to be held by process %d, got %d", os.Getpid(), lockErr.PID)
+	}
+	if !strings.Contains(err.Error(), fmt.Sprintf("process %d", os.Getpid())) {
+		t.Errorf("Expected the error to name the holder, got %q", err)
+	}
+}
+
+// exitedPID returns the PID of a process that has exited.
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to run a process: %v", err)
+	}
+	return cmd.ProcessState.Pid()
+}
+
+// thisHost returns the hostname of this host.
+func thisHost(t *testing.T) string {
+	t.Helper()
+	host, err := os.Hostname()
+	if err != nil {
+		t.Fatalf("Failed to get hostname: %v", err)
+	}
+	return host
+}
+
+// setLockHolder records pid on host as the holder of the lock on the store
+// file, as if the system had lost track of a lock left by that process.
+func setLockHolder(t *testing.T, filename string, pid int, host string) {
+	t.Helper()
+	if err := os.WriteFile(filename+".lock", []byte(strconv.Itoa(pid)+"\n"+host+"\n"), 0666); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+}
+
+func TestForceUnlock_RefusesLiveHolder(t *testing.T) {
+	s, filename := createTempStore(t)
+	id := mustAdd(t, s, testBookmark())
+
+	lock, err := store.ForceUnlock(filename)
+	var lockErr *store.LockError
+	if !errors.As(err, &lockErr) || lockErr.PID != os.Getpid() {
+		t.Fatalf("Expected a LockError naming process %d, got %v", os.Getpid(), err)
+	}
+	if lock != nil {
+		t.Errorf("Expected no lock to be broken, got %+v", lock)
+	}
+
+	// The lock still keeps other processes out
+	if _, err := store.NewStore(filename); !errors.Is(err, internal.ErrStoreLocked) {
+		t.Fatalf("Expected ErrStoreLocked after the refused ForceUnlock, got %v", err)
+	}
+	s2 := reloadStore(t, s, filename)
+	if _, err := s2.Get(id); err != nil {
+		t.Errorf("Expected bookmark %d: %v", id, err)
+	}
+}
+
+func TestForceUnlock_ExitedHolder(t *testing.T) {
+	s, filename := createTempStore(t)
+	id := mustAdd(t, s, testBookmark())
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	pid := exitedPID(t)
+	setLockHolder(t, filename, pid, thisHost(t))
+
+	lock, err := store.ForceUnlock(filename)
+	if err != nil {
+		t.Fatalf("ForceUnlock failed: %v", err)
+	}
+	if lock == nil || lock.PID != pid || lock.Host != thisHost(t) {
+		t.Fatalf("Expected the broken lock to name process %d, got %+v", pid, lock)
+	}
+
+	s2, err := store.NewStore(filename)
+	if err != nil {
+		t.Fatalf("Expected NewStore to succeed after ForceUnlock: %v", err)
+	}
+	if _, err := s2.Get(id); err != nil {
+		t.Errorf("Expected bookmark %d: %v", id, err)
+	}
+	s2.Close()
+
+	// Nothing to break once the store is closed
+	lock, err = store.ForceUnlock(filename)
+	if err != nil {
+		t.Fatalf("ForceUnlock failed: %v", err)
+	}
+	if lock != nil {
+		t.Errorf("Expected no lock to break, got %+v", lock)
+	}
+}
+
+func TestForceUnlock_RefusesOtherHost(t *testing.T) {
+	_, filename := createTempStore(t)
+	pid := exitedPID(t)
+
+	// The process only exited on this host; on another, or on an unknown one
+	// as in older lock files, it may still be running
+	for _, host := range []string{thisHost(t) + "-other", ""} {
+		setLockHolder(t, filename, pid, host)
+
+		lock, err := store.ForceUnlock(filename)
+		var lockErr *store.LockError
+		if !errors.As(err, &lockErr) || lockErr.PID != pid || lockErr.Host != host {
+			t.Fatalf("Expected a LockError naming process %d on host %q, got %v", pid, host, err)
+		}
+		if lock != nil {
+			t.Errorf("Expected no lock to be broken, got %+v", lock)
+		}
+		if _, err := os.Stat(filename + ".lock"); err != nil {
+			t.Errorf("Expected the lock file to be kept: %v", err)
+		}
+	}
+}
+
+func TestUsers_ForceUnlock(t *testing.T) {
+	users, err := store.OpenUsers(filepath.Join(t.TempDir(), "users.json"))
+	if err != nil {
+		t.Fatalf("OpenUsers failed: %v", err)
+	}
+	t.Cleanup(func() { users.Close() })
+	for _, username := range []string{"alice", "bob"} {
+		if err := users.Create(username, "correct horse"); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if _, err := users.Namespace(username); err != nil {
+			t.Fatalf("Namespace failed: %v", err)
+		}
+	}
+	pid := exitedPID(t)
+	setLockHolder(t, users.NamespaceFile("alice"), pid, thisHost(t))
+
+	// Alice's lock names an exited process; bob's is held by this one
+	broken, err := users.ForceUnlock()
+	if !errors.Is(err, internal.ErrStoreLocked) || !strings.Contains(err.Error(), "bob.json") {
+		t.Errorf("Expected the lock on bob's namespace to be refused, got %v", err)
+	}
+	if len(broken) != 1 || broken[0].PID != pid || !strings.HasSuffix(broken[0].FileName, "alice.json") {
+		t.Errorf("Expected the lock on alice's namespace to be broken, got %v", broken)
+	}
+	if _, err := os.Stat(users.NamespaceFile("bob") + ".lock"); err != nil {
+		t.Errorf("Expected the lock file of bob's namespace to be kept: %v", err)
+	}
+}
+
+func TestLock_ReleasedWhenOpenFails(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bookmarks")
+	if err := os.WriteFile(filename, []byte("not json"), 0666); err != nil {
+		t.Fatalf("Failed to write store file: %v", err)
+	}
+
+	if _, err := store.NewStore(filename); err == nil {
+		t.Fatal("Expected an error opening a corrupt store file")
+	}
+
+	// Once the file is fixed, opening it must not find a stale lock
+	if err := os.WriteFile(filename, nil, 0666); err != nil {
+		t.Fatalf("Failed to truncate store file: %v", err)
+	}
+	s, err := store.NewStore(filename)
+	if err != nil {
+		t.Fatalf("Expected the lock to be released after the failed open: %v", err)
+	}
+	s.Close()
+}
+
+// Snapshot Schema Tests
+
+// legacySnapshot is a snapshot as written before the schema was versioned,
+// with one bookmark from before revisions were tracked.
+const legacySnapshot = `{"bookmarks":{"1":{"url":"https://example.com","name":"Old","description":"","tags":["a"],"created_at":1700000000,"updated_at":1700000000},"2":{"url":"https://example.org","name":"Newer","description":"","tags":null,"created_at":1700000001,"updated_at":1700000001,"revision":3}},"idx_counter":2,"generation":5}`
+
+func writeLegacySnapshot(t *testing.T) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "bookmarks.json")
+	if err := os.WriteFile(filename, []byte(legacySnapshot), 0666); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+	return filename
+}
+
+func TestSnapshot_WritesSchemaHeader(t *testing.T) {
+	s, filename := createTempStore(t)
+	mustAdd(t, s, testBookmark())
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	var header struct {
+		Format        string `json:"format"`
+		SchemaVersion int    `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		t.Fatalf("Failed to decode snapshot: %v", err)
+	}
+	if header.Format != "fave-store" || header.SchemaVersion != store.SchemaVersion {
+		t.Errorf("Expected format fave-store at schema version %d, got %+v", store.SchemaVersion, header)
+	}
+}
+
+func TestMigrate_LegacySnapshotOnLoad(t *testing.T) {
+	filename := writeLegacySnapshot(t)
+
+	s, err := store.NewStore(filename)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer s.Close()
+
+	old, err := s.Get(1)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if old.Revision != 1 {
+		t.Errorf("Expected the bookmark without a revision to get revision 1, got %d", old.Revision)
+	}
+	newer, err := s.Get(2)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if newer.Revision != 3 {
+		t.Errorf("Expected revision 3 to be kept, got %d", newer.Revision)
+	}
+	if s.CurrentGeneration() != 5 {
+		t.Errorf("Expected generation 5, got %d", s.CurrentGeneration())
+	}
+
+	// The original is kept, and the file is rewritten at the current version
+	backup, err := os.ReadFile(filename + ".v0.bak")
+	if err != nil {
+		t.Fatalf("Expected a backup of the original: %v", err)
+	}
+	if string(backup) != legacySnapshot {
+		t.Errorf("Expected the backup to match the original, got %s", backup)
+	}
+	report, err := store.MigrateFile(filename, true)
+	if err != nil {
+		t.Fatalf("MigrateFile failed: %v", err)
+	}
+	if report.Migrated() || report.FromVersion != store.SchemaVersion {
+		t.Errorf("Expected the file to be up to date, got %+v", report)
+	}
+}
+
+func TestMigrateFile_DryRun(t *testing.T) {
+	filename := writeLegacySnapshot(t)
+
+	report, err := store.MigrateFile(filename, true)
+	if err != nil {
+		t.Fatalf("MigrateFile failed: %v", err)
+	}
+	if report.FromVersion != 0 || report.ToVersion != store.SchemaVersion || len(report.Steps) != store.SchemaVersion {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if len(report.Steps) > 0 && !strings.Contains(report.Steps[0].Changes, "1 of 2 bookmarks") {
+		t.Errorf("Expected the step to count the changed bookmarks, got %q", report.Steps[0].Changes)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	if string(data) != legacySnapshot {
+		t.Error("Expected a dry run to leave the file unchanged")
+	}
+	if _, err := os.Stat(filename + ".v0.bak"); !os.IsNotExist(err) {
+		t.Error("Expected a dry run to write no backup")
+	}
+}
+
+func TestMigrateFile_LockedByStore(t *testing.T) {
+	filename := writeLegacySnapshot(t)
+	s, err := store.NewStore(filename)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := store.MigrateFile(filename, false); !errors.Is(err, internal.ErrStoreLocked) {
+		t.Errorf("Expected ErrStoreLocked while the store is open, got %v", err)
+	}
+}
+
+func TestMigrate_NewerSchemaRejected(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bookmarks.json")
+	newer := fmt.Sprintf(`{"format":"fave-store","schema_version":%d,"bookmarks":{}}`, store.SchemaVersion+1)
+	if err := os.WriteFile(filename, []byte(newer), 0666); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	if _, err := store.NewStore(filename); err == nil || !strings.Contains(err.Error(), "schema version") {
+		t.Fatalf("Expected a snapshot from a newer version to be refused, got %v", err)
+	}
+	data, _ := os.ReadFile(filename)
+	if string(data) != newer {
+		t.Error("Expected the newer snapshot to be left unchanged")
+	}
+}
+
+func TestMigrate_LegacySnapshotToBTree(t *testing.T) {
+	filename := writeLegacySnapshot(t)
+
+	s, err := store.NewStoreWithEngine(filename, store.EngineBTree)
+	if err != nil {
+		t.Fatalf("NewStoreWithEngine failed: %v", err)
+	}
+	defer s.Close()
+
+	bookmark, err := s.Get(1)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if bookmark.Revision != 1 {
+		t.Errorf("Expected revision 1 after converting a legacy snapshot, got %d", bookmark.Revision)
+	}
+}
+
+// StoreInterface Conformance Tests
+
+func TestStoreInterface(t *testing.T) {
+	for _, engine := range []string{store.EngineJSON, store.EngineBTree} {
+		t.Run(engine, func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T, dir string) server.StoreInterface {
+				s, err := store.NewStoreWithEngine(filepath.Join(dir, "bookmarks"), engine)
+				if err != nil {
+					t.Fatalf("Failed to open store: %v", err)
+				}
+				return s
+			})
+		})
+	}
+}
+
+// B-tree Storage Engine Tests
+
+// openBTreeStore opens a store using the B-tree engine and closes it when the test ends.
+func openBTreeStore(t *testing.T, filename string) *store.Store {
+	t.Helper()
+	s, err := store.NewStoreWithEngine(filename, store.EngineBTree)
+	if err != nil {
+		t.Fatalf("Failed to open B-tree store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestBTree_ReloadAfterMutations(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bookmarks.db")
+	s := openBTreeStore(t, filename)
+
+	// Enough bookmarks for a tree several levels deep, saved over several snapshots
+	want := make(map[int]internal.Bookmark)
+	for i := range 3000 {
+		bookmark := testBookmark(func(b *internal.Bookmark) {
+			b.Name = fmt.Sprintf("Bookmark %d", i)
+			b.Description = strings.Repeat("x", i%200)
+		})
+		id := mustAdd(t, s, bookmark)
+		bookmark.Revision = 1
+		want[id] = bookmark
+
+		if i%1000 == 999 {
+			if err := s.SaveSnapshot(); err != nil {
+				t.Fatalf("SaveSnapshot failed: %v", err)
+			}
+		}
+	}
+
+	for id := range want {
+		switch {
+		case id%3 == 0:
+			if err := s.Delete(id); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			delete(want, id)
+		case id%5 == 0:
+			bookmark := want[id]
+			bookmark.Name = "Updated"
+			if err := s.Update(id, bookmark); err != nil {
+				t.Fatalf("Update failed: %v", err)
+			}
+			bookmark.Revision = 2
+			want[id] = bookmark
+		}
+	}
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	generation := s.CurrentGeneration()
+	s.Close()
+
+	// Remove the log, so everything has to come from the B-tree file
+	os.Remove(filename + ".wal")
+	s2 := openBTreeStore(t, filename)
+
+	got := s2.List()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d bookmarks after reload, got %d", len(want), len(got))
+	}
+	for id, bookmark := range want {
+		if got[id].Name != bookmark.Name || got[id].Description != bookmark.Description || got[id].Revision != bookmark.Revision {
+			t.Fatalf("Bookmark %d: expected %+v, got %+v", id, bookmark, got[id])
+		}
+	}
+	if gen, _ := s2.Persisted(); gen != generation || s2.CurrentGeneration() != generation {
+		t.Errorf("Expected generation %d after reload, got %d", generation, gen)
+	}
+	if id := mustAdd(t, s2, testBookmark()); id != 3001 {
+		t.Errorf("Expected next ID 3001 after reload, got %d", id)
+	}
+}
+
+func TestBTree_SnapshotWritesOnlyChanges(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bookmarks.db")
+	s := openBTreeStore(t, filename)
+
+	for range 5000 {
+		mustAdd(t, s, testBookmark())
+	}
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	initial, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+
+	// Each snapshot rewrites one path through the tree, reusing the pages
+	// released by the snapshot before, so the file barely grows.
+	for i := range 20 {
+		if err := s.Update(i*250+1, testBookmark(func(b *internal.Bookmark) { b.Name = "Changed" })); err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+		if err := s.SaveSnapshot(); err != nil {
+			t.Fatalf("SaveSnapshot failed: %v", err)
+		}
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if growth := info.Size() - initial.Size(); growth > 8*4096 {
+		t.Errorf("Expected small snapshots to reuse pages, but the file grew from %d to %d bytes", initial.Size(), info.Size())
+	}
+}
+
+func TestBTree_LargeBookmark(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bookmarks.db")
+	s := openBTreeStore(t, filename)
+
+	large := testBookmark(func(b *internal.Bookmark) { b.Description = strings.Repeat("long ", 5000) })
+	mustAdd(t, s, testBookmark())
+	id := mustAdd(t, s, large)
+	mustAdd(t, s, testBookmark())
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	s.Close()
+	os.Remove(filename + ".wal")
+
+	result, err := openBTreeStore(t, filename).Get(id)
+	if err != nil {
+		t.Fatalf("Get failed after reload: %v", err)
+	}
+	assertBookmarkEqual(t, large, result)
+}
+
+func TestBTree_MigratesJSONSnapshot(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bookmarks.json")
+
+	s, err := store.NewStore(filename)
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	first := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Snapshot" }))
+	if err := s.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	second := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Log" }))
+	s.Close()
+
+	migrated := openBTreeStore(t, filename)
+	for id, name := range map[int]string{first: "Snapshot", second: "Log"} {
+		bookmark, err := migrated.Get(id)
+		if err != nil || bookmark.Name != name {
+			t.Errorf("Expected bookmark %d named %q after migration, got %+v, %v", id, name, bookmark, err)
+		}
+	}
+
+	backup, err := os.ReadFile(filename + ".bak")
+	if err != nil {
+		t.Fatalf("Expected a backup of the JSON snapshot: %v", err)
+	}
+	if !json.Valid(backup) {
+		t.Errorf("Expected the backup to be the JSON snapshot, got %q", backup)
+	}
+
+	if err := migrated.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	migrated.Close()
+
+	if _, err := store.NewStore(filename); err == nil || !strings.Contains(err.Error(), store.EngineBTree) {
+		t.Errorf("Expected the JSON engine to refuse a B-tree file, got %v", err)
+	}
+}
+
+func TestBTree_TornMetaPageFallsBack(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bookmarks.db")
+	s := openBTreeStore(t, filename)
+
+	mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "First" }))
+	if err := s.SaveSnapshot(); err != nil { // Transaction 1, meta page 1
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Second" }))
+	if err := s.SaveSnapshot(); err != nil { // Transaction 2, meta page 0
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	s.Close()
+
+	// Tear the meta page of the second transaction
+	f, err := os.OpenFile(filename, os.O_WRONLY, 0666)
+	if err != nil {
+		t.Fatalf("Failed to open file: %v", err)
+	}
+	f.WriteAt([]byte("torn"), 20)
+	f.Close()
+
+	s2 := openBTreeStore(t, filename)
+	if got := s2.List(); len(got) != 1 || got[1].Name != "First" {
+		t.Errorf("Expected the first snapshot after a torn meta page, got %v", got)
+	}
+
+	// Both meta pages torn is an error
+	s2.Close()
+	f, _ = os.OpenFile(filename, os.O_WRONLY, 0666)
+	f.WriteAt([]byte("torn"), 4096+20)
+	f.Close()
+	if _, err := store.NewStoreWithEngine(filename, store.EngineBTree); err == nil {
+		t.Error("Expected error when both meta pages are torn, got nil")
 	}
 }
 
@@ -646,8 +2476,8 @@ func TestConcurrent_MultipleReads(t *testing.T) {
 	s, _ := createTempStore(t)
 
 	// Add some test data
-	id1 := s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "First" }))
-	id2 := s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "Second" }))
+	id1 := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "First" }))
+	id2 := mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Second" }))
 
 	var wg sync.WaitGroup
 	errors := make(chan error, 100)
@@ -690,7 +2520,7 @@ func TestConcurrent_ReadWrite(t *testing.T) {
 	s, _ := createTempStore(t)
 
 	// Add initial data
-	id := s.Add(testBookmark())
+	id := mustAdd(t, s, testBookmark())
 
 	var wg sync.WaitGroup
 	errors := make(chan error, 100)
@@ -749,9 +2579,13 @@ func TestConcurrent_MultipleAdds(t *testing.T) {
 		go func(n int) {
 			defer wg.Done()
 			for j := 0; j < 10; j++ {
-				id := s.Add(testBookmark(func(b *internal.Bookmark) {
+				id, err := s.Add(testBookmark(func(b *internal.Bookmark) {
 					b.Name = fmt.Sprintf("Worker %d Bookmark %d", n, j)
 				}))
+				if err != nil {
+					errors <- err
+					return
+				}
 				ids <- id
 			}
 		}(i)
@@ -783,7 +2617,7 @@ func TestConcurrent_MultipleAdds(t *testing.T) {
 func TestConcurrent_MultipleUpdates(t *testing.T) {
 	s, _ := createTempStore(t)
 
-	id := s.Add(testBookmark())
+	id := mustAdd(t, s, testBookmark())
 
 	var wg sync.WaitGroup
 	errors := make(chan error, 50)
@@ -829,9 +2663,9 @@ func TestConcurrent_AddUpdateDelete(t *testing.T) {
 
 	// Seed with some data
 	initialIDs := []int{
-		s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "Initial 1" })),
-		s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "Initial 2" })),
-		s.Add(testBookmark(func(b *internal.Bookmark) { b.Name = "Initial 3" })),
+		mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Initial 1" })),
+		mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Initial 2" })),
+		mustAdd(t, s, testBookmark(func(b *internal.Bookmark) { b.Name = "Initial 3" })),
 	}
 
 	var wg sync.WaitGroup
@@ -843,9 +2677,13 @@ func TestConcurrent_AddUpdateDelete(t *testing.T) {
 		go func(n int) {
 			defer wg.Done()
 			for j := 0; j < 10; j++ {
-				s.Add(testBookmark(func(b *internal.Bookmark) {
+				_, err := s.Add(testBookmark(func(b *internal.Bookmark) {
 					b.Name = fmt.Sprintf("Adder %d Item %d", n, j)
 				}))
+				if err != nil {
+					errors <- err
+					return
+				}
 			}
 		}(i)
 	}
@@ -900,7 +2738,7 @@ func TestBookmarkWithEmptyFields(t *testing.T) {
 		Tags:        []string{},
 	}
 
-	id := s.Add(bookmark)
+	id := mustAdd(t, s, bookmark)
 	result, err := s.Get(id)
 	if err != nil {
 		t.Fatalf("Get failed: %v", err)
@@ -911,7 +2749,7 @@ func TestBookmarkWithEmptyFields(t *testing.T) {
 	// Verify persistence with empty fields
 	s.SaveSnapshot()
 
-	s2 := reloadStore(t, filename)
+	s2 := reloadStore(t, s, filename)
 	result2, err := s2.Get(id)
 	if err != nil {
 		t.Fatalf("Get failed after reload: %v", err)
@@ -927,7 +2765,7 @@ func TestLargeDataset(t *testing.T) {
 
 	// Add 1000 bookmarks
 	for i := 0; i < count; i++ {
-		s.Add(testBookmark(func(b *internal.Bookmark) {
+		mustAdd(t, s, testBookmark(func(b *internal.Bookmark) {
 			b.Name = fmt.Sprintf("Bookmark %d", i)
 			b.Url = fmt.Sprintf("https://example.com/bookmark/%d", i)
 		}))
@@ -945,7 +2783,7 @@ func TestLargeDataset(t *testing.T) {
 		t.Fatalf("SaveSnapshot failed: %v", err)
 	}
 
-	s2 := reloadStore(t, filename)
+	s2 := reloadStore(t, s, filename)
 	bookmarks2 := s2.List()
 	if len(bookmarks2) != count {
 		t.Fatalf("Expected %d bookmarks after reload, got %d", count, len(bookmarks2))
@@ -962,7 +2800,7 @@ func TestAdd_CounterPersistence(t *testing.T) {
 
 	// Add 5 bookmarks
 	for i := 0; i < 5; i++ {
-		id := s.Add(testBookmark())
+		id := mustAdd(t, s, testBookmark())
 		if id != i+1 {
 			t.Errorf("Expected ID %d, got %d", i+1, id)
 		}
@@ -971,10 +2809,10 @@ func TestAdd_CounterPersistence(t *testing.T) {
 	s.SaveSnapshot()
 
 	// Reload
-	s2 := reloadStore(t, filename)
+	s2 := reloadStore(t, s, filename)
 
 	// Next ID should be 6
-	nextID := s2.Add(testBookmark())
+	nextID := mustAdd(t, s2, testBookmark())
 	if nextID != 6 {
 		t.Errorf("Expected next ID to be 6, got %d", nextID)
 	}
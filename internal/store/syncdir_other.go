@@ -0,0 +1,9 @@
+//go:build !unix
+
+package store
+
+// syncDir does nothing on platforms where directories cannot be synced; their
+// file systems persist renames with the file.
+func syncDir(dir string) error {
+	return nil
+}
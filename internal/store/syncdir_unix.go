@@ -0,0 +1,17 @@
+//go:build unix
+
+package store
+
+import "os"
+
+// syncDir flushes the entries of the directory at dir to stable storage, so a
+// file renamed or created in it is still there after a power loss.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,222 @@
+package store
+
+import (
+	"cmp"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// tokenPrefix starts every token, so leaked tokens are easy to recognize.
+const tokenPrefix = "fave_"
+
+// storedToken is a token as kept in the tokens file, with the hash of its secret.
+type storedToken struct {
+	internal.Token
+	Hash string `json:"hash"`
+}
+
+// tokensFile is the layout of the tokens file.
+type tokensFile struct {
+	Tokens []storedToken `json:"tokens"`
+}
+
+// Tokens keeps API tokens in a file. Only a SHA-256 hash of each token is
+// stored; tokens are random, so a hash is enough to make a stolen file useless.
+//
+// Creating and revoking tokens is written to the file immediately. Last-used
+// times change on every request, so they are only written by SaveSnapshot.
+type Tokens struct {
+	fileName string
+	tokens   map[string]*storedToken // Keyed by hash
+	dirty    bool                    // Last-used times changed since the file was written
+
+	mutex sync.Mutex
+}
+
+// OpenTokens loads the tokens kept in the file at `fileName`.
+// If the file does not exist, there are no tokens; it is created when the first token is.
+func OpenTokens(fileName string) (*Tokens, error) {
+	t := &Tokens{
+		fileName: fileName,
+		tokens:   make(map[string]*storedToken),
+	}
+
+	data, err := os.ReadFile(fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file tokensFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	for i := range file.Tokens {
+		t.tokens[file.Tokens[i].Hash] = &file.Tokens[i]
+	}
+
+	return t, nil
+}
+
+// Create issues a new token for owner with the given scopes, expiring at the
+// Unix time expiresAt (0 for never). It returns the token and its secret,
+// which cannot be retrieved again.
+func (t *Tokens) Create(owner, name string, scopes []string, expiresAt int64) (internal.Token, string, error) {
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return internal.Token{}, "", err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return internal.Token{}, "", err
+	}
+	secret = tokenPrefix + secret
+
+	token := internal.Token{
+		ID:        id,
+		Name:      name,
+		Owner:     owner,
+		Scopes:    slices.Clone(scopes),
+		CreatedAt: time.Now().Unix(),
+		ExpiresAt: expiresAt,
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	hash := hashToken(secret)
+	t.tokens[hash] = &storedToken{Token: token, Hash: hash}
+	if err := t.save(); err != nil {
+		delete(t.tokens, hash)
+		return internal.Token{}, "", err
+	}
+
+	return token, secret, nil
+}
+
+// List returns the tokens of owner, oldest first.
+func (t *Tokens) List(owner string) []internal.Token {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	tokens := []internal.Token{}
+	for _, stored := range t.tokens {
+		if stored.Owner == owner {
+			tokens = append(tokens, stored.Token)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b internal.Token) int {
+		return cmp.Or(cmp.Compare(a.CreatedAt, b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	return tokens
+}
+
+// Revoke deletes the token of owner with the given ID.
+// It returns internal.ErrTokenNotFound if owner has no such token.
+func (t *Tokens) Revoke(owner, id string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for hash, stored := range t.tokens {
+		if stored.Owner == owner && stored.ID == id {
+			delete(t.tokens, hash)
+			if err := t.save(); err != nil {
+				t.tokens[hash] = stored
+				return err
+			}
+			return nil
+		}
+	}
+
+	return internal.ErrTokenNotFound
+}
+
+// Authenticate returns the token matching a secret and records that it was used.
+// It returns internal.ErrInvalidToken if no token matches, and
+// internal.ErrTokenExpired if the token has expired.
+func (t *Tokens) Authenticate(secret string) (internal.Token, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	stored, ok := t.tokens[hashToken(secret)]
+	if !ok {
+		return internal.Token{}, internal.ErrInvalidToken
+	}
+
+	now := time.Now()
+	if stored.Expired(now) {
+		return internal.Token{}, internal.ErrTokenExpired
+	}
+	if stored.LastUsedAt != now.Unix() {
+		stored.LastUsedAt = now.Unix()
+		t.dirty = true
+	}
+
+	return stored.Token, nil
+}
+
+// SaveSnapshot writes last-used times that changed since the file was last written.
+func (t *Tokens) SaveSnapshot() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.dirty {
+		return nil
+	}
+	return t.save()
+}
+
+// save atomically writes all tokens to the tokens file.
+// The caller must hold the lock.
+func (t *Tokens) save() error {
+	file := tokensFile{Tokens: make([]storedToken, 0, len(t.tokens))}
+	for _, stored := range t.tokens {
+		file.Tokens = append(file.Tokens, *stored)
+	}
+	slices.SortFunc(file.Tokens, func(a, b storedToken) int {
+		return cmp.Or(cmp.Compare(a.CreatedAt, b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	b, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.fileName), 0755); err != nil {
+		return err
+	}
+	if err := replaceFile(t.fileName, "tokens-*.json", b); err != nil {
+		return err
+	}
+
+	t.dirty = false
+	return nil
+}
+
+// hashToken returns the hex-encoded SHA-256 hash of a token secret.
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString encodes n random bytes.
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
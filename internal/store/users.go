@@ -0,0 +1,400 @@
+package store
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// usernamePattern restricts usernames to characters that are safe in file names
+// on every platform, since each one names the file of its namespace.
+var usernamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,63}$`)
+
+// Users keeps user accounts, with bcrypt password hashes, in an accounts file
+// and gives every user a bookmark namespace: a Store of their own, in a
+// directory named after the accounts file. For users.json, the bookmarks of
+// alice are kept in users/alice.json.
+//
+// The accounts file is read again whenever it changes, so accounts managed by
+// another process, such as the fave user command, take effect immediately.
+// Changes hold a lock on the accounts file from reading it to saving it, so
+// two processes changing accounts at once do not overwrite each other.
+type Users struct {
+	fileName string
+	accounts map[string]internal.User
+	stores   map[string]*Store
+
+	// Modification time and size of the accounts file when it was last read
+	modTime time.Time
+	size    int64
+
+	mutex sync.Mutex
+}
+
+// usersFile is the layout of the accounts file.
+type usersFile struct {
+	Users map[string]internal.User `json:"users"`
+}
+
+// OpenUsers loads the user accounts kept in the file at `fileName`.
+// If the file does not exist, there are no accounts; it is created when the first user is.
+func OpenUsers(fileName string) (*Users, error) {
+	u := &Users{
+		fileName: fileName,
+		accounts: make(map[string]internal.User),
+		stores:   make(map[string]*Store),
+	}
+
+	if err := u.reload(); err != nil {
+		return nil, err
+	}
+
+	return u, nil
+}
+
+// Close closes the namespaces of all users.
+// It does not save snapshots; call SaveSnapshot first.
+func (u *Users) Close() error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	var errs []error
+	for username, store := range u.stores {
+		errs = append(errs, store.Close())
+		delete(u.stores, username)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Create adds a user account with the given password.
+// It returns internal.ErrUserExists if the username is taken.
+func (u *Users) Create(username, password string) error {
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("invalid username %q: use up to 64 lowercase letters, digits, '.', '_', and '-'", username)
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	lock, err := u.lockFile()
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	if err := u.reload(); err != nil {
+		return err
+	}
+	if _, exists := u.accounts[username]; exists {
+		return internal.ErrUserExists
+	}
+
+	now := time.Now().Unix()
+	u.accounts[username] = internal.User{
+		Username:     username,
+		PasswordHash: hash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	return u.save()
+}
+
+// SetPassword replaces the password of a user account.
+// It returns internal.ErrUserNotFound if the user does not exist.
+func (u *Users) SetPassword(username, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return u.modify(username, func(user *internal.User) {
+		user.PasswordHash = hash
+	})
+}
+
+// SetDisabled disables or re-enables a user account.
+// Disabled users cannot authenticate, but their bookmarks are kept.
+// It returns internal.ErrUserNotFound if the user does not exist.
+func (u *Users) SetDisabled(username string, disabled bool) error {
+	return u.modify(username, func(user *internal.User) {
+		user.Disabled = disabled
+	})
+}
+
+func (u *Users) modify(username string, fn func(*internal.User)) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	lock, err := u.lockFile()
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	if err := u.reload(); err != nil {
+		return err
+	}
+	user, exists := u.accounts[username]
+	if !exists {
+		return internal.ErrUserNotFound
+	}
+
+	fn(&user)
+	user.UpdatedAt = time.Now().Unix()
+	u.accounts[username] = user
+
+	return u.save()
+}
+
+// List returns all user accounts, ordered by username.
+func (u *Users) List() ([]internal.User, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if err := u.reload(); err != nil {
+		return nil, err
+	}
+
+	users := make([]internal.User, 0, len(u.accounts))
+	for _, user := range u.accounts {
+		users = append(users, user)
+	}
+	slices.SortFunc(users, func(a, b internal.User) int {
+		return cmp.Compare(a.Username, b.Username)
+	})
+
+	return users, nil
+}
+
+// Lookup returns the account of a user.
+// It returns internal.ErrUserNotFound if the user does not exist.
+func (u *Users) Lookup(username string) (internal.User, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if err := u.reload(); err != nil {
+		return internal.User{}, err
+	}
+	user, exists := u.accounts[username]
+	if !exists {
+		return internal.User{}, internal.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// unknownUserHash is checked against the password given for a username with no
+// account, and the result ignored. It is a bcrypt hash at the cost that
+// hashPassword uses, so the check takes as long as for an account.
+const unknownUserHash = "$2a$10$oXEJj1VpqkMuhTaRY69YtuLsSXUajiN0PnuKsSMypBsW270uXF2iy"
+
+// Authenticate checks a username and password against the accounts.
+// It returns internal.ErrInvalidCredentials if they do not match an account,
+// and internal.ErrUserDisabled if they match a disabled one.
+func (u *Users) Authenticate(username, password string) (internal.User, error) {
+	u.mutex.Lock()
+	err := u.reload()
+	user, exists := u.accounts[username]
+	u.mutex.Unlock()
+
+	if err != nil {
+		return internal.User{}, err
+	}
+	if !exists {
+		// Take as long as checking a password of an account would, so the
+		// time taken does not give away which usernames exist
+		bcrypt.CompareHashAndPassword([]byte(unknownUserHash), []byte(password))
+		return internal.User{}, internal.ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return internal.User{}, internal.ErrInvalidCredentials
+	}
+	if user.Disabled {
+		return internal.User{}, internal.ErrUserDisabled
+	}
+
+	return user, nil
+}
+
+// Namespace returns the store holding the bookmarks of a user, opening it on first use.
+// It returns internal.ErrUserNotFound if the user does not exist.
+func (u *Users) Namespace(username string) (*Store, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if store, ok := u.stores[username]; ok {
+		return store, nil
+	}
+
+	if err := u.reload(); err != nil {
+		return nil, err
+	}
+	if _, exists := u.accounts[username]; !exists {
+		return nil, internal.ErrUserNotFound
+	}
+
+	dir := u.namespaceDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	store, err := NewStore(u.NamespaceFile(username))
+	if err != nil {
+		return nil, fmt.Errorf("opening bookmarks of %s: %w", username, err)
+	}
+	u.stores[username] = store
+
+	return store, nil
+}
+
+// SaveSnapshot saves a snapshot of every open namespace.
+func (u *Users) SaveSnapshot() error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	var errs []error
+	for username, store := range u.stores {
+		if err := store.SaveSnapshot(); err != nil {
+			errs = append(errs, fmt.Errorf("saving bookmarks of %s: %w", username, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// ForceUnlock breaks the locks on the namespaces of all users, as ForceUnlock
+// does for a single store, and returns the locks that were broken. Locks held
+// by live processes are left in place, and returned together as the error.
+func (u *Users) ForceUnlock() ([]*LockError, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	lockFiles, err := filepath.Glob(filepath.Join(u.namespaceDir(), "*.json.lock"))
+	if err != nil {
+		return nil, err
+	}
+
+	var broken []*LockError
+	var errs []error
+	for _, lockFile := range lockFiles {
+		held, err := ForceUnlock(strings.TrimSuffix(lockFile, ".lock"))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if held != nil {
+			broken = append(broken, held)
+		}
+	}
+
+	return broken, errors.Join(errs...)
+}
+
+// NamespaceFile returns the path of the store file holding the bookmarks of a
+// user, which does not exist until their namespace is first opened.
+func (u *Users) NamespaceFile(username string) string {
+	return filepath.Join(u.namespaceDir(), username+".json")
+}
+
+// namespaceDir returns the directory holding the namespaces of all users.
+func (u *Users) namespaceDir() string {
+	dir := strings.TrimSuffix(u.fileName, filepath.Ext(u.fileName))
+	if dir == u.fileName {
+		return dir + ".d"
+	}
+	return dir
+}
+
+// lockFile takes the lock on the accounts file, waiting for any other process
+// changing accounts, and creates the directory holding it if needed.
+func (u *Users) lockFile() (*fileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(u.fileName), 0755); err != nil {
+		return nil, err
+	}
+	return waitLock(u.fileName)
+}
+
+// reload reads the accounts file again if it changed since it was last read.
+// The caller must hold the lock.
+func (u *Users) reload() error {
+	info, err := os.Stat(u.fileName)
+	if errors.Is(err, os.ErrNotExist) {
+		clear(u.accounts)
+		u.modTime, u.size = time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(u.modTime) && info.Size() == u.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(u.fileName)
+	if err != nil {
+		return err
+	}
+	var file usersFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("reading users file: %w", err)
+	}
+	if file.Users == nil {
+		file.Users = make(map[string]internal.User)
+	}
+
+	u.accounts = file.Users
+	u.modTime, u.size = info.ModTime(), info.Size()
+
+	return nil
+}
+
+// save atomically writes the accounts to the accounts file.
+// The caller must hold the lock, and the lock on the accounts file.
+func (u *Users) save() error {
+	b, err := json.MarshalIndent(usersFile{Users: u.accounts}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := replaceFile(u.fileName, "users-*.json", b); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(u.fileName)
+	if err != nil {
+		return err
+	}
+	u.modTime, u.size = info.ModTime(), info.Size()
+
+	return nil
+}
+
+// hashPassword returns the bcrypt hash of a password.
+func hashPassword(password string) (string, error) {
+	if len(password) < internal.MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", internal.MinPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
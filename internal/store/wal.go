@@ -0,0 +1,177 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/t-eckert/fave/internal"
+)
+
+// Operations recorded in the write-ahead log.
+const (
+	opAdd    = "add"
+	opUpdate = "update"
+	opDelete = "delete"
+	opBatch  = "batch"
+)
+
+// walRecord is a single mutation appended to the write-ahead log.
+// A batch record carries several mutations in Records, so they are
+// acknowledged, and survive a crash, together.
+type walRecord struct {
+	Op         string             `json:"op"`
+	ID         int                `json:"id"`
+	Generation uint64             `json:"generation"`
+	Bookmark   *internal.Bookmark `json:"bookmark,omitempty"`
+	Records    []walRecord        `json:"records,omitempty"`
+}
+
+// wal is an append-only, fsynced log of mutations made since the last snapshot.
+// Every record is one JSON document terminated by a newline.
+type wal struct {
+	fileName string
+	file     *os.File
+
+	// broken is set when a failed append could not be cut off the log, which
+	// then fails every append rather than write records after a corrupt one.
+	broken error
+}
+
+// writeWAL writes b to the log file. It is replaced in tests to fail writes.
+var writeWAL = func(file *os.File, b []byte) (int, error) {
+	return file.Write(b)
+}
+
+// walFileName returns the path of the write-ahead log for a snapshot file.
+func walFileName(snapshotFileName string) string {
+	return snapshotFileName + ".wal"
+}
+
+// append writes the record to the end of the log and flushes it to stable storage.
+// The log file is opened lazily on the first append.
+// If the write or the flush fails, the record is cut off the log again: left
+// in place, a partly written record would be followed by the next one, and
+// replay would refuse the log as corrupt.
+func (w *wal) append(rec walRecord) error {
+	if w.broken != nil {
+		return w.broken
+	}
+	if w.file == nil {
+		file, err := os.OpenFile(w.fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return fmt.Errorf("opening wal: %w", err)
+		}
+		// The log may have just been created; its records are only durable
+		// once the directory entry is.
+		if err := syncDir(filepath.Dir(w.fileName)); err != nil {
+			file.Close()
+			return fmt.Errorf("opening wal: %w", err)
+		}
+		w.file = file
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	offset, err := w.file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("writing wal: %w", err)
+	}
+	if _, err := writeWAL(w.file, b); err != nil {
+		return w.rollback(offset, fmt.Errorf("writing wal: %w", err))
+	}
+	if err := w.file.Sync(); err != nil {
+		return w.rollback(offset, fmt.Errorf("syncing wal: %w", err))
+	}
+
+	return nil
+}
+
+// rollback cuts the log back to offset, where a failed append started, and
+// returns err. If it cannot, the log is marked broken.
+func (w *wal) rollback(offset int64, err error) error {
+	if truncErr := w.file.Truncate(offset); truncErr != nil {
+		w.broken = fmt.Errorf("wal holds a partly written record: %w", errors.Join(err, truncErr))
+		return w.broken
+	}
+	return err
+}
+
+// truncate discards every record in the log.
+// It is called once a snapshot containing those records has been saved.
+func (w *wal) truncate() error {
+	if w.file == nil {
+		// Nothing was appended by this process, but a previous one may have left records behind.
+		err := os.Truncate(w.fileName, 0)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	return w.file.Sync()
+}
+
+// close releases the log file handle.
+func (w *wal) close() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// replay reads every record in the log and passes it to apply in order.
+// A record without its trailing newline was never acknowledged, since the
+// fsync happens after the full line is written. Such a torn record, left behind
+// by a crash mid-write, is cut off the file so later appends start cleanly.
+func (w *wal) replay(apply func(walRecord)) error {
+	file, err := os.OpenFile(w.fileName, os.O_RDWR, 0666)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("opening wal: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("reading wal: %w", readErr)
+		}
+
+		if readErr == io.EOF {
+			if len(bytes.TrimSpace(line)) > 0 {
+				// Torn final record: the write was never acknowledged, drop it.
+				return file.Truncate(offset)
+			}
+			return nil
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			var rec walRecord
+			if err := json.Unmarshal(line, &rec); err != nil {
+				return fmt.Errorf("corrupt wal record at offset %d: %w", offset, err)
+			}
+			apply(rec)
+		}
+
+		offset += int64(len(line))
+	}
+}
@@ -0,0 +1,676 @@
+// Package storetest checks that implementations of server.StoreInterface
+// behave as the server expects.
+//
+// A test of an implementation passes a Factory to Run:
+//
+//	func TestStoreInterface(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T, dir string) server.StoreInterface {
+//			s, err := mystore.Open(filepath.Join(dir, "bookmarks"))
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return s
+//		})
+//	}
+//
+// Run it with -race to check that the store is safe for concurrent use.
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/t-eckert/fave/internal"
+	"github.com/t-eckert/fave/internal/server"
+)
+
+// Factory opens the store kept in dir, creating an empty one if there is none.
+// It is called with a new, empty directory for every test, and again with the
+// same directory to check that a store reloads what it recorded.
+// If the store implements io.Closer, it is closed before it is opened again
+// and when the test ends.
+type Factory func(t *testing.T, dir string) server.StoreInterface
+
+// Run checks the whole StoreInterface contract against the stores made by open.
+func Run(t *testing.T, open Factory) {
+	t.Helper()
+	run(t, open, true)
+}
+
+// RunInMemory is Run for stores that keep nothing on disk. It skips the
+// checks that reopen a store.
+func RunInMemory(t *testing.T, open Factory) {
+	t.Helper()
+	run(t, open, false)
+}
+
+// suite opens the stores of one test.
+type suite struct {
+	open Factory
+	dir  string
+}
+
+func run(t *testing.T, open Factory, persistent bool) {
+	tests := []struct {
+		name       string
+		test       func(*testing.T, *suite)
+		persistent bool
+	}{
+		{"AddAndGet", testAddAndGet, false},
+		{"NotFound", testNotFound, false},
+		{"Update", testUpdate, false},
+		{"Delete", testDelete, false},
+		{"Revisions", testRevisions, false},
+		{"IDsIncrease", testIDsIncrease, false},
+		{"ListReturnsCopy", testListReturnsCopy, false},
+		{"Query", testQuery, false},
+		{"Search", testSearch, false},
+		{"Batch", testBatch, false},
+		{"AtomicBatch", testAtomicBatch, false},
+		{"Subscribe", testSubscribe, false},
+		{"Generations", testGenerations, false},
+		{"Concurrent", testConcurrent, false},
+		{"ReloadSnapshot", testReloadSnapshot, true},
+		{"ReloadWithoutSnapshot", testReloadWithoutSnapshot, true},
+		{"IDsIncreaseAcrossReload", testIDsIncreaseAcrossReload, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.persistent && !persistent {
+				t.Skip("store does not persist")
+			}
+			tt.test(t, &suite{open: open, dir: t.TempDir()})
+		})
+	}
+}
+
+// store opens the store of the test, closing it when the test ends.
+func (s *suite) store(t *testing.T) server.StoreInterface {
+	t.Helper()
+
+	store := s.open(t, s.dir)
+	if closer, ok := store.(io.Closer); ok {
+		var once sync.Once
+		t.Cleanup(func() { once.Do(func() { closer.Close() }) })
+	}
+	return store
+}
+
+// reopen closes a store and opens it again from disk.
+func (s *suite) reopen(t *testing.T, store server.StoreInterface) server.StoreInterface {
+	t.Helper()
+
+	if closer, ok := store.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+	}
+	return s.store(t)
+}
+
+func bookmark(name string) internal.Bookmark {
+	return internal.Bookmark{
+		Url:         "https://example.com/" + name,
+		Name:        name,
+		Description: "Bookmark " + name,
+		Tags:        []string{"storetest"},
+		CreatedAt:   1700000000,
+		UpdatedAt:   1700000000,
+	}
+}
+
+func mustAdd(t *testing.T, store server.StoreInterface, b internal.Bookmark) int {
+	t.Helper()
+	id, err := store.Add(b)
+	if err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	return id
+}
+
+func mustGet(t *testing.T, store server.StoreInterface, id int) internal.Bookmark {
+	t.Helper()
+	b, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Get(%d) failed: %v", id, err)
+	}
+	return b
+}
+
+// assertSame checks that a stored bookmark has the fields it was given.
+func assertSame(t *testing.T, want, got internal.Bookmark) {
+	t.Helper()
+	if got.Url != want.Url || got.Name != want.Name || got.Description != want.Description ||
+		!slices.Equal(got.Tags, want.Tags) || got.CreatedAt != want.CreatedAt || got.UpdatedAt != want.UpdatedAt {
+		t.Errorf("Expected bookmark %+v, got %+v", want, got)
+	}
+}
+
+func testAddAndGet(t *testing.T, s *suite) {
+	store := s.store(t)
+
+	want := bookmark("first")
+	id := mustAdd(t, store, want)
+	if id <= 0 {
+		t.Errorf("Expected a positive ID, got %d", id)
+	}
+
+	got := mustGet(t, store, id)
+	assertSame(t, want, got)
+	if got.Revision != 1 {
+		t.Errorf("Expected revision 1 for a new bookmark, got %d", got.Revision)
+	}
+
+	if list := store.List(); len(list) != 1 || list[id].Name != want.Name {
+		t.Errorf("Expected List to hold the bookmark, got %v", list)
+	}
+	if count := store.Count(); count != 1 {
+		t.Errorf("Expected a count of 1, got %d", count)
+	}
+}
+
+func testNotFound(t *testing.T, s *suite) {
+	store := s.store(t)
+	id := mustAdd(t, store, bookmark("present"))
+	missing := id + 1000
+
+	if _, err := store.Get(missing); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Get: expected ErrNotFound, got %v", err)
+	}
+	if err := store.Update(missing, bookmark("missing")); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Update: expected ErrNotFound, got %v", err)
+	}
+	if err := store.UpdateIfRevision(missing, bookmark("missing"), 1); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("UpdateIfRevision: expected ErrNotFound, got %v", err)
+	}
+	if err := store.Delete(missing); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Delete: expected ErrNotFound, got %v", err)
+	}
+	if err := store.DeleteIfRevision(missing, 1); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("DeleteIfRevision: expected ErrNotFound, got %v", err)
+	}
+
+	if len(store.List()) != 1 {
+		t.Errorf("Expected failed operations to leave the store unchanged")
+	}
+}
+
+func testUpdate(t *testing.T, s *suite) {
+	store := s.store(t)
+	id := mustAdd(t, store, bookmark("before"))
+	other := mustAdd(t, store, bookmark("other"))
+
+	want := bookmark("after")
+	want.Tags = []string{"changed", "tags"}
+	if err := store.Update(id, want); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	got := mustGet(t, store, id)
+	assertSame(t, want, got)
+	if got.Revision != 2 {
+		t.Errorf("Expected revision 2 after an update, got %d", got.Revision)
+	}
+	assertSame(t, bookmark("other"), mustGet(t, store, other))
+}
+
+func testDelete(t *testing.T, s *suite) {
+	store := s.store(t)
+	id := mustAdd(t, store, bookmark("doomed"))
+	kept := mustAdd(t, store, bookmark("kept"))
+
+	if err := store.Delete(id); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after Delete, got %v", err)
+	}
+	if err := store.Delete(id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+
+	list := store.List()
+	if _, ok := list[id]; ok || len(list) != 1 {
+		t.Errorf("Expected only bookmark %d to be listed, got %v", kept, list)
+	}
+	if count := store.Count(); count != 1 {
+		t.Errorf("Expected a count of 1 after Delete, got %d", count)
+	}
+}
+
+func testRevisions(t *testing.T, s *suite) {
+	store := s.store(t)
+	id := mustAdd(t, store, bookmark("versioned"))
+
+	if err := store.UpdateIfRevision(id, bookmark("stale"), 2); !errors.Is(err, internal.ErrRevisionMismatch) {
+		t.Errorf("Expected ErrRevisionMismatch for a wrong revision, got %v", err)
+	}
+	if err := store.UpdateIfRevision(id, bookmark("fresh"), 1); err != nil {
+		t.Fatalf("UpdateIfRevision failed: %v", err)
+	}
+	if got := mustGet(t, store, id); got.Name != "fresh" || got.Revision != 2 {
+		t.Errorf("Expected fresh at revision 2, got %q at %d", got.Name, got.Revision)
+	}
+
+	if err := store.DeleteIfRevision(id, 1); !errors.Is(err, internal.ErrRevisionMismatch) {
+		t.Errorf("Expected ErrRevisionMismatch for a wrong revision, got %v", err)
+	}
+	mustGet(t, store, id)
+	if err := store.DeleteIfRevision(id, 2); err != nil {
+		t.Fatalf("DeleteIfRevision failed: %v", err)
+	}
+	if _, err := store.Get(id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after DeleteIfRevision, got %v", err)
+	}
+}
+
+func testIDsIncrease(t *testing.T, s *suite) {
+	store := s.store(t)
+
+	last := 0
+	for i := range 10 {
+		id := mustAdd(t, store, bookmark(fmt.Sprint(i)))
+		if id <= last {
+			t.Fatalf("Expected IDs to increase, got %d after %d", id, last)
+		}
+		last = id
+	}
+
+	// IDs of deleted bookmarks are not given out again
+	if err := store.Delete(last); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if id := mustAdd(t, store, bookmark("after delete")); id <= last {
+		t.Errorf("Expected a new ID after deleting %d, got %d", last, id)
+	}
+}
+
+func testListReturnsCopy(t *testing.T, s *suite) {
+	store := s.store(t)
+	id := mustAdd(t, store, bookmark("original"))
+
+	list := store.List()
+	list[id] = bookmark("replaced")
+	list[id+1000] = bookmark("inserted")
+	delete(list, id)
+
+	list = store.List()
+	if len(list) != 1 || list[id].Name != "original" {
+		t.Errorf("Expected changes to a listed map not to reach the store, got %v", list)
+	}
+}
+
+func testQuery(t *testing.T, s *suite) {
+	store := s.store(t)
+
+	var ids []int
+	for i := range 7 {
+		ids = append(ids, mustAdd(t, store, bookmark(fmt.Sprint(i))))
+	}
+
+	var got []int
+	opts := internal.ListOptions{Limit: 3}
+	for pages := 0; ; pages++ {
+		if pages > len(ids) {
+			t.Fatal("Expected pagination to end")
+		}
+		page, err := store.Query(opts)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(page.Bookmarks) > 3 {
+			t.Fatalf("Expected at most 3 bookmarks per page, got %d", len(page.Bookmarks))
+		}
+		for _, entry := range page.Bookmarks {
+			got = append(got, entry.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	if !slices.Equal(got, ids) {
+		t.Errorf("Expected pages to list %v in ID order, got %v", ids, got)
+	}
+
+	if _, err := store.Query(internal.ListOptions{Cursor: "not a cursor"}); !errors.Is(err, internal.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func testSearch(t *testing.T, s *suite) {
+	store := s.store(t)
+	id := mustAdd(t, store, bookmark("Unmistakable"))
+	mustAdd(t, store, bookmark("Ordinary"))
+
+	results, err := store.Search("unmistakable", 0)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != id || results[0].Name != "Unmistakable" {
+		t.Errorf("Expected bookmark %d to match, got %v", id, results)
+	}
+
+	if _, err := store.Search("", 0); !errors.Is(err, internal.ErrEmptyQuery) {
+		t.Errorf("Expected ErrEmptyQuery, got %v", err)
+	}
+}
+
+func testBatch(t *testing.T, s *suite) {
+	store := s.store(t)
+	existing := mustAdd(t, store, bookmark("existing"))
+	doomed := mustAdd(t, store, bookmark("doomed"))
+
+	created, updated := bookmark("created"), bookmark("updated")
+	results, err := store.Batch([]internal.BatchOp{
+		{Op: internal.BatchCreate, Bookmark: &created},
+		{Op: internal.BatchUpdate, ID: existing, Bookmark: &updated},
+		{Op: internal.BatchDelete, ID: doomed},
+		{Op: internal.BatchDelete, ID: doomed + 1000},
+	}, false)
+	if err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+
+	for i, result := range results[:3] {
+		if result.Err != nil {
+			t.Errorf("Operation %d failed: %v", i, result.Err)
+		}
+	}
+	if !errors.Is(results[3].Err, internal.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting a missing bookmark, got %v", results[3].Err)
+	}
+
+	if got := mustGet(t, store, results[0].ID); got.Name != "created" || results[0].Revision != 1 {
+		t.Errorf("Expected created bookmark at revision 1, got %+v", results[0])
+	}
+	if got := mustGet(t, store, existing); got.Name != "updated" || got.Revision != 2 || results[1].Revision != 2 {
+		t.Errorf("Expected updated bookmark at revision 2, got %+v", got)
+	}
+	if _, err := store.Get(doomed); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected deleted bookmark to be gone, got %v", err)
+	}
+}
+
+func testAtomicBatch(t *testing.T, s *suite) {
+	store := s.store(t)
+	existing := mustAdd(t, store, bookmark("existing"))
+	generation := store.CurrentGeneration()
+
+	created, updated := bookmark("created"), bookmark("updated")
+	stale := uint64(7)
+	results, err := store.Batch([]internal.BatchOp{
+		{Op: internal.BatchCreate, Bookmark: &created},
+		{Op: internal.BatchUpdate, ID: existing, Bookmark: &updated, IfRevision: &stale},
+	}, true)
+	if err != nil {
+		t.Fatalf("Batch failed: %v", err)
+	}
+
+	if !errors.Is(results[0].Err, internal.ErrBatchAborted) {
+		t.Errorf("Expected ErrBatchAborted for the operation that did not fail, got %v", results[0].Err)
+	}
+	if !errors.Is(results[1].Err, internal.ErrRevisionMismatch) {
+		t.Errorf("Expected ErrRevisionMismatch, got %v", results[1].Err)
+	}
+
+	if list := store.List(); len(list) != 1 || list[existing].Name != "existing" {
+		t.Errorf("Expected a failed atomic batch to change nothing, got %v", list)
+	}
+	if got := store.CurrentGeneration(); got != generation {
+		t.Errorf("Expected generation %d after a failed atomic batch, got %d", generation, got)
+	}
+}
+
+func testSubscribe(t *testing.T, s *suite) {
+	store := s.store(t)
+	first := mustAdd(t, store, bookmark("before"))
+	since := store.CurrentGeneration()
+
+	events, cancel, err := store.Subscribe(since)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer cancel()
+
+	second := mustAdd(t, store, bookmark("added"))
+	if err := store.Update(first, bookmark("updated")); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := store.Delete(second); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	want := []struct {
+		kind string
+		id   int
+	}{
+		{internal.EventAdd, second},
+		{internal.EventUpdate, first},
+		{internal.EventDelete, second},
+	}
+	for i, w := range want {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("Expected event %d, but the channel was closed", i)
+			}
+			if event.Type != w.kind || event.ID != w.id || event.Seq != since+uint64(i)+1 {
+				t.Errorf("Expected %s of %d with sequence %d, got %+v", w.kind, w.id, since+uint64(i)+1, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for event %d", i)
+		}
+	}
+
+	// Resuming from an earlier event replays the events after it
+	replay, cancelReplay, err := store.Subscribe(since + 1)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer cancelReplay()
+	select {
+	case event := <-replay:
+		if event.Seq != since+2 {
+			t.Errorf("Expected replay to start at sequence %d, got %d", since+2, event.Seq)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for replayed event")
+	}
+
+	if _, _, err := store.Subscribe(store.CurrentGeneration() + 10); !errors.Is(err, internal.ErrEventsExpired) {
+		t.Errorf("Expected ErrEventsExpired subscribing from the future, got %v", err)
+	}
+}
+
+func testGenerations(t *testing.T, s *suite) {
+	store := s.store(t)
+	start := store.CurrentGeneration()
+	modified := store.LastModified()
+
+	id := mustAdd(t, store, bookmark("counted"))
+	if err := store.Update(id, bookmark("counted again")); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := store.Delete(id); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	store.Delete(id) // Fails, so does not count
+
+	if got := store.CurrentGeneration(); got != start+3 {
+		t.Errorf("Expected generation %d after three mutations, got %d", start+3, got)
+	}
+	if store.LastModified().Before(modified) || store.LastModified().IsZero() {
+		t.Errorf("Expected LastModified to advance, got %v after %v", store.LastModified(), modified)
+	}
+
+	if err := store.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	generation, at := store.Persisted()
+	if generation != store.CurrentGeneration() || at.IsZero() {
+		t.Errorf("Expected generation %d persisted, got %d at %v", store.CurrentGeneration(), generation, at)
+	}
+
+	// Saving again without changes is a no-op
+	if err := store.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	if _, again := store.Persisted(); !again.Equal(at) {
+		t.Errorf("Expected an unchanged store not to be saved again, but it was saved at %v", again)
+	}
+}
+
+func testConcurrent(t *testing.T, s *suite) {
+	store := s.store(t)
+
+	const workers, perWorker = 8, 25
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		added = make(map[int]bool)
+	)
+
+	for w := range workers {
+		wg.Go(func() {
+			for i := range perWorker {
+				id, err := store.Add(bookmark(fmt.Sprintf("w%d-%d", w, i)))
+				if err != nil {
+					t.Errorf("Add failed: %v", err)
+					return
+				}
+				mu.Lock()
+				if added[id] {
+					t.Errorf("ID %d given out twice", id)
+				}
+				added[id] = true
+				mu.Unlock()
+
+				if _, err := store.Get(id); err != nil {
+					t.Errorf("Get failed: %v", err)
+				}
+				if err := store.Update(id, bookmark(fmt.Sprintf("w%d-%d-updated", w, i))); err != nil {
+					t.Errorf("Update failed: %v", err)
+				}
+				if i%5 == 0 {
+					if err := store.Delete(id); err != nil {
+						t.Errorf("Delete failed: %v", err)
+					}
+					mu.Lock()
+					delete(added, id)
+					mu.Unlock()
+				}
+
+				store.List()
+				store.Query(internal.ListOptions{Limit: 10})
+				store.Search("updated", 5)
+				store.CurrentGeneration()
+				if i%10 == 0 {
+					if err := store.SaveSnapshot(); err != nil {
+						t.Errorf("SaveSnapshot failed: %v", err)
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	list := store.List()
+	if len(list) != len(added) {
+		t.Errorf("Expected %d bookmarks, got %d", len(added), len(list))
+	}
+	for id := range added {
+		if _, ok := list[id]; !ok {
+			t.Errorf("Bookmark %d missing", id)
+		}
+	}
+}
+
+func testReloadSnapshot(t *testing.T, s *suite) {
+	store := s.store(t)
+	kept := mustAdd(t, store, bookmark("kept"))
+	changed := mustAdd(t, store, bookmark("changed"))
+	deleted := mustAdd(t, store, bookmark("deleted"))
+	if err := store.Update(changed, bookmark("changed again")); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if err := store.Delete(deleted); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	want, generation := store.List(), store.CurrentGeneration()
+
+	store = s.reopen(t, store)
+
+	assertSameList(t, want, store.List())
+	if got := mustGet(t, store, changed); got.Revision != 2 {
+		t.Errorf("Expected revision 2 after reload, got %d", got.Revision)
+	}
+	if got := store.CurrentGeneration(); got != generation {
+		t.Errorf("Expected generation %d after reload, got %d", generation, got)
+	}
+	if persisted, _ := store.Persisted(); persisted != generation {
+		t.Errorf("Expected generation %d persisted after reload, got %d", generation, persisted)
+	}
+	assertSame(t, bookmark("kept"), mustGet(t, store, kept))
+}
+
+func testReloadWithoutSnapshot(t *testing.T, s *suite) {
+	store := s.store(t)
+	mustAdd(t, store, bookmark("snapshotted"))
+	if err := store.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	// Mutations are durable once acknowledged, snapshot or not
+	id := mustAdd(t, store, bookmark("acknowledged"))
+	if err := store.Update(id, bookmark("acknowledged twice")); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	want := store.List()
+
+	store = s.reopen(t, store)
+	assertSameList(t, want, store.List())
+}
+
+func testIDsIncreaseAcrossReload(t *testing.T, s *suite) {
+	store := s.store(t)
+	mustAdd(t, store, bookmark("first"))
+	last := mustAdd(t, store, bookmark("last"))
+	if err := store.Delete(last); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.SaveSnapshot(); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	store = s.reopen(t, store)
+	if id := mustAdd(t, store, bookmark("after reload")); id <= last {
+		t.Errorf("Expected an ID above %d after reload, got %d", last, id)
+	}
+}
+
+func assertSameList(t *testing.T, want, got map[int]internal.Bookmark) {
+	t.Helper()
+	if !slices.Equal(slices.Sorted(maps.Keys(got)), slices.Sorted(maps.Keys(want))) {
+		t.Fatalf("Expected bookmarks %v, got %v", slices.Sorted(maps.Keys(want)), slices.Sorted(maps.Keys(got)))
+	}
+	for id, bookmark := range want {
+		assertSame(t, bookmark, got[id])
+		if got[id].Revision != bookmark.Revision {
+			t.Errorf("Bookmark %d: expected revision %d, got %d", id, bookmark.Revision, got[id].Revision)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// Scopes an API token can be granted.
+const (
+	ScopeRead  = "read"  // Read bookmarks and the change feed
+	ScopeWrite = "write" // Create, update, and delete bookmarks
+	ScopeAdmin = "admin" // Everything, including managing tokens
+)
+
+var (
+	// ErrInvalidToken is returned when a bearer token does not match any token.
+	ErrInvalidToken = errors.New("invalid token")
+
+	// ErrTokenExpired is returned when a bearer token is past its expiry.
+	ErrTokenExpired = errors.New("token expired")
+
+	// ErrTokenNotFound is returned when revoking a token that does not exist.
+	ErrTokenNotFound = errors.New("token not found")
+)
+
+// SharedOwner is the owner of tokens for the shared store of a server without
+// user accounts.
+const SharedOwner = "*"
+
+// Token describes an API token. The token itself is only shown when it is
+// created; stores keep a hash of it.
+// Owner is the user whose bookmarks the token gives access to, or SharedOwner
+// on servers without user accounts. Tokens without an owner are never
+// accepted: only a server without authentication could have created them.
+type Token struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Owner      string   `json:"owner,omitempty"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  int64    `json:"created_at"`             // Unix seconds
+	ExpiresAt  int64    `json:"expires_at,omitempty"`   // Unix seconds, 0 if it never expires
+	LastUsedAt int64    `json:"last_used_at,omitempty"` // Unix seconds, 0 if never used
+}
+
+// Allows reports whether the token grants a scope. The admin scope grants every scope.
+func (t Token) Allows(scope string) bool {
+	return ScopesAllow(t.Scopes, scope)
+}
+
+// Expired reports whether the token is past its expiry at the given time.
+func (t Token) Expired(now time.Time) bool {
+	return t.ExpiresAt != 0 && now.Unix() >= t.ExpiresAt
+}
+
+// ScopesAllow reports whether a set of scopes grants a scope. The admin scope grants every scope.
+func ScopesAllow(scopes []string, scope string) bool {
+	return slices.Contains(scopes, ScopeAdmin) || slices.Contains(scopes, scope)
+}
+
+// ValidScope reports whether scope is one a token can be granted.
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeRead, ScopeWrite, ScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// TokenRequest is the body of a request to create a token.
+type TokenRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt int64    `json:"expires_at,omitempty"` // Unix seconds, 0 for a token that never expires
+}
+
+// CreatedToken is a newly created token along with its secret value,
+// which is sent in the Authorization header as "Bearer <token>".
+type CreatedToken struct {
+	Token
+	Secret string `json:"token"`
+}
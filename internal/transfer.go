@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ImportIssue describes a bookmark from an import file that was not imported.
+type ImportIssue struct {
+	Index  int    `json:"index"` // Position of the bookmark in the file, counting from 0
+	Name   string `json:"name"`
+	Url    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
+// ImportReport summarizes a bulk import.
+// In a dry run nothing is stored, and Imported counts the bookmarks that would have been.
+type ImportReport struct {
+	DryRun     bool          `json:"dry_run"`
+	Total      int           `json:"total"`
+	Imported   int           `json:"imported"`
+	IDs        []int         `json:"ids,omitempty"`
+	Duplicates []ImportIssue `json:"duplicates"`
+	Invalid    []ImportIssue `json:"invalid"`
+}
+
+// ImportStore is the part of a bookmark store that ImportBookmarks uses.
+type ImportStore interface {
+	List() map[int]Bookmark
+	Batch(ops []BatchOp, atomic bool) ([]BatchOpResult, error)
+}
+
+// ImportBookmarks adds bookmarks parsed from a bookmark file to store and
+// reports on each one. Bookmarks that fail validation, with tags normalized if
+// normalize is set, or whose URL is already stored or appears earlier in the
+// list are skipped. With dryRun set nothing is stored.
+// The bookmarks are added in a single atomic batch: if they cannot be stored,
+// none are, and the error is returned with an empty report.
+func ImportBookmarks(store ImportStore, bookmarks []Bookmark, dryRun, normalize bool) (ImportReport, error) {
+	// Index of the first bookmark in the file with each URL; -1 for stored bookmarks
+	seen := make(map[string]int)
+	for _, bookmark := range store.List() {
+		seen[bookmark.Url] = -1
+	}
+
+	report := ImportReport{
+		DryRun:     dryRun,
+		Total:      len(bookmarks),
+		Duplicates: []ImportIssue{},
+		Invalid:    []ImportIssue{},
+	}
+	now := time.Now().Unix()
+	var ops []BatchOp
+
+	for i, bookmark := range bookmarks {
+		issue := ImportIssue{Index: i, Name: bookmark.Name, Url: bookmark.Url}
+
+		// Browsers allow untitled bookmarks
+		if bookmark.Name == "" {
+			bookmark.Name = bookmark.Url
+		}
+		if err := ValidateBookmark(&bookmark, normalize); err != nil {
+			issue.Reason = err.Error()
+			report.Invalid = append(report.Invalid, issue)
+			continue
+		}
+
+		if first, ok := seen[bookmark.Url]; ok {
+			if first < 0 {
+				issue.Reason = "already bookmarked"
+			} else {
+				issue.Reason = "duplicate of bookmark " + strconv.Itoa(first) + " in the file"
+			}
+			report.Duplicates = append(report.Duplicates, issue)
+			continue
+		}
+		seen[bookmark.Url] = i
+
+		// Keep the browser's dates, but never from the future
+		if bookmark.CreatedAt <= 0 || bookmark.CreatedAt > now {
+			bookmark.CreatedAt = now
+		}
+		if bookmark.UpdatedAt < bookmark.CreatedAt || bookmark.UpdatedAt > now {
+			bookmark.UpdatedAt = bookmark.CreatedAt
+		}
+
+		report.Imported++
+		ops = append(ops, BatchOp{Op: BatchCreate, Bookmark: &bookmark})
+	}
+
+	if dryRun || len(ops) == 0 {
+		return report, nil
+	}
+
+	results, err := store.Batch(ops, true)
+	if err != nil {
+		return ImportReport{}, err
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			return ImportReport{}, fmt.Errorf("storing bookmarks: %w", result.Err)
+		}
+		report.IDs = append(report.IDs, result.ID)
+	}
+
+	return report, nil
+}
@@ -0,0 +1,31 @@
+package internal
+
+import "errors"
+
+// MinPasswordLength is the shortest password accepted for a user account.
+const MinPasswordLength = 8
+
+var (
+	// ErrUserNotFound is returned when a user account does not exist.
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrUserExists is returned when creating a user account whose name is taken.
+	ErrUserExists = errors.New("user already exists")
+
+	// ErrUserDisabled is returned when authenticating as a disabled user.
+	ErrUserDisabled = errors.New("user is disabled")
+
+	// ErrInvalidCredentials is returned when a username and password do not match an account.
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// User is an account that can sign in to the server.
+// Every user has a bookmark namespace of their own.
+// Only a hash of the password is kept.
+type User struct {
+	Username     string `json:"username"`
+	PasswordHash string `json:"password_hash"`
+	Disabled     bool   `json:"disabled"`
+	CreatedAt    int64  `json:"created_at"` // Unix seconds
+	UpdatedAt    int64  `json:"updated_at"` // Unix seconds
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits on bookmark fields, enforced by the server and by local mode.
+const (
+	MaxURLLength         = 2048
+	MaxNameLength        = 256
+	MaxDescriptionLength = 4096
+	MaxTags              = 32
+	MaxTagLength         = 64
+)
+
+// ValidationError is returned when a bookmark fails validation.
+// It lists every offending field rather than stopping at the first.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Message
+	}
+	return "invalid bookmark: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateBookmark checks a bookmark submitted by a client and normalizes its tags in place.
+// It returns a *ValidationError listing every invalid field.
+// When normalize is set, tags are trimmed, lowercased, and deduplicated, and blank tags are dropped.
+func ValidateBookmark(bookmark *Bookmark, normalize bool) error {
+	verr := &ValidationError{}
+
+	switch {
+	case bookmark.Name == "":
+		verr.add("name", "is required")
+	case utf8.RuneCountInString(bookmark.Name) > MaxNameLength:
+		verr.add("name", "must be at most %d characters", MaxNameLength)
+	}
+
+	if msg := checkURL(bookmark.Url); msg != "" {
+		verr.add("url", "%s", msg)
+	}
+
+	if utf8.RuneCountInString(bookmark.Description) > MaxDescriptionLength {
+		verr.add("description", "must be at most %d characters", MaxDescriptionLength)
+	}
+
+	if normalize {
+		bookmark.Tags = normalizeTags(bookmark.Tags)
+	}
+	if len(bookmark.Tags) > MaxTags {
+		verr.add("tags", "must have at most %d tags", MaxTags)
+	}
+	for i, tag := range bookmark.Tags {
+		field := fmt.Sprintf("tags[%d]", i)
+		switch {
+		case strings.TrimSpace(tag) == "":
+			verr.add(field, "must not be blank")
+		case utf8.RuneCountInString(tag) > MaxTagLength:
+			verr.add(field, "must be at most %d characters", MaxTagLength)
+		}
+	}
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// checkURL returns a description of what is wrong with a bookmark URL, or "" if it is acceptable.
+// Only absolute http, https, and file URLs are accepted.
+func checkURL(raw string) string {
+	if raw == "" {
+		return "is required"
+	}
+	if len(raw) > MaxURLLength {
+		return fmt.Sprintf("must be at most %d characters", MaxURLLength)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() {
+		return "must be an absolute URL"
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "must include a host"
+		}
+	case "file":
+		if u.Path == "" {
+			return "must include a path"
+		}
+	default:
+		return "scheme must be http, https, or file"
+	}
+
+	return ""
+}
+
+// normalizeTags trims and lowercases tags, dropping blanks and duplicates while keeping their order.
+func normalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,4 @@
+package internal
+
+// Version is the version of fave.
+const Version = "0.1.0"
@@ -15,17 +15,29 @@ Usage: fave <subcommand> [flags]
 Available subcommands:
 (Server)
 	serve	Starts a Fave server to store and share bookmarks.
+	user	Manage user accounts (create, reset, disable, enable, list).
+	token	Manage API tokens (create, list, revoke).
+	migrate	Upgrade store files to the current schema (--dry-run to preview).
 (Client)
 	add	Add a bookmark.
 	list	List all bookmarks.
+	search	Search bookmarks by name, description, URL, and tags.
 	get	Get a bookmark by ID.
 	update	Update an existing bookmark.
 	delete	Delete a bookmark by ID.
-	health	Check server health.
+	import	Import bookmarks from a browser bookmark file.
+	export	Export bookmarks to a browser bookmark file.
+	watch	Print bookmark changes as they happen.
+	health	Show the server's health report.
 
 Common flags:
 	--host		Server URL (default: http://localhost:8080)
-	--password	Authentication password`
+	--username	Account username (servers with user accounts)
+	--password	Authentication password
+	--token		API token (instead of username and password)
+	--ca-cert-file	CA bundle to trust for HTTPS servers
+	--local		Store file to use directly instead of a server (add, list,
+			search, get, update, delete, import, export)`
 
 func main() {
 	if len(os.Args) < 2 {
@@ -42,16 +54,30 @@ func main() {
 	switch subcommand {
 	case "serve":
 		err = cmd.RunServe(rest)
+	case "user":
+		err = cmd.RunUser(rest)
+	case "token":
+		err = cmd.RunToken(rest)
+	case "migrate":
+		err = cmd.RunMigrate(rest)
 	case "add":
 		err = cmd.RunAdd(rest)
 	case "list":
 		err = cmd.RunList(rest)
+	case "search":
+		err = cmd.RunSearch(rest)
 	case "get":
 		err = cmd.RunGet(rest)
 	case "update":
 		err = cmd.RunUpdate(rest)
 	case "delete":
 		err = cmd.RunDelete(rest)
+	case "import":
+		err = cmd.RunImport(rest)
+	case "export":
+		err = cmd.RunExport(rest)
+	case "watch":
+		err = cmd.RunWatch(rest)
 	case "health":
 		err = cmd.RunHealth(rest)
 	default: